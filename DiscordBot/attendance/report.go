@@ -0,0 +1,112 @@
+package attendance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 会議に出席したメンバー
+type Attendee struct {
+	UserID    string        `json:"user_id"`
+	Name      string        `json:"name"`
+	FirstJoin time.Time     `json:"first_join"`
+	LastLeave time.Time     `json:"last_leave"`
+	Duration  time.Duration `json:"duration"`
+	Sessions  int           `json:"sessions"` // 入室した回数
+}
+
+// 会議の出席記録
+type Report struct {
+	Meeting   *Meeting   `json:"meeting"`
+	Attendees []Attendee `json:"attendees"`
+}
+
+// 会議のチャンネル・時間帯に含まれる在室時間をメンバーごとに合計する
+func buildReport(m *Meeting, events []*Event, end time.Time) *Report {
+	joinedAt := make(map[string]time.Time)
+	attendees := make(map[string]*Attendee)
+
+	// 在室していた区間[from, to]を会議の時間帯に切り詰めて加算する
+	add := func(e *Event, from, to time.Time) {
+		if from.Before(m.StartedAt) {
+			from = m.StartedAt
+		}
+		if to.After(end) {
+			to = end
+		}
+		if !to.After(from) {
+			return
+		}
+		a, ok := attendees[e.UserID]
+		if !ok {
+			a = &Attendee{UserID: e.UserID, FirstJoin: from}
+			attendees[e.UserID] = a
+		}
+		a.Name = e.Name
+		a.Duration += to.Sub(from)
+		a.Sessions++
+		if to.After(a.LastLeave) {
+			a.LastLeave = to
+		}
+	}
+
+	for _, e := range events {
+		if e.ChannelID != m.ChannelID || e.At.After(end) {
+			continue
+		}
+		switch e.Type {
+		case EventJoin:
+			if _, ok := joinedAt[e.UserID]; !ok {
+				joinedAt[e.UserID] = e.At
+			}
+		case EventLeave:
+			if from, ok := joinedAt[e.UserID]; ok {
+				add(e, from, e.At)
+				delete(joinedAt, e.UserID)
+			}
+		}
+	}
+	// 終了時点でまだ在室しているメンバー
+	for _, e := range events {
+		if from, ok := joinedAt[e.UserID]; ok && e.ChannelID == m.ChannelID {
+			add(e, from, end)
+			delete(joinedAt, e.UserID)
+		}
+	}
+
+	r := &Report{Meeting: m}
+	for _, a := range attendees {
+		r.Attendees = append(r.Attendees, *a)
+	}
+	sort.Slice(r.Attendees, func(i, j int) bool {
+		return r.Attendees[i].FirstJoin.Before(r.Attendees[j].FirstJoin)
+	})
+	return r
+}
+
+// CSV形式で出力する
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF") // Excelで文字化けしないようBOMを付ける
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"ユーザーID", "名前", "最初の入室", "最後の退室", "在室時間(分)", "入室回数"})
+	for _, a := range r.Attendees {
+		w.Write([]string{
+			a.UserID,
+			a.Name,
+			a.FirstJoin.Format("2006/01/02 15:04:05"),
+			a.LastLeave.Format("2006/01/02 15:04:05"),
+			fmt.Sprintf("%.1f", a.Duration.Minutes()),
+			fmt.Sprintf("%d", a.Sessions),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,130 @@
+package attendance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildReport(t *testing.T) {
+	start := time.Date(2025, 4, 2, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	event := func(typ, channelID, userID string, minutes int) *Event {
+		return &Event{Type: typ, GuildID: "guild", ChannelID: channelID, UserID: userID, Name: "name-" + userID, At: at(minutes)}
+	}
+	m := &Meeting{ID: "m", GuildID: "guild", ChannelID: "vc", StartedAt: start}
+
+	for _, tc := range []struct {
+		name   string
+		events []*Event
+		end    int
+		want   []Attendee
+	}{
+		{
+			name: "入室と退室",
+			events: []*Event{
+				event(EventJoin, "vc", "a", 0),
+				event(EventLeave, "vc", "a", 30),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "a", FirstJoin: at(0), LastLeave: at(30), Duration: 30 * time.Minute, Sessions: 1}},
+		},
+		{
+			name: "開始前からの在室は開始時刻から数える",
+			events: []*Event{
+				event(EventJoin, "vc", "a", -20),
+				event(EventLeave, "vc", "a", 10),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "a", FirstJoin: at(0), LastLeave: at(10), Duration: 10 * time.Minute, Sessions: 1}},
+		},
+		{
+			name: "開始前に退室したメンバーは含めない",
+			events: []*Event{
+				event(EventJoin, "vc", "a", -20),
+				event(EventLeave, "vc", "a", -10),
+			},
+			end: 60,
+		},
+		{
+			name: "再入室は合計する",
+			events: []*Event{
+				event(EventJoin, "vc", "a", 0),
+				event(EventLeave, "vc", "a", 10),
+				event(EventJoin, "vc", "a", 20),
+				event(EventLeave, "vc", "a", 45),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "a", FirstJoin: at(0), LastLeave: at(45), Duration: 35 * time.Minute, Sessions: 2}},
+		},
+		{
+			name: "別のチャンネルへの移動",
+			events: []*Event{
+				event(EventJoin, "vc", "a", 0),
+				event(EventLeave, "vc", "a", 15),
+				event(EventJoin, "other", "a", 15),
+				event(EventLeave, "other", "a", 40),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "a", FirstJoin: at(0), LastLeave: at(15), Duration: 15 * time.Minute, Sessions: 1}},
+		},
+		{
+			name: "別のチャンネルからの移動",
+			events: []*Event{
+				event(EventJoin, "other", "b", 0),
+				event(EventLeave, "other", "b", 5),
+				event(EventJoin, "vc", "b", 5),
+				event(EventLeave, "vc", "b", 25),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "b", FirstJoin: at(5), LastLeave: at(25), Duration: 20 * time.Minute, Sessions: 1}},
+		},
+		{
+			name: "別のチャンネルだけにいたメンバーは含めない",
+			events: []*Event{
+				event(EventJoin, "other", "c", 0),
+				event(EventLeave, "other", "c", 30),
+			},
+			end: 60,
+		},
+		{
+			name: "開催中の会議は終了時刻まで在室として数える",
+			events: []*Event{
+				event(EventJoin, "vc", "a", 0),
+				event(EventJoin, "vc", "b", 20),
+				event(EventLeave, "vc", "b", 30),
+			},
+			end: 45,
+			want: []Attendee{
+				{UserID: "a", FirstJoin: at(0), LastLeave: at(45), Duration: 45 * time.Minute, Sessions: 1},
+				{UserID: "b", FirstJoin: at(20), LastLeave: at(30), Duration: 10 * time.Minute, Sessions: 1},
+			},
+		},
+		{
+			name: "終了後のイベントは数えない",
+			events: []*Event{
+				event(EventJoin, "vc", "a", 10),
+				event(EventLeave, "vc", "a", 90),
+				event(EventJoin, "vc", "b", 70),
+			},
+			end:  60,
+			want: []Attendee{{UserID: "a", FirstJoin: at(10), LastLeave: at(60), Duration: 50 * time.Minute, Sessions: 1}},
+		},
+	} {
+		got := buildReport(m, tc.events, at(tc.end)).Attendees
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %d attendees %+v, want %d", tc.name, len(got), got, len(tc.want))
+			continue
+		}
+		for n, want := range tc.want {
+			a := got[n]
+			if a.UserID != want.UserID || a.Name != "name-"+want.UserID || !a.FirstJoin.Equal(want.FirstJoin) ||
+				!a.LastLeave.Equal(want.LastLeave) || a.Duration != want.Duration || a.Sessions != want.Sessions {
+				t.Errorf("%s: attendee %d = %+v, want %+v", tc.name, n, a, want)
+			}
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
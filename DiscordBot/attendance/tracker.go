@@ -0,0 +1,332 @@
+package attendance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"main/storage"
+)
+
+const (
+	EventJoin  = "join"
+	EventLeave = "leave"
+)
+
+var (
+	ErrMeetingActive   = errors.New("このサーバーではすでに会議が開始されています")
+	ErrNoActiveMeeting = errors.New("開始中の会議はありません")
+	ErrMeetingNotFound = errors.New("会議が見つかりません")
+)
+
+// ボイスチャンネルへの入退室の記録
+type Event struct {
+	Type      string    `json:"type"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	At        time.Time `json:"at"`
+}
+
+// 出席を取る会議
+type Meeting struct {
+	ID        string     `json:"id"`
+	GuildID   string     `json:"guild_id"`
+	ChannelID string     `json:"channel_id"`
+	Name      string     `json:"name"`
+	Source    string     `json:"source"` // manual または recording
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// 開始時点のイベントファイルの大きさ（集計はここから読む）
+	EventsOffset int64 `json:"events_offset,omitempty"`
+}
+
+// VoiceStateUpdateから入退室を記録し、会議ごとの出席を集計する
+type Tracker struct {
+	dir          string
+	meetingsFile *storage.JSONFile
+
+	mu       sync.Mutex
+	current  map[string]map[string]string // ギルドID → ユーザーID → ボイスチャンネルID
+	meetings []*Meeting
+}
+
+// 保存済みの会議を読み込んでTrackerを返す
+func NewTracker(dir string) (*Tracker, error) {
+	t := &Tracker{
+		dir:          dir,
+		meetingsFile: storage.NewJSONFile(dir, "meetings.json"),
+		current:      make(map[string]map[string]string),
+	}
+	if err := t.meetingsFile.Load(&t.meetings); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ボイスチャンネルの入退室を記録する
+func (t *Tracker) OnVoiceStateUpdate(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
+	if isBot(s, vs.VoiceState) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	users, ok := t.current[vs.GuildID]
+	if !ok {
+		users = make(map[string]string)
+		t.current[vs.GuildID] = users
+	}
+	before, known := users[vs.UserID]
+	if !known && vs.BeforeUpdate != nil {
+		before = vs.BeforeUpdate.ChannelID
+	}
+	if before == vs.ChannelID {
+		// ミュートの切り替えなど
+		return
+	}
+
+	now := time.Now()
+	name := memberName(s, vs.GuildID, vs.UserID, vs.Member)
+	if before != "" {
+		if err := t.appendEvent(&Event{Type: EventLeave, GuildID: vs.GuildID, ChannelID: before, UserID: vs.UserID, Name: name, At: now}); err != nil {
+			log.Printf("退室の記録に失敗しました (%s): %v\n", vs.GuildID, err)
+		}
+	}
+	if vs.ChannelID != "" {
+		if err := t.appendEvent(&Event{Type: EventJoin, GuildID: vs.GuildID, ChannelID: vs.ChannelID, UserID: vs.UserID, Name: name, At: now}); err != nil {
+			log.Printf("入室の記録に失敗しました (%s): %v\n", vs.GuildID, err)
+		}
+		users[vs.UserID] = vs.ChannelID
+	} else {
+		delete(users, vs.UserID)
+	}
+}
+
+// 会議を開始する
+// 開始時点でチャンネルにいるメンバーは、その時刻に入室したものとして記録する
+func (t *Tracker) StartMeeting(s *discordgo.Session, guildID, channelID, name, source string) (*Meeting, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.activeMeeting(guildID) != nil {
+		return nil, ErrMeetingActive
+	}
+
+	now := time.Now()
+	m := &Meeting{
+		ID:        strings.SplitN(uuid.New().String(), "-", 2)[0],
+		GuildID:   guildID,
+		ChannelID: channelID,
+		Name:      name,
+		Source:    source,
+		StartedAt: now,
+	}
+	if info, err := os.Stat(t.eventsPath(guildID)); err == nil {
+		m.EventsOffset = info.Size()
+	}
+
+	users, ok := t.current[guildID]
+	if !ok {
+		users = make(map[string]string)
+		t.current[guildID] = users
+	}
+	if guild, err := s.State.Guild(guildID); err == nil {
+		for _, vs := range guild.VoiceStates {
+			if vs.ChannelID != channelID || users[vs.UserID] == channelID || isBot(s, vs) {
+				continue
+			}
+			users[vs.UserID] = channelID
+			err := t.appendEvent(&Event{
+				Type: EventJoin, GuildID: guildID, ChannelID: channelID, UserID: vs.UserID,
+				Name: memberName(s, guildID, vs.UserID, vs.Member), At: now,
+			})
+			if err != nil {
+				log.Printf("入室の記録に失敗しました (%s): %v\n", guildID, err)
+			}
+		}
+	}
+
+	t.meetings = append(t.meetings, m)
+	copied := *m
+	return &copied, t.meetingsFile.Save(t.meetings)
+}
+
+// 開始中の会議を終了する
+func (t *Tracker) EndMeeting(guildID string) (*Meeting, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.activeMeeting(guildID)
+	if m == nil {
+		return nil, ErrNoActiveMeeting
+	}
+	now := time.Now()
+	m.EndedAt = &now
+	copied := *m
+	return &copied, t.meetingsFile.Save(t.meetings)
+}
+
+// 開始中の会議を返す（なければnil）
+// 返す会議は複製で、終了しても書き換わらない
+func (t *Tracker) ActiveMeeting(guildID string) *Meeting {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if m := t.activeMeeting(guildID); m != nil {
+		copied := *m
+		return &copied
+	}
+	return nil
+}
+
+func (t *Tracker) activeMeeting(guildID string) *Meeting {
+	for _, m := range t.meetings {
+		if m.GuildID == guildID && m.EndedAt == nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// ギルドの会議を新しい順に返す
+func (t *Tracker) Meetings(guildID string) []*Meeting {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var meetings []*Meeting
+	for i := len(t.meetings) - 1; i >= 0; i-- {
+		if t.meetings[i].GuildID == guildID {
+			copied := *t.meetings[i]
+			meetings = append(meetings, &copied)
+		}
+	}
+	return meetings
+}
+
+// IDまたは名前で会議を探す（空なら最新の会議）
+func (t *Tracker) FindMeeting(guildID, key string) (*Meeting, error) {
+	for _, m := range t.Meetings(guildID) {
+		if key == "" || m.ID == key || m.Name == key {
+			return m, nil
+		}
+	}
+	return nil, ErrMeetingNotFound
+}
+
+// 会議の出席を集計する
+// 終了時刻は EndMeeting が書き換えるため、ロックを取って最新の会議を複製してから使う
+func (t *Tracker) Report(m *Meeting) (*Report, error) {
+	t.mu.Lock()
+	for _, current := range t.meetings {
+		if current.ID == m.ID {
+			copied := *current
+			m = &copied
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	end := time.Now()
+	if m.EndedAt != nil {
+		end = *m.EndedAt
+	}
+	events, err := t.events(m.GuildID, m.EventsOffset, end)
+	if err != nil {
+		return nil, err
+	}
+	return buildReport(m, events, end), nil
+}
+
+// イベントをギルドごとのJSON Linesファイルに追記する
+func (t *Tracker) appendEvent(e *Event) error {
+	if err := os.MkdirAll(t.dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.eventsPath(e.GuildID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(e); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ギルドのイベントのうち、ファイルの offset から end までに記録されたものを時刻順に読み込む
+// イベントは記録した順に追記されるため、end より後のイベントが出てきたら読むのをやめる
+func (t *Tracker) events(guildID string, offset int64, end time.Time) ([]*Event, error) {
+	f, err := os.Open(t.eventsPath(guildID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if e.At.After(end) {
+			break
+		}
+		events = append(events, &e)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events, scanner.Err()
+}
+
+func (t *Tracker) eventsPath(guildID string) string {
+	return filepath.Join(t.dir, "events_"+filepath.Base(guildID)+".jsonl")
+}
+
+func isBot(s *discordgo.Session, vs *discordgo.VoiceState) bool {
+	if vs.Member != nil && vs.Member.User != nil {
+		return vs.Member.User.Bot
+	}
+	if m, err := s.State.Member(vs.GuildID, vs.UserID); err == nil && m.User != nil {
+		return m.User.Bot
+	}
+	return false
+}
+
+// サーバーでの表示名を返す
+func memberName(s *discordgo.Session, guildID, userID string, member *discordgo.Member) string {
+	if member == nil {
+		member, _ = s.State.Member(guildID, userID)
+	}
+	if member != nil {
+		if member.Nick != "" {
+			return member.Nick
+		}
+		if member.User != nil {
+			return member.User.Username
+		}
+	}
+	return userID
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
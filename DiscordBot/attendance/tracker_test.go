@@ -0,0 +1,55 @@
+package attendance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReportReadsFromMeetingStart(t *testing.T) {
+	tr, err := NewTracker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := discordgo.New("")
+
+	// 会議の前の記録は読まない
+	before := time.Now().Add(-time.Hour)
+	tr.appendEvent(&Event{Type: EventJoin, GuildID: "guild", ChannelID: "vc", UserID: "old", At: before})
+
+	m, err := tr.StartMeeting(s, "guild", "vc", "定例", "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.EventsOffset == 0 {
+		t.Fatal("EventsOffset was not recorded")
+	}
+	tr.appendEvent(&Event{Type: EventJoin, GuildID: "guild", ChannelID: "vc", UserID: "a", At: time.Now()})
+
+	ended, err := tr.EndMeeting("guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 終了後の記録は読まない
+	tr.appendEvent(&Event{Type: EventJoin, GuildID: "guild", ChannelID: "vc", UserID: "late", At: ended.EndedAt.Add(time.Minute)})
+
+	// 開始時に受け取った会議でも、終了時刻は記録されたものを使う
+	if m.EndedAt != nil {
+		t.Fatal("the meeting returned by StartMeeting was modified by EndMeeting")
+	}
+	r, err := tr.Report(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Meeting.EndedAt == nil || !r.Meeting.EndedAt.Equal(*ended.EndedAt) {
+		t.Errorf("report meeting ended at %v, want %v", r.Meeting.EndedAt, ended.EndedAt)
+	}
+	if len(r.Attendees) != 1 || r.Attendees[0].UserID != "a" {
+		t.Errorf("attendees = %+v, want only a", r.Attendees)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -25,7 +25,13 @@ type Command struct {
 	Description string
 	Options     []*discordgo.ApplicationCommandOption
 	AppCommand  *discordgo.ApplicationCommand
-	Executor    func(s *discordgo.Session, i *discordgo.InteractionCreate)
+	Executor    func(s *discordgo.Session, i *discordgo.InteractionCreate) error // 返したエラーはルーターが応答に変換する
+
+	// 既定で実行できるメンバーの権限（nilなら全員）
+	DefaultMemberPermissions *int64
+
+	// ボタンなどのコンポーネントの処理（CustomIDが "<Name>:" で始まるものを受け取る）
+	ComponentExecutor func(s *discordgo.Session, i *discordgo.InteractionCreate) error
 }
 
 func (c *Command) AddApplicationCommand(appCmd *discordgo.ApplicationCommand) {
@@ -0,0 +1,124 @@
+package botRouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// コマンドのエラーの種類
+type ErrorKind int
+
+const (
+	// 入力の誤りなど、利用者が対処できるエラー（文言をそのまま伝える）
+	KindUser ErrorKind = iota
+	// レート制限や通信の失敗など、時間をおけば成功する可能性があるエラー
+	KindTransient
+	// Botの不具合など想定外のエラー（ログチャンネルに報告する）
+	KindInternal
+)
+
+// Executorが返すエラー
+type CommandError struct {
+	Kind    ErrorKind
+	Message string // 利用者に表示する文言（空なら種類ごとの既定の文言）
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// 利用者に伝えるエラーを返す
+func UserError(message string) error {
+	return &CommandError{Kind: KindUser, Message: message}
+}
+
+// 時間をおけば成功する可能性があるエラーを返す
+func TransientError(message string, err error) error {
+	return &CommandError{Kind: KindTransient, Message: message, Err: err}
+}
+
+// 想定外のエラーを返す
+func InternalError(message string, err error) error {
+	return &CommandError{Kind: KindInternal, Message: message, Err: err}
+}
+
+// エラーを種類つきのエラーに変換する
+// 種類が付いていないエラーは、Discordのレート制限・サーバーエラーと通信エラーを一時的なものとみなす
+func AsCommandError(err error) *CommandError {
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return ce
+	}
+	if isTransient(err) {
+		return &CommandError{Kind: KindTransient, Err: err}
+	}
+	return &CommandError{Kind: KindInternal, Err: err}
+}
+
+// 利用者に表示する文言を差し替えたエラーを返す（種類はそのまま）
+func (e *CommandError) WithMessage(message string) error {
+	return &CommandError{Kind: e.Kind, Message: message, Err: e.Err}
+}
+
+func isTransient(err error) bool {
+	var rest *discordgo.RESTError
+	if errors.As(err, &rest) && rest.Response != nil {
+		code := rest.Response.StatusCode
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	var rateLimit *discordgo.RateLimitError
+	if errors.As(err, &rateLimit) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// 再試行の回数と最初の待ち時間
+const (
+	retryAttempts = 3
+	retryBackoff  = time.Second
+)
+
+// 一時的なエラーの間は待ち時間を倍にしながら fn を再試行する
+func Retry(fn func() error) error {
+	wait := retryBackoff
+	var err error
+	for n := 0; n < retryAttempts; n++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if AsCommandError(err).Kind != KindTransient {
+			return err
+		}
+		if n < retryAttempts-1 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	return fmt.Errorf("%d 回試行しましたが失敗しました: %w", retryAttempts, err)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
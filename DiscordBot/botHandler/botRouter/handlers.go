@@ -2,6 +2,7 @@ package botRouter
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -10,6 +11,9 @@ type Handler struct {
 	session  *discordgo.Session
 	commands map[string]*Command
 	guild    string
+
+	// 内部エラーを報告するチャンネル（空なら報告しない）
+	errorChannelID string
 }
 
 // ハンドラーの登録（未使用部分）
@@ -28,6 +32,11 @@ func NewCommandHandler(session *discordgo.Session, guildID string) *Handler {
 	}
 }
 
+// 内部エラーを報告するチャンネルを設定する
+func (h *Handler) SetErrorChannel(channelID string) {
+	h.errorChannelID = channelID
+}
+
 // スラッシュコマンドの登録
 func (h *Handler) CommandRegister(command *Command) error {
 	if _, exists := h.commands[command.Name]; exists {
@@ -42,6 +51,8 @@ func (h *Handler) CommandRegister(command *Command) error {
 			Name:          command.Name,
 			Description:   command.Description,
 			Options:       command.Options,
+
+			DefaultMemberPermissions: command.DefaultMemberPermissions,
 		},
 	)
 	if err != nil {
@@ -56,7 +67,11 @@ func (h *Handler) CommandRegister(command *Command) error {
 		func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 			if i.Type == discordgo.InteractionApplicationCommand &&
 				i.ApplicationCommandData().Name == command.Name {
-				command.Executor(s, i)
+				h.execute(s, i, command.Name, command.Executor)
+			}
+			if i.Type == discordgo.InteractionMessageComponent && command.ComponentExecutor != nil &&
+				strings.HasPrefix(i.MessageComponentData().CustomID, command.Name+":") {
+				h.execute(s, i, command.Name, command.ComponentExecutor)
 			}
 		},
 	)
@@ -0,0 +1,142 @@
+package botRouter
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 種類ごとの既定の文言（言語ごと）
+var errorMessages = map[ErrorKind]map[discordgo.Locale]string{
+	KindUser: {
+		discordgo.Japanese:  "コマンドを実行できませんでした。",
+		discordgo.EnglishUS: "The command could not be run.",
+	},
+	KindTransient: {
+		discordgo.Japanese:  "Discordまたは外部サービスが混み合っています。しばらく待ってからもう一度お試しください。",
+		discordgo.EnglishUS: "Discord or an external service is busy. Please try again in a moment.",
+	},
+	KindInternal: {
+		discordgo.Japanese:  "予期しないエラーが発生しました。管理者に報告しました。",
+		discordgo.EnglishUS: "An unexpected error occurred. It has been reported to the administrators.",
+	},
+}
+
+// 利用者の言語に合わせた既定の文言を返す（未対応の言語は日本語）
+func localizedMessage(kind ErrorKind, locale discordgo.Locale) string {
+	messages := errorMessages[kind]
+	if strings.HasPrefix(string(locale), "en") {
+		return messages[discordgo.EnglishUS]
+	}
+	return messages[discordgo.Japanese]
+}
+
+// Executorを実行し、返されたエラーやpanicを利用者への応答に変換する
+// どのコマンドでもプロセスが終了しないようにする
+func (h *Handler) execute(s *discordgo.Session, i *discordgo.InteractionCreate, name string, fn func(s *discordgo.Session, i *discordgo.InteractionCreate) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.handleError(s, i, name, InternalError("", fmt.Errorf("panic: %v\n%s", r, debug.Stack())))
+		}
+	}()
+	if err := fn(s, i); err != nil {
+		h.handleError(s, i, name, err)
+	}
+}
+
+func (h *Handler) handleError(s *discordgo.Session, i *discordgo.InteractionCreate, name string, err error) {
+	ce := AsCommandError(err)
+	if ce.Kind != KindUser {
+		log.Printf("コマンド %s でエラーが発生しました: %v\n", name, err)
+	}
+
+	// 元のエラーの詳細は利用者に見せない
+	message := ce.Message
+	if message == "" {
+		message = localizedMessage(ce.Kind, i.Locale)
+	}
+	respondError(s, i, message)
+
+	if ce.Kind == KindInternal {
+		h.reportError(s, i, name, err)
+	}
+}
+
+// 実行した本人にだけ見えるメッセージでエラーを伝える
+// すでに一時応答を返している場合は、その応答をエラーに置き換える
+// 公開の一時応答は書き換えると全員に見えるため、削除してから本人にだけフォローアップを送る
+func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err == nil {
+		return
+	}
+
+	if original, err := s.InteractionResponse(i.Interaction); err == nil {
+		if original.Flags&discordgo.MessageFlagsEphemeral != 0 {
+			embeds := []*discordgo.MessageEmbed{}
+			components := []discordgo.MessageComponent{}
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content:    &message,
+				Embeds:     &embeds,
+				Components: &components,
+			})
+			if err == nil {
+				return
+			}
+		} else if err := s.InteractionResponseDelete(i.Interaction); err != nil {
+			log.Printf("一時応答の削除に失敗しました: %v\n", err)
+		}
+	}
+	_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: message,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		log.Printf("エラーの応答に失敗しました: %v\n", err)
+	}
+}
+
+// 内部エラーをログチャンネルに報告する
+func (h *Handler) reportError(s *discordgo.Session, i *discordgo.InteractionCreate, name string, err error) {
+	if h.errorChannelID == "" {
+		return
+	}
+
+	user := ""
+	if i.Member != nil && i.Member.User != nil {
+		user = "<@" + i.Member.User.ID + ">"
+	} else if i.User != nil {
+		user = "<@" + i.User.ID + ">"
+	}
+	detail := err.Error()
+	if r := []rune(detail); len(r) > 1000 {
+		detail = string(r[:1000]) + "…"
+	}
+
+	_, sendErr := s.ChannelMessageSendEmbed(h.errorChannelID, &discordgo.MessageEmbed{
+		Title: "コマンドの内部エラー",
+		Color: 0xED4245,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "コマンド", Value: "/" + name, Inline: true},
+			{Name: "実行者", Value: user, Inline: true},
+			{Name: "場所", Value: fmt.Sprintf("サーバー `%s` / <#%s>", i.GuildID, i.ChannelID)},
+			{Name: "内容", Value: "```\n" + detail + "\n```"},
+		},
+	})
+	if sendErr != nil {
+		log.Printf("ログチャンネルへの報告に失敗しました: %v\n", sendErr)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
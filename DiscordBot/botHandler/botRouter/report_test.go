@@ -0,0 +1,120 @@
+package botRouter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// インタラクションの応答とWebhookのREST APIを真似るサーバー
+// 一時応答は返し済みとして、最初の応答は常に失敗させる
+type fakeInteraction struct {
+	flags discordgo.MessageFlags // 一時応答のフラグ
+
+	mu       sync.Mutex
+	requests []string // "メソッド パス"
+	bodies   []string
+}
+
+func (f *fakeInteraction) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.bodies = append(f.bodies, string(body))
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/callback"):
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"code":40060,"message":"Interaction has already been acknowledged."}`)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		json.NewEncoder(w).Encode(&discordgo.Message{ID: "original", Flags: f.flags})
+	}
+}
+
+func (f *fakeInteraction) has(request string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for n, r := range f.requests {
+		if r == request {
+			return f.bodies[n], true
+		}
+	}
+	return "", false
+}
+
+func newFakeInteractionSession(t *testing.T, f *fakeInteraction) *discordgo.Session {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	savedAPI, savedWebhooks := discordgo.EndpointAPI, discordgo.EndpointWebhooks
+	discordgo.EndpointAPI = srv.URL + "/"
+	discordgo.EndpointWebhooks = srv.URL + "/webhooks/"
+	t.Cleanup(func() {
+		discordgo.EndpointAPI, discordgo.EndpointWebhooks = savedAPI, savedWebhooks
+		srv.Close()
+	})
+
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Client = srv.Client()
+	return s
+}
+
+func testInteraction() *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: "interaction", AppID: "app", Token: "token"}}
+}
+
+func TestRespondErrorEditsEphemeralResponse(t *testing.T) {
+	f := &fakeInteraction{flags: discordgo.MessageFlagsEphemeral}
+	s := newFakeInteractionSession(t, f)
+
+	respondError(s, testInteraction(), "失敗しました")
+
+	body, ok := f.has("PATCH /webhooks/app/token/messages/@original")
+	if !ok || !strings.Contains(body, "失敗しました") {
+		t.Errorf("ephemeral response was not replaced: %v", f.requests)
+	}
+	if _, ok := f.has("POST /webhooks/app/token"); ok {
+		t.Error("follow-up was sent for an ephemeral response")
+	}
+}
+
+func TestRespondErrorDeletesPublicResponse(t *testing.T) {
+	f := &fakeInteraction{}
+	s := newFakeInteractionSession(t, f)
+
+	respondError(s, testInteraction(), "失敗しました")
+
+	if _, ok := f.has("DELETE /webhooks/app/token/messages/@original"); !ok {
+		t.Errorf("public placeholder was not deleted: %v", f.requests)
+	}
+	if _, ok := f.has("PATCH /webhooks/app/token/messages/@original"); ok {
+		t.Error("public placeholder was edited with the error")
+	}
+	body, ok := f.has("POST /webhooks/app/token")
+	if !ok {
+		t.Fatalf("no follow-up was sent: %v", f.requests)
+	}
+	var params discordgo.WebhookParams
+	if err := json.Unmarshal([]byte(body), &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Flags&discordgo.MessageFlagsEphemeral == 0 || params.Content != "失敗しました" {
+		t.Errorf("follow-up = %+v, want an ephemeral error", params)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"main/botHandler/botRouter"
+	"main/guildarchive"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 進捗表示を更新する間隔
+const archiveProgressInterval = 5 * time.Second
+
+func ArchiveCommand(archiver *guildarchive.Archiver) *botRouter.Command {
+	/*
+		archiveコマンドの定義
+
+		コマンド名: archive
+		説明: サーバー全体のメッセージと添付ファイルをzipにまとめます
+		オプション: restart (中断したアーカイブを破棄して最初からやり直す)
+	*/
+	permission := int64(discordgo.PermissionManageServer)
+	return &botRouter.Command{
+		Name:        "archive",
+		Description: "サーバー全体のメッセージと添付ファイルをzipにまとめます",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "restart",
+				Description: "中断したアーカイブを続きから再開せず、最初からやり直す",
+			},
+		},
+		DefaultMemberPermissions: &permission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleArchive(s, i, archiver)
+		},
+	}
+}
+
+func handleArchive(s *discordgo.Session, i *discordgo.InteractionCreate, archiver *guildarchive.Archiver) error {
+	/*
+		archiveコマンドの実行
+
+		非公開のチャンネルやスレッドも含むため、進捗は本人だけに見える一時応答で表示し、
+		完成したzipは実行した人にDMで送る
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "archive" {
+		return nil
+	}
+
+	restart := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "restart" {
+			restart = opt.BoolValue()
+		}
+	}
+
+	// 3秒以内に一時応答を返す
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 時間のかかる取得を始める前に、DMを受け取れるか確かめる
+	dm, err := s.UserChannelCreate(i.Member.User.ID)
+	if err == nil {
+		_, err = s.ChannelMessageSend(dm.ID, "サーバーのアーカイブを作成しています。完成したらここに送ります。")
+	}
+	if err != nil {
+		return botRouter.UserError("DMを送信できませんでした。サーバーメンバーからのDMを許可してから再度実行してください。")
+	}
+
+	var mu sync.Mutex
+	var lastEdit time.Time
+	progress := func(p guildarchive.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(lastEdit) < archiveProgressInterval {
+			return
+		}
+		lastEdit = time.Now()
+		editWithError(s, i, formatArchiveProgress(p))
+	}
+
+	result, err := archiver.Run(context.Background(), i.GuildID, restart, progress)
+	if errors.Is(err, guildarchive.ErrRunning) {
+		return botRouter.UserError(err.Error())
+	}
+	if err != nil {
+		return botRouter.AsCommandError(err).WithMessage("アーカイブの作成に失敗しました。\n再度 /archive を実行すると続きから再開します。")
+	}
+	// 送り終えたらサーバーには残さない
+	defer result.Remove()
+
+	summary := fmt.Sprintf("アーカイブを作成しました（チャンネル %d 件 / メッセージ %d 件 / %.1fMB）",
+		result.Channels, result.Messages, float64(result.Size)/1024/1024)
+	if len(result.Paths) > 1 {
+		summary += fmt.Sprintf("\n%d 個のzipに分割しています。すべて同じフォルダに展開してください。", len(result.Paths))
+	}
+	if len(result.RedactionKeys) > 0 {
+		summary += "\n個人情報を伏せ字にしました。復元キー: `" + strings.Join(result.RedactionKeys, "`, `") + "`"
+	}
+
+	// 応答の有効期限（15分）を過ぎていることがあるため、結果はDMに送る
+	// 1通に添付できる大きさに収まるよう、分割したzipを1つずつ送る
+	if _, err := s.ChannelMessageSend(dm.ID, summary); err != nil {
+		return botRouter.AsCommandError(err).WithMessage("アーカイブをDMで送信できませんでした。")
+	}
+	for n, path := range result.Paths {
+		if err := sendArchivePart(s, dm.ID, path, n+1, len(result.Paths)); err != nil {
+			return botRouter.AsCommandError(err).WithMessage("アーカイブをDMで送信できませんでした。")
+		}
+	}
+	editWithError(s, i, summary+"\nzipはDMに送りました。")
+	return nil
+}
+
+// 分割したzipの1つをDMに送る
+func sendArchivePart(s *discordgo.Session, channelID, path string, n, total int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("%d/%d", n, total),
+		Files:   []*discordgo.File{{Name: filepath.Base(path), ContentType: "application/zip", Reader: f}},
+	})
+	return err
+}
+
+func formatArchiveProgress(p guildarchive.Progress) string {
+	text := fmt.Sprintf("アーカイブを作成中です… %d/%d チャンネル（メッセージ %d 件）",
+		p.ChannelsDone, p.ChannelsTotal, p.Messages)
+	if p.Current != "" {
+		text += "\n取得中: " + p.Current
+	}
+	if p.Resumed {
+		text += "\n（前回の続きから再開しています）"
+	}
+	return text
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"main/attendance"
+	"main/botHandler/botRouter"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func AttendanceCommand(tracker *attendance.Tracker) *botRouter.Command {
+	/*
+		attendanceコマンドの定義
+
+		コマンド名: attendance
+		説明: 会議の出席を記録します
+		サブコマンド: start, end, report, list
+	*/
+	return &botRouter.Command{
+		Name:        "attendance",
+		Description: "ボイスチャンネルでの会議の出席を記録します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "start",
+				Description: "参加中のボイスチャンネルで会議を開始します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "会議名",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "end",
+				Description: "開始中の会議を終了します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "report",
+				Description: "会議の出席記録を表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "meeting",
+						Description: "会議名またはID（省略時は最新の会議）",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "最近の会議を表示します",
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleAttendance(s, i, tracker)
+		},
+	}
+}
+
+func handleAttendance(s *discordgo.Session, i *discordgo.InteractionCreate, tracker *attendance.Tracker) error {
+	/*
+		attendanceコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "attendance" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "start":
+		vs, err := s.State.VoiceState(i.GuildID, i.Interaction.Member.User.ID)
+		if err != nil || vs == nil {
+			return botRouter.UserError("ボイスチャンネルに入ってください")
+		}
+		m, err := tracker.StartMeeting(s, i.GuildID, vs.ChannelID, args["name"].StringValue(), "manual")
+		if err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return responseText(s, i, fmt.Sprintf("会議「%s」(ID: %s) の出席記録を開始しました <#%s>", m.Name, m.ID, m.ChannelID))
+
+	case "end":
+		m, err := tracker.EndMeeting(i.GuildID)
+		if err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return sendAttendanceReport(s, i, tracker, m)
+
+	case "report":
+		key := ""
+		if opt, ok := args["meeting"]; ok {
+			key = opt.StringValue()
+		}
+		m, err := tracker.FindMeeting(i.GuildID, key)
+		if err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return sendAttendanceReport(s, i, tracker, m)
+
+	case "list":
+		meetings := tracker.Meetings(i.GuildID)
+		if len(meetings) == 0 {
+			return responseText(s, i, "記録された会議はありません")
+		}
+		var lines []string
+		for n, m := range meetings {
+			if n >= 10 {
+				break
+			}
+			status := "終了"
+			if m.EndedAt == nil {
+				status = "開催中"
+			}
+			lines = append(lines, fmt.Sprintf("`%s` %s（%s, %s）", m.ID, m.Name, m.StartedAt.Format("2006/01/02 15:04"), status))
+		}
+		return responseText(s, i, strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// 出席記録を埋め込みとCSVファイルで返す
+func sendAttendanceReport(s *discordgo.Session, i *discordgo.InteractionCreate, tracker *attendance.Tracker, m *attendance.Meeting) error {
+	report, err := tracker.Report(m)
+	if err != nil {
+		return botRouter.InternalError("出席記録の集計に失敗しました", err)
+	}
+	data, err := report.CSV()
+	if err != nil {
+		return botRouter.InternalError("出席記録の出力に失敗しました", err)
+	}
+
+	end := "開催中"
+	if m.EndedAt != nil {
+		end = m.EndedAt.Format("15:04")
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "出席記録: " + m.Name,
+		Description: fmt.Sprintf("<#%s>\n%s 〜 %s\n出席者 %d 名", m.ChannelID, m.StartedAt.Format("2006/01/02 15:04"), end, len(report.Attendees)),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "会議ID: " + m.ID},
+	}
+	for n, a := range report.Attendees {
+		// 埋め込みのフィールドは25個まで
+		if n >= 25 {
+			break
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   a.Name,
+			Value:  fmt.Sprintf("%s〜%s（%d分）", a.FirstJoin.Format("15:04"), a.LastLeave.Format("15:04"), int(a.Duration/time.Minute)),
+			Inline: true,
+		})
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Files: []*discordgo.File{
+				{
+					Name:        fmt.Sprintf("attendance_%s.csv", m.ID),
+					ContentType: "text/csv",
+					Reader:      bytes.NewReader(data),
+				},
+			},
+		},
+	})
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+
+	"main/crawler"
+	"main/exporter"
+	"main/messagestore"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 会話を集めるときの条件
+type conversationScope struct {
+	GuildID        string
+	ChannelID      string
+	ViewerID       string // スレッドを閲覧できるか確認するユーザー
+	IncludeThreads bool
+	// 各チャンネル・スレッドの古い順のメッセージから、出力するものを選ぶ（nilならすべて）
+	Pick func([]*discordgo.Message) []*discordgo.Message
+}
+
+// チャンネルのメッセージを取得し、指定があればスレッド・フォーラム投稿も入れ子にして集める
+// フォーラムはそれ自体にメッセージがないため、常に投稿を集める
+func collectConversation(s *discordgo.Session, store *messagestore.Store, scope *conversationScope) (*exporter.Section, error) {
+	ch, err := s.State.Channel(scope.ChannelID)
+	if err != nil {
+		if ch, err = s.Channel(scope.ChannelID); err != nil {
+			return nil, err
+		}
+	}
+	root := &exporter.Section{ID: ch.ID, Title: ch.Name, Kind: exporter.SectionChannel}
+	if ch.IsThread() {
+		root.Kind = exporter.SectionThread
+	}
+
+	forum := ch.Type == discordgo.ChannelTypeGuildForum
+	if !forum {
+		if root.Records, err = conversationRecords(s, store, scope, ch.ID); err != nil {
+			return nil, err
+		}
+	}
+	if !scope.IncludeThreads && !forum || ch.IsThread() {
+		return root, nil
+	}
+
+	threads, err := crawler.ChannelThreads(s, scope.GuildID, ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	var sections []*exporter.Section
+	for _, th := range threads {
+		if !canViewThread(s, th, scope.ViewerID) {
+			continue
+		}
+		section := &exporter.Section{ID: th.ID, Title: th.Name, Kind: exporter.SectionThread}
+		if forum {
+			section.Kind = exporter.SectionForumPost
+		} else {
+			// メッセージから作成したスレッドは、そのメッセージと同じIDになる
+			section.StarterID = th.ID
+		}
+		sections = append(sections, section)
+	}
+
+	// スレッドは数が多くなりやすいため、並行して取得する
+	errs := crawler.Run(context.Background(), crawler.DefaultWorkers, len(sections), func(ctx context.Context, n int) (err error) {
+		sections[n].Records, err = conversationRecords(s, store, scope, sections[n].ID)
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	root.Sections = sections
+	return root, nil
+}
+
+func conversationRecords(s *discordgo.Session, store *messagestore.Store, scope *conversationScope, channelID string) ([]*exporter.Record, error) {
+	messages, err := syncedMessages(s, store, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Pick != nil {
+		messages = scope.Pick(messages)
+	}
+	records := make([]*exporter.Record, 0, len(messages))
+	for _, m := range messages {
+		records = append(records, exporter.FromMessage(scope.GuildID, m))
+	}
+	return records, nil
+}
+
+// ユーザーがチャンネルまたはスレッドのメッセージ履歴を読めるか
+// スレッドは親チャンネルの権限で判定し、非公開スレッドは参加しているかも確かめる
+func canReadChannel(s *discordgo.Session, channelID, userID string) bool {
+	ch, err := s.State.Channel(channelID)
+	if err != nil {
+		if ch, err = s.Channel(channelID); err != nil {
+			return false
+		}
+	}
+	if !ch.IsThread() {
+		return viewableChecker(s, userID)(channelID)
+	}
+	return viewableChecker(s, userID)(ch.ParentID) && canViewThread(s, ch, userID)
+}
+
+// 非公開スレッドは参加者とスレッドの管理権限を持つユーザーだけが読める
+func canViewThread(s *discordgo.Session, th *discordgo.Channel, userID string) bool {
+	if th.Type != discordgo.ChannelTypeGuildPrivateThread || userID == "" {
+		return true
+	}
+	if perms, err := s.State.UserChannelPermissions(userID, th.ParentID); err == nil && perms&discordgo.PermissionManageThreads != 0 {
+		return true
+	}
+	_, err := s.ThreadMember(th.ID, userID)
+	return err == nil
+}
+
+// サーバーの全員（@everyone）がチャンネルまたはスレッドのメッセージ履歴を読めるか
+// スレッドは親チャンネルで判定し、非公開スレッドは全員には読めないものとする
+func publicChannel(s *discordgo.Session, channelID string) bool {
+	ch, err := s.State.Channel(channelID)
+	if err != nil {
+		if ch, err = s.Channel(channelID); err != nil {
+			return false
+		}
+	}
+	if ch.IsThread() {
+		if ch.Type == discordgo.ChannelTypeGuildPrivateThread {
+			return false
+		}
+		return publicChannel(s, ch.ParentID)
+	}
+	g, err := s.State.Guild(ch.GuildID)
+	if err != nil {
+		return false
+	}
+
+	// @everyoneのロールはギルドと同じIDになる
+	var perms int64
+	for _, r := range g.Roles {
+		if r.ID == g.ID {
+			perms = r.Permissions
+		}
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return true
+	}
+	for _, o := range ch.PermissionOverwrites {
+		if o.Type == discordgo.PermissionOverwriteTypeRole && o.ID == g.ID {
+			perms &^= o.Deny
+			perms |= o.Allow
+		}
+	}
+	need := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	return perms&need == need
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
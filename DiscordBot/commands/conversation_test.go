@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPublicChannel(t *testing.T) {
+	view := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	everyone := func(allow, deny int64) []*discordgo.PermissionOverwrite {
+		return []*discordgo.PermissionOverwrite{{ID: "guild", Type: discordgo.PermissionOverwriteTypeRole, Allow: allow, Deny: deny}}
+	}
+
+	s, _ := discordgo.New("")
+	s.State.GuildAdd(&discordgo.Guild{ID: "guild", Roles: []*discordgo.Role{{ID: "guild", Permissions: view}}})
+	for _, ch := range []*discordgo.Channel{
+		{ID: "general", Type: discordgo.ChannelTypeGuildText},
+		{ID: "staff", Type: discordgo.ChannelTypeGuildText, PermissionOverwrites: everyone(0, discordgo.PermissionViewChannel)},
+		{ID: "history-hidden", Type: discordgo.ChannelTypeGuildText, PermissionOverwrites: everyone(0, discordgo.PermissionReadMessageHistory)},
+		{ID: "public-thread", Type: discordgo.ChannelTypeGuildPublicThread, ParentID: "general"},
+		{ID: "private-thread", Type: discordgo.ChannelTypeGuildPrivateThread, ParentID: "general"},
+		{ID: "staff-thread", Type: discordgo.ChannelTypeGuildPublicThread, ParentID: "staff"},
+	} {
+		ch.GuildID = "guild"
+		s.State.ChannelAdd(ch)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		channelID string
+		want      bool
+	}{
+		{"公開チャンネル", "general", true},
+		{"@everyoneが見られないチャンネル", "staff", false},
+		{"履歴を読めないチャンネル", "history-hidden", false},
+		{"公開チャンネルの公開スレッド", "public-thread", true},
+		{"非公開スレッド", "private-thread", false},
+		{"非公開チャンネルの公開スレッド", "staff-thread", false},
+	} {
+		if got := publicChannel(s, tc.channelID); got != tc.want {
+			t.Errorf("%s: publicChannel = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
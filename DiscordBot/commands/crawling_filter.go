@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"main/exporter"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// crawlingで取得するメッセージの条件
+type crawlFilter struct {
+	ChannelID   string
+	UserID      string // 空なら全員
+	After       time.Time
+	Before      time.Time
+	IncludeBots bool
+	MaxCount    int  // 0なら上限なし（スレッドごとに数える）
+	Threads     bool // スレッド・フォーラム投稿も含める
+}
+
+// 日付として受け付ける形式
+var crawlDateLayouts = []string{
+	"2006-01-02 15:04",
+	"2006/01/02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// コマンドのオプションから条件を作る
+// 既定では実行したユーザーのメッセージだけを取得する
+func parseCrawlFilter(i *discordgo.InteractionCreate) (*crawlFilter, error) {
+	f := &crawlFilter{
+		ChannelID: i.ChannelID,
+		UserID:    i.Member.User.ID,
+	}
+	allUsers := false
+
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "user":
+			f.UserID = opt.UserValue(nil).ID
+		case "all_users":
+			allUsers = opt.BoolValue()
+		case "channel":
+			f.ChannelID = opt.ChannelValue(nil).ID
+		case "after":
+			t, err := parseCrawlDate(opt.StringValue())
+			if err != nil {
+				return nil, err
+			}
+			f.After = t
+		case "before":
+			t, err := parseCrawlDate(opt.StringValue())
+			if err != nil {
+				return nil, err
+			}
+			f.Before = t
+		case "include_bots":
+			f.IncludeBots = opt.BoolValue()
+		case "limit":
+			f.MaxCount = int(opt.IntValue())
+		case "include_threads":
+			f.Threads = opt.BoolValue()
+		}
+	}
+	// スレッドの指定はチャンネルより優先する
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "thread" {
+			f.ChannelID = opt.ChannelValue(nil).ID
+		}
+	}
+
+	if allUsers {
+		f.UserID = ""
+	}
+	if !f.After.IsZero() && !f.Before.IsZero() && !f.After.Before(f.Before) {
+		return nil, fmt.Errorf("after には before より前の日時を指定してください")
+	}
+	return f, nil
+}
+
+func parseCrawlDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range crawlDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("日付の形式が正しくありません: %s（例: 2025-04-01）", value)
+}
+
+// メッセージが条件に合うか判定する
+func (f *crawlFilter) matches(m *discordgo.Message) bool {
+	if m.Author == nil {
+		return false
+	}
+	if f.UserID != "" && m.Author.ID != f.UserID {
+		return false
+	}
+	if !f.IncludeBots && m.Author.Bot {
+		return false
+	}
+	if !f.After.IsZero() && m.Timestamp.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !m.Timestamp.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// 条件の説明文を返す
+func (f *crawlFilter) describe() string {
+	var parts []string
+	parts = append(parts, "<#"+f.ChannelID+">")
+	if f.UserID == "" {
+		parts = append(parts, "全員")
+	} else {
+		parts = append(parts, "<@"+f.UserID+">")
+	}
+	if !f.After.IsZero() {
+		parts = append(parts, f.After.Format("2006/01/02 15:04")+"以降")
+	}
+	if !f.Before.IsZero() {
+		parts = append(parts, f.Before.Format("2006/01/02 15:04")+"より前")
+	}
+	if f.IncludeBots {
+		parts = append(parts, "Botを含む")
+	}
+	if f.MaxCount > 0 {
+		parts = append(parts, fmt.Sprintf("最大%d件", f.MaxCount))
+	}
+	if f.Threads {
+		parts = append(parts, "スレッドを含む")
+	}
+	return strings.Join(parts, " / ")
+}
+
+// 古い順のメッセージから条件に合うものを古い順に返す
+// 件数の上限がある場合は新しいものから数える
+func (f *crawlFilter) pick(all []*discordgo.Message) []*discordgo.Message {
+	var messages []*discordgo.Message
+	for n := len(all) - 1; n >= 0; n-- {
+		m := all[n]
+		if !f.After.IsZero() && m.Timestamp.Before(f.After) {
+			break
+		}
+		if f.matches(m) {
+			messages = append(messages, m)
+			if f.MaxCount > 0 && len(messages) >= f.MaxCount {
+				break
+			}
+		}
+	}
+
+	// 古い順に並べ替える
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+// 出力形式と出力前のフィルターをオプションから決める
+func parseExportOptions(i *discordgo.InteractionCreate) (exporter.Format, []exporter.Filter, error) {
+	format := exporter.FormatText
+	var filters []exporter.Filter
+
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "format":
+			f, err := exporter.ParseFormat(opt.StringValue())
+			if err != nil {
+				return "", nil, err
+			}
+			format = f
+		case "strip_urls":
+			if opt.BoolValue() {
+				filters = append(filters, exporter.StripURLs())
+			}
+		case "strip_emoji":
+			if opt.BoolValue() {
+				filters = append(filters, exporter.StripCustomEmoji())
+			}
+		}
+	}
+	// 加工した結果、空になったメッセージは出力しない
+	filters = append(filters, exporter.SkipEmpty())
+	return format, filters, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
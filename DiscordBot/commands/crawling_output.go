@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"main/exporter"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 出力を添付ファイルの上限に収まるよう分割して書き出す
+// 分割はメッセージ単位で行い、各ファイルはそれぞれ単体で読める形式にする
+// メッセージごとの大きさは1件だけを書き出した大きさで見積もる（見出しの分だけ実際より大きくなるため、上限を超えることはない）
+func exportParts(format exporter.Format, title string, records []*exporter.Record, limit int) ([][]byte, error) {
+	var all bytes.Buffer
+	if err := exporter.Export(&all, format, title, records); err != nil {
+		return nil, err
+	}
+	if all.Len() <= limit || len(records) <= 1 {
+		return [][]byte{all.Bytes()}, nil
+	}
+
+	// タイトルやヘッダーなど各ファイルに共通する部分の大きさ。タイトルは最も長くなる番号で見積もる
+	longest := partTitle(title, len(records), len(records))
+	overhead, err := exportSize(format, longest, nil)
+	if err != nil {
+		return nil, err
+	}
+	var groups [][]*exporter.Record
+	var current []*exporter.Record
+	size := overhead
+	for _, r := range records {
+		n, err := exportSize(format, longest, []*exporter.Record{r})
+		if err != nil {
+			return nil, err
+		}
+		if n > limit {
+			return nil, fmt.Errorf("1件のメッセージが添付ファイルの上限を超えています")
+		}
+		n -= overhead
+		if size+n > limit && len(current) > 0 {
+			groups = append(groups, current)
+			current, size = nil, overhead
+		}
+		current = append(current, r)
+		size += n
+	}
+	groups = append(groups, current)
+
+	parts := make([][]byte, 0, len(groups))
+	for k, group := range groups {
+		var buf bytes.Buffer
+		if err := exporter.Export(&buf, format, partTitle(title, k+1, len(groups)), group); err != nil {
+			return nil, err
+		}
+		parts = append(parts, buf.Bytes())
+	}
+	return parts, nil
+}
+
+func partTitle(title string, n, total int) string {
+	return fmt.Sprintf("%s（%d/%d）", title, n, total)
+}
+
+// 書き出した場合の大きさ（バイト数）
+func exportSize(format exporter.Format, title string, records []*exporter.Record) (int, error) {
+	var w byteCounter
+	err := exporter.Export(&w, format, title, records)
+	return int(w), err
+}
+
+type byteCounter int
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// 分割したファイルをインタラクションの応答として送信する
+// 1つ目は一時応答を書き換え、残りはフォローアップとして送る（ephemeralなら本人にだけ見える）
+func sendExportParts(s *discordgo.Session, i *discordgo.InteractionCreate, content, baseName string, format exporter.Format, parts [][]byte, ephemeral bool) error {
+	file := func(n int) *discordgo.File {
+		name := baseName + "." + format.Ext()
+		if len(parts) > 1 {
+			name = fmt.Sprintf("%s_part%d.%s", baseName, n+1, format.Ext())
+		}
+		return &discordgo.File{
+			Name:        name,
+			ContentType: format.ContentType(),
+			Reader:      bytes.NewReader(parts[n]),
+		}
+	}
+
+	if len(parts) > 1 {
+		content += fmt.Sprintf("\nファイルが大きいため %d 個に分割しました。", len(parts))
+	}
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Files:   []*discordgo.File{file(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	var flags discordgo.MessageFlags
+	if ephemeral {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	for n := 1; n < len(parts); n++ {
+		_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Files: []*discordgo.File{file(n)},
+			Flags: flags,
+		})
+		if err != nil {
+			log.Printf("分割ファイルの送信に失敗しました (%d/%d): %v\n", n+1, len(parts), err)
+			return err
+		}
+	}
+	return nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"main/exporter"
+)
+
+func testRecords(n int) []*exporter.Record {
+	base := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+	records := make([]*exporter.Record, 0, n)
+	for i := 0; i < n; i++ {
+		r := &exporter.Record{
+			ID:        strconv.Itoa(i),
+			Author:    "user",
+			Content:   "message-" + strconv.Itoa(i) + "-end",
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			URL:       "https://discord.com/channels/g/c/" + strconv.Itoa(i),
+		}
+		// スレッドの見出しをまたぐ分割も確かめる
+		if i%7 == 3 {
+			r.ThreadID, r.Thread, r.ThreadKind = "t", "thread", "thread"
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestExportPartsFitLimit(t *testing.T) {
+	records := testRecords(200)
+	for _, f := range exporter.Formats {
+		const limit = 2048
+		parts, err := exportParts(f, "メッセージ", records, limit)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if len(parts) < 2 {
+			t.Errorf("%s: got %d parts, want a split", f, len(parts))
+		}
+		found := 0
+		for n, p := range parts {
+			if len(p) > limit {
+				t.Errorf("%s: part %d is %d bytes, over %d", f, n+1, len(p), limit)
+			}
+			for _, r := range records {
+				if bytes.Contains(p, []byte(r.Content)) {
+					found++
+				}
+			}
+		}
+		if found != len(records) {
+			t.Errorf("%s: %d of %d messages found across parts", f, found, len(records))
+		}
+	}
+}
+
+func TestExportPartsOversizedMessage(t *testing.T) {
+	records := testRecords(3)
+	records[1].Content = strings.Repeat("あ", 2000)
+	if _, err := exportParts(exporter.FormatText, "メッセージ", records, 1024); err == nil {
+		t.Error("expected an error for a message over the limit")
+	}
+}
+
+func BenchmarkExportParts(b *testing.B) {
+	records := testRecords(5000)
+	for i := 0; i < b.N; i++ {
+		if _, err := exportParts(exporter.FormatMarkdown, "メッセージ", records, 64*1024); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
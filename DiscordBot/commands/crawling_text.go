@@ -2,117 +2,191 @@ package commands
 
 import (
 	"fmt"
-	"log"
 	"main/botHandler/botRouter"
-	"os"
-	"regexp"
+	"main/exporter"
+	"main/messagestore"
+	"main/redact"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func CrawlingTextCommand() *botRouter.Command {
+func CrawlingTextCommand(store *messagestore.Store, redactor *redact.Engine) *botRouter.Command {
 	/*
 		コマンド名: crawling
-		説明: メッセージを取得して .txt ファイルに保存します
-		オプション: なし
+		説明: メッセージを取得してファイルに保存します
+		オプション: user, all_users, channel, thread, after, before, include_bots, limit,
+		          include_threads, format, strip_urls, strip_emoji
 	*/
+	minLimit := 1.0
 	return &botRouter.Command{
 		Name:        "crawling",
-		Description: "メッセージを取得して .txt ファイルに保存します",
-		Options:     []*discordgo.ApplicationCommandOption{},
-		Executor:    handleCrawlingText,
+		Description: "メッセージを取得してファイルに保存します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "対象のユーザー（省略時は自分）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "all_users",
+				Description: "全員のメッセージを取得する",
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "対象のチャンネル（省略時はこのチャンネル）",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews, discordgo.ChannelTypeGuildForum},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "thread",
+				Description: "対象のスレッド",
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildPublicThread,
+					discordgo.ChannelTypeGuildPrivateThread,
+					discordgo.ChannelTypeGuildNewsThread,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "after",
+				Description: "この日時以降のメッセージ（例: 2025-04-01）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "before",
+				Description: "この日時より前のメッセージ（例: 2025-05-01）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "include_bots",
+				Description: "Botのメッセージも含める",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "limit",
+				Description: "取得する最大件数",
+				MinValue:    &minLimit,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "include_threads",
+				Description: "スレッド・フォーラム投稿も含める（アーカイブ済みを含む）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "format",
+				Description: "出力形式（省略時はテキスト）",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "テキスト", Value: string(exporter.FormatText)},
+					{Name: "CSV", Value: string(exporter.FormatCSV)},
+					{Name: "JSON Lines", Value: string(exporter.FormatJSONL)},
+					{Name: "Markdown", Value: string(exporter.FormatMarkdown)},
+					{Name: "HTML", Value: string(exporter.FormatHTML)},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "strip_urls",
+				Description: "本文からURLを取り除く",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "strip_emoji",
+				Description: "カスタム絵文字を名前に置き換える",
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleCrawlingText(s, i, store, redactor)
+		},
 	}
 }
 
-func handleCrawlingText(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handleCrawlingText(s *discordgo.Session, i *discordgo.InteractionCreate, store *messagestore.Store, redactor *redact.Engine) error {
 	/*
 		crawlingコマンドの実行
 
 		コマンドの実行結果を返す
 	*/
 	if i.Interaction.ApplicationCommandData().Name != "crawling" {
-		return
+		return nil
 	}
 	if i.Interaction.GuildID != i.GuildID {
-		return
+		return nil
 	}
 
-	const limit = 100
-	var beforeId string
-	var messages []*discordgo.Message
-
-	for {
-		c, err := s.ChannelMessages(i.ChannelID, limit, beforeId, "", "")
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		for _, m := range c {
-			if m.Author.ID == i.Member.User.ID {
-				messages = append(messages, m)
-			}
-		}
-
-		if len(c) != limit {
-			break
-		}
-
-		beforeId = c[len(c)-1].ID
+	filter, err := parseCrawlFilter(i)
+	if err != nil {
+		return botRouter.UserError(err.Error())
 	}
-
-	var fileName string = i.Member.User.Username + ".txt"
-
-	file, err := os.Create(fileName)
+	format, filters, err := parseExportOptions(i)
 	if err != nil {
-		log.Fatal(err)
+		return botRouter.UserError(err.Error())
 	}
-	defer file.Close()
-
-	urlRegex := regexp.MustCompile(`https?://[^\s]+`)
-
-	for _, m := range messages {
-		content := m.Content
-		content = urlRegex.ReplaceAllString(content, "")
-		if content == "" {
-			continue
-		}
-
-		_, err := file.WriteString(m.Content + "\n")
-		if err != nil {
-			log.Fatal(err)
-		}
+	// 実行したユーザーが読めないチャンネル・スレッドは出力しない
+	if !canReadChannel(s, filter.ChannelID, i.Member.User.ID) {
+		return botRouter.UserError("指定したチャンネルのメッセージ履歴を閲覧する権限がありません。")
 	}
 
-	file, err = os.Open(fileName)
+	// 他のチャンネル・スレッドの内容は、このチャンネルを見られる人に公開しないよう本人にだけ送る
+	// スレッドを含める場合も、非公開スレッドが混ざることがあるため同じ扱いにする
+	private := filter.ChannelID != i.ChannelID || filter.Threads
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}
+	if private {
+		response.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	// 取得に時間がかかることがあるため、一時応答を返しておく
+	err = s.InteractionRespond(i.Interaction, response)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer file.Close()
 
-	_, err = s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
-		Content: "取得したメッセージをファイルに出力しました。",
-		Files: []*discordgo.File{
-			{
-				Name:   fileName,
-				Reader: file,
-			},
-		},
+	scope := &conversationScope{
+		GuildID:        i.GuildID,
+		ChannelID:      filter.ChannelID,
+		ViewerID:       i.Member.User.ID,
+		IncludeThreads: filter.Threads,
+		Pick:           filter.pick,
+	}
+	var root *exporter.Section
+	err = botRouter.Retry(func() (err error) {
+		root, err = collectConversation(s, store, scope)
+		return err
 	})
-
 	if err != nil {
-		log.Fatal(err)
+		return botRouter.AsCommandError(err).WithMessage("メッセージの取得に失敗しました。")
 	}
 
-	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "メッセージを取得し、ファイルを送信しました。",
-		},
-	})
+	// 電話番号や住所などは伏せ字にしてから出力する
+	redaction := redactor.NewSession(i.GuildID, "crawling")
+	root.Apply(append(filters, redact.RecordFilter(redaction))...)
+	// スレッドは作成元のメッセージの直後に並べる
+	records := root.Flatten()
+	key, err := redaction.Save()
+	if err != nil {
+		return botRouter.InternalError("伏せ字の対応表を保存できませんでした。", err)
+	}
 
+	// ファイルはリクエストごとにメモリ上で作成し、作業ディレクトリには書き込まない
+	parts, err := exportParts(format, "メッセージ: "+filter.describe(), records, discordFileSizeLimit)
 	if err != nil {
-		fmt.Printf("error responding to ping command: %v\n", err)
+		return botRouter.InternalError("ファイルの作成に失敗しました。", err)
+	}
+
+	baseName := fmt.Sprintf("messages_%s_%s", filter.ChannelID, time.Now().Format("20060102_150405"))
+	content := fmt.Sprintf("取得したメッセージ（%d件）をファイルに出力しました。\n%s", len(records), filter.describe())
+	if len(root.Sections) > 0 {
+		content += fmt.Sprintf("\nスレッド・フォーラム投稿: %d件", len(root.Sections))
+	}
+	content += redactionNote(redaction, key)
+	if err := sendExportParts(s, i, content, baseName, format, parts, private); err != nil {
+		return botRouter.AsCommandError(err).WithMessage("ファイルの送信に失敗しました。")
 	}
+	return nil
 }
 
 /* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
@@ -2,19 +2,26 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"main/botHandler/botRouter"
+	"main/httpclient"
 
 	"github.com/google/uuid"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func CreateCommissionCommand() *botRouter.Command {
+// 委任状のAPI
+// 受け取り側は同じ委任状を見分けないため、届いていた場合に二重に作成しないよう再試行しない
+var commissionClient = httpclient.New(httpclient.Config{Name: "commission", Timeout: 15 * time.Second, Retries: -1})
+
+func CreateCommissionCommand(apiURL string) *botRouter.Command {
 	/*
 		create_commission コマンドの定義
 
@@ -51,18 +58,20 @@ func CreateCommissionCommand() *botRouter.Command {
 				Required:    true,
 			},
 		},
-		Executor: handleCreateCommission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleCreateCommission(s, i, apiURL)
+		},
 	}
 }
 
-func handleCreateCommission(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handleCreateCommission(s *discordgo.Session, i *discordgo.InteractionCreate, apiURL string) error {
 	/*
 		create_commissionコマンドの実行
 
 		コマンドの実行結果を返す
 	*/
 	if i.Interaction.ApplicationCommandData().Name != "create_commission" {
-		return
+		return nil
 	}
 
 	// 3秒以内に一時応答を返す
@@ -70,8 +79,7 @@ func handleCreateCommission(s *discordgo.Session, i *discordgo.InteractionCreate
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 	if err != nil {
-		log.Printf("初期応答失敗: %v\n", err)
-		return
+		return err
 	}
 
 	options := i.ApplicationCommandData().Options
@@ -89,8 +97,9 @@ func handleCreateCommission(s *discordgo.Session, i *discordgo.InteractionCreate
 		}
 	}
 
+	id := uuid.New().String()
 	commission := map[string]interface{}{
-		"id":               uuid.New().String(),
+		"id":               id,
 		"title":            title,
 		"description":      description,
 		"recipientName":    recipientName,
@@ -101,30 +110,36 @@ func handleCreateCommission(s *discordgo.Session, i *discordgo.InteractionCreate
 
 	body, err := json.Marshal(commission)
 	if err != nil {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "データの作成に失敗しました。",
-			},
-		})
-		return
+		return botRouter.InternalError("データの作成に失敗しました。", err)
 	}
 
-	resp, err := http.Post("http://localhost:3000/api/submit/", "application/json", bytes.NewBuffer(body))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "APIへの送信に失敗しました。",
-			},
-		})
-		return
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return botRouter.InternalError("データの作成に失敗しました。", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := commissionClient.Do(req)
+	var unavailable *httpclient.UnavailableError
+	if errors.As(err, &unavailable) {
+		return botRouter.UserError(fmt.Sprintf("委任状のAPIが応答していないため、送信を止めています。%s後にもう一度お試しください。", unavailable.RetryIn()))
+	}
+	if err != nil {
+		// タイムアウトでも受け取り側では作成されていることがある
+		return botRouter.TransientError("APIへの送信に失敗しました。委任状が作成されている場合があるため、確認してからもう一度お試しください。", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return botRouter.InternalError("APIへの送信に失敗しました。", fmt.Errorf("status %s", resp.Status))
 	}
 
 	msg := "委任状を作成し、APIに送信しました。"
-	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: &msg,
 	})
+	return err
 }
 
 /* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main/botHandler/botRouter"
+	"main/digest"
+	"main/render"
+	"main/summary"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func DigestCommand(scheduler *digest.Scheduler) *botRouter.Command {
+	/*
+		digestコマンドの定義
+
+		コマンド名: digest
+		説明: チャンネルの会話を定期的に要約して投稿します
+		サブコマンド: add, list, remove
+	*/
+	permission := int64(discordgo.PermissionManageServer)
+	textChannels := []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews}
+	return &botRouter.Command{
+		Name:        "digest",
+		Description: "チャンネルの会話を定期的に要約して投稿します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "ダイジェストを追加します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "schedule",
+						Description: "cron形式の予定「分 時 日 月 曜日」（例: 0 9 * * 6, @daily, @weekly）",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "要約するチャンネル（省略時はこのチャンネル）",
+						ChannelTypes: append(textChannels, discordgo.ChannelTypeGuildForum),
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "target",
+						Description:  "投稿先のチャンネル（省略時はこのチャンネル）",
+						ChannelTypes: textChannels,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "include_threads",
+						Description: "スレッド・フォーラム投稿の会話も含める",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "style",
+						Description: "要約のスタイル（省略時は標準）",
+						Choices:     styleChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "language",
+						Description: "要約を出力する言語（省略時は日本語）",
+						Choices:     languageChoices(),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "このサーバーのダイジェストを表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "ダイジェストを削除します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "id",
+						Description: "list で表示されるID",
+						Required:    true,
+					},
+				},
+			},
+		},
+		DefaultMemberPermissions: &permission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleDigest(s, i, scheduler)
+		},
+	}
+}
+
+func handleDigest(s *discordgo.Session, i *discordgo.InteractionCreate, scheduler *digest.Scheduler) error {
+	/*
+		digestコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "digest" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "add":
+		d := &digest.Digest{
+			GuildID:         i.GuildID,
+			ChannelID:       i.ChannelID,
+			TargetChannelID: i.ChannelID,
+			Schedule:        args["schedule"].StringValue(),
+			CreatedBy:       i.Member.User.ID,
+		}
+		if opt, ok := args["channel"]; ok {
+			d.ChannelID = opt.ChannelValue(nil).ID
+		}
+		if opt, ok := args["target"]; ok {
+			d.TargetChannelID = opt.ChannelValue(nil).ID
+		}
+		if opt, ok := args["include_threads"]; ok {
+			d.IncludeThreads = opt.BoolValue()
+		}
+		if opt, ok := args["style"]; ok {
+			d.Style = opt.StringValue()
+		}
+		if opt, ok := args["language"]; ok {
+			d.Language = opt.StringValue()
+		}
+		if err := checkDigestChannels(s, d); err != nil {
+			return err
+		}
+		added, err := scheduler.Add(d)
+		if err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return responseText(s, i, fmt.Sprintf("ダイジェスト `%s` を追加しました: <#%s> → <#%s>（`%s`、次回 %s）",
+			added.ID, added.ChannelID, added.TargetChannelID, added.Schedule, added.NextRunAt.Format("2006/01/02 15:04")))
+
+	case "list":
+		digests := scheduler.List(i.GuildID)
+		if len(digests) == 0 {
+			return responseText(s, i, "ダイジェストは設定されていません")
+		}
+		lines := make([]string, 0, len(digests))
+		for _, d := range digests {
+			line := fmt.Sprintf("`%s` <#%s> → <#%s> `%s` 次回 %s", d.ID, d.ChannelID, d.TargetChannelID, d.Schedule, d.NextRunAt.Format("2006/01/02 15:04"))
+			if d.IncludeThreads {
+				line += "（スレッドを含む）"
+			}
+			if d.Style != "" || d.Language != "" {
+				line += fmt.Sprintf("（%s）", describeFormat(digestFormat(d)))
+			}
+			if d.LastError != "" {
+				line += "\n　前回の実行に失敗しました: " + d.LastError
+			}
+			lines = append(lines, line)
+		}
+		return responseText(s, i, truncateRunes(strings.Join(lines, "\n"), discordMessageLimit))
+
+	case "remove":
+		id := args["id"].StringValue()
+		if err := scheduler.Remove(i.GuildID, id); err != nil {
+			if errors.Is(err, digest.ErrNotFound) {
+				return botRouter.UserError(err.Error())
+			}
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, fmt.Sprintf("ダイジェスト `%s` を削除しました", id))
+	}
+	return nil
+}
+
+// ダイジェストの要約元と投稿先を確かめる
+// 設定した人が読めないチャンネルは要約できず、要約元より多くの人が読めるチャンネルには投稿できない
+func checkDigestChannels(s *discordgo.Session, d *digest.Digest) error {
+	if !canReadChannel(s, d.ChannelID, d.CreatedBy) {
+		return botRouter.UserError("要約するチャンネルを読む権限がありません。")
+	}
+	if d.TargetChannelID == d.ChannelID {
+		return nil
+	}
+	if !canReadChannel(s, d.TargetChannelID, d.CreatedBy) {
+		return botRouter.UserError("投稿先のチャンネルを読む権限がありません。")
+	}
+	if !publicChannel(s, d.ChannelID) && publicChannel(s, d.TargetChannelID) {
+		return botRouter.UserError("非公開のチャンネルのダイジェストは、公開のチャンネルに投稿できません。")
+	}
+	// 非公開のスレッドは参加者しか読めないため、別のチャンネルには投稿しない
+	if d.IncludeThreads {
+		return botRouter.UserError("スレッドを含むダイジェストは、要約するチャンネル自身にしか投稿できません。")
+	}
+	return nil
+}
+
+// 予定の時刻に前回のダイジェスト以降の会話を要約し、投稿先のチャンネルに送る
+// /summary と同じく伏せ字にしてから、ギルドで選ばれているバックエンドで要約する
+func DigestRunner(s *discordgo.Session, cfg *SummaryConfig) digest.RunFunc {
+	return func(d *digest.Digest) (string, error) {
+		rng := &summaryRange{ChannelID: d.ChannelID, AfterID: d.LastMessageID}
+		if d.LastMessageID == "" {
+			// 初回は設定した時点以降の会話を要約する
+			rng.Since = d.CreatedAt
+		}
+		scope := &conversationScope{
+			GuildID:        d.GuildID,
+			ChannelID:      d.ChannelID,
+			ViewerID:       d.CreatedBy,
+			IncludeThreads: d.IncludeThreads,
+			Pick:           rng.pick,
+		}
+		out, err := summarizeConversation(s, cfg, scope, digestFormat(d), "digest", nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if len(out.Records) == 0 {
+			return "", nil // 新しい会話が無ければ投稿しない
+		}
+
+		title := digestTitle(out.Format.Language)
+		header := fmt.Sprintf("【%s】<#%s>\n%s", title, d.ChannelID, summarizedRange(out.Records))
+		resp := summaryResponse(header, out)
+		resp.Title = title
+		resp.FileName = fmt.Sprintf("digest_%s", time.Now().Format("20060102_1504"))
+		if err := render.Channel(s, d.TargetChannelID, resp); err != nil {
+			return "", err
+		}
+		return out.NewestID(), nil
+	}
+}
+
+// 言語ごとのダイジェストのタイトル（要約の見出しと揃える）
+var digestTitles = map[string]string{
+	"ja": "ダイジェスト",
+	"en": "Digest",
+	"vi": "Bản tin tóm tắt",
+}
+
+func digestTitle(language string) string {
+	if title, ok := digestTitles[language]; ok {
+		return title
+	}
+	return digestTitles[summary.DefaultLanguage]
+}
+
+// ダイジェストのスタイルと言語（設定が無ければ標準・日本語）
+func digestFormat(d *digest.Digest) summaryFormat {
+	format := summaryFormat{Style: d.Style, Language: d.Language}
+	if format.Style == "" {
+		format.Style = summary.StyleStandard
+	}
+	if format.Language == "" {
+		format.Language = summary.DefaultLanguage
+	}
+	return format
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"testing"
+
+	"main/digest"
+	"main/summary"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDigestTitleCoversLanguages(t *testing.T) {
+	for _, lang := range summary.Languages {
+		if _, ok := digestTitles[lang.Code]; !ok {
+			t.Errorf("no digest title for %s", lang.Code)
+		}
+		if _, ok := summaryHeadings[lang.Code]; !ok {
+			t.Errorf("no summary headings for %s", lang.Code)
+		}
+	}
+	if got := digestTitle("en"); got != "Digest" {
+		t.Errorf("digestTitle(en) = %q, want Digest", got)
+	}
+	if got := digestTitle("unknown"); got != digestTitles[summary.DefaultLanguage] {
+		t.Errorf("digestTitle(unknown) = %q, want the default language", got)
+	}
+}
+
+func TestCheckDigestChannels(t *testing.T) {
+	view := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	private := []*discordgo.PermissionOverwrite{
+		{ID: "guild", Type: discordgo.PermissionOverwriteTypeRole, Deny: discordgo.PermissionViewChannel},
+		{ID: "staff", Type: discordgo.PermissionOverwriteTypeRole, Allow: discordgo.PermissionViewChannel},
+	}
+
+	s, _ := discordgo.New("")
+	s.State.GuildAdd(&discordgo.Guild{ID: "guild", OwnerID: "owner", Roles: []*discordgo.Role{
+		{ID: "guild", Permissions: view},
+		{ID: "staff"},
+	}})
+	s.State.MemberAdd(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "member"}})
+	s.State.MemberAdd(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "staffer"}, Roles: []string{"staff"}})
+	for _, ch := range []*discordgo.Channel{
+		{ID: "general", Type: discordgo.ChannelTypeGuildText},
+		{ID: "news", Type: discordgo.ChannelTypeGuildText},
+		{ID: "staff", Type: discordgo.ChannelTypeGuildText, PermissionOverwrites: private},
+		{ID: "staff-notes", Type: discordgo.ChannelTypeGuildText, PermissionOverwrites: private},
+	} {
+		ch.GuildID = "guild"
+		s.State.ChannelAdd(ch)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		user    string
+		source  string
+		target  string
+		threads bool
+		wantErr bool
+	}{
+		{"同じチャンネル", "member", "general", "general", false, false},
+		{"同じチャンネルならスレッドも含められる", "member", "general", "general", true, false},
+		{"公開から公開", "member", "general", "news", false, false},
+		{"読めないチャンネルを要約する", "member", "staff", "staff", false, true},
+		{"読めないチャンネルに投稿する", "member", "general", "staff", false, true},
+		{"公開から非公開", "staffer", "general", "staff", false, false},
+		{"非公開から非公開", "staffer", "staff", "staff-notes", false, false},
+		{"非公開から公開", "staffer", "staff", "general", false, true},
+		{"スレッドを含めて別のチャンネルへ", "member", "general", "news", true, true},
+	} {
+		d := &digest.Digest{GuildID: "guild", ChannelID: tc.source, TargetChannelID: tc.target, IncludeThreads: tc.threads, CreatedBy: tc.user}
+		if err := checkDigestChannels(s, d); (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
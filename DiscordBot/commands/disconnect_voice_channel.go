@@ -1,12 +1,20 @@
 package commands
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/bwmarrin/discordgo"
 
 	"main/botHandler/botRouter"
+	"main/recording"
+	"main/voice"
 )
 
-func DisconnectCommand() *botRouter.Command {
+// 録音の停止を待つ最大時間
+const stopRecordTimeout = 2 * time.Second
+
+func DisconnectCommand(recordings *recording.Manager, player *voice.Player) *botRouter.Command {
 	/*
 		disconnectコマンドの定義
 
@@ -18,38 +26,37 @@ func DisconnectCommand() *botRouter.Command {
 		Name:        "disconnect",
 		Description: "接続中のボイスチャンネルから切断します",
 		Options:     []*discordgo.ApplicationCommandOption{},
-		Executor:    disconnectVoiceChannel,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return disconnectVoiceChannel(s, i, recordings, player)
+		},
 	}
 }
 
-func disconnectVoiceChannel(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func disconnectVoiceChannel(s *discordgo.Session, i *discordgo.InteractionCreate, recordings *recording.Manager, player *voice.Player) error {
 	/*
 		test_disconnectコマンドの実行
 
-		コマンドの実行結果を返す
+		録音中なら音声の受信が終わるのを待ってから切断する
 	*/
 	if i.Interaction.ApplicationCommandData().Name == "disconnect" {
-		if len(s.VoiceConnections) == 0 {
-			responseText(s, i, "ボイスチャンネルに接続していません")
-			return
+		v := voice.Connection(s, i.GuildID)
+		if v == nil {
+			return botRouter.UserError("ボイスチャンネルに接続していません")
 		}
-		if s.VoiceConnections[i.GuildID] == nil {
-			responseText(s, i, "ボイスチャンネルに接続していません")
-			return
+		if err := recordings.StopAndWait(i.GuildID, stopRecordTimeout); err != nil {
+			fmt.Printf("error stopping recording: %v\n", err)
 		}
+		player.Stop(i.GuildID)
+
 		// 接続中のボイスチャンネルから切断する
-		err := s.VoiceConnections[i.GuildID].Disconnect()
+		err := v.Disconnect()
 		if err != nil {
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "切断に失敗しました",
-				},
-			})
-			return
+			return botRouter.InternalError("切断に失敗しました", err)
 		}
-		responseText(s, i, "切断しました")
+		speakingUpdates.forget(v)
+		return responseText(s, i, "切断しました")
 	}
+	return nil
 }
 
 // MIT License
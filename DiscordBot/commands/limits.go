@@ -0,0 +1,12 @@
+package commands
+
+const (
+	// メッセージ本文の最大文字数
+	discordMessageLimit = 2000
+	// 1メッセージに添付できるファイルの合計サイズ（ブーストなしのサーバー）
+	discordFileSizeLimit = 10 * 1024 * 1024
+)
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
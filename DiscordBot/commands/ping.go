@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"fmt"
-
 	"main/botHandler/botRouter"
 
 	"github.com/bwmarrin/discordgo"
@@ -24,28 +22,24 @@ func PingCommand() *botRouter.Command {
 	}
 }
 
-func handlePing(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func handlePing(s *discordgo.Session, i *discordgo.InteractionCreate) error {
 	/*
 		pingコマンドの実行
 
 		コマンドの実行結果を返す
 	*/
 	if i.Interaction.ApplicationCommandData().Name != "ping" {
-		return
+		return nil
 	}
 	if i.Interaction.GuildID != i.GuildID {
-		return
+		return nil
 	}
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Content: "Pong",
 		},
 	})
-	if err != nil {
-		fmt.Printf("error responding to ping command: %v\n", err)
-	}
-
 }
 
 // MIT License
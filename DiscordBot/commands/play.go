@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+
+	"main/botHandler/botRouter"
+	"main/voice"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func PlayCommand(p *voice.Player) *botRouter.Command {
+	/*
+		playコマンドの定義
+
+		コマンド名: play
+		説明: 添付した音声ファイルをボイスチャンネルで再生します
+		オプション: file (音声ファイル)
+	*/
+	return &botRouter.Command{
+		Name:        "play",
+		Description: "添付した音声ファイルをボイスチャンネルで再生します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "再生する音声ファイル",
+				Required:    true,
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handlePlay(s, i, p)
+		},
+	}
+}
+
+func handlePlay(s *discordgo.Session, i *discordgo.InteractionCreate, p *voice.Player) error {
+	/*
+		playコマンドの実行
+
+		録音中でも同じ接続のまま再生できる
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "play" {
+		return nil
+	}
+
+	data := i.ApplicationCommandData()
+	var attachment *discordgo.MessageAttachment
+	for _, opt := range data.Options {
+		if opt.Name == "file" && data.Resolved != nil {
+			if id, ok := opt.Value.(string); ok {
+				attachment = data.Resolved.Attachments[id]
+			}
+		}
+	}
+	if attachment == nil {
+		return botRouter.UserError("音声ファイルを添付してください")
+	}
+
+	// すでに接続中ならそのチャンネル、そうでなければ実行者のチャンネルで再生する
+	channelID := ""
+	if voice.Connection(s, i.GuildID) == nil {
+		vs, err := s.State.VoiceState(i.GuildID, i.Interaction.Member.User.ID)
+		if err != nil || vs == nil {
+			return botRouter.UserError("ボイスチャンネルに入ってください")
+		}
+		channelID = vs.ChannelID
+	}
+
+	position := p.Enqueue(i.GuildID, channelID, &voice.Track{
+		Title:       attachment.Filename,
+		Source:      attachment.URL,
+		RequestedBy: i.Interaction.Member.User.ID,
+	})
+
+	message := fmt.Sprintf("「%s」を再生します", attachment.Filename)
+	if position > 0 {
+		message = fmt.Sprintf("「%s」をキューに追加しました（%d番目）", attachment.Filename, position)
+	}
+	return responseText(s, i, message)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
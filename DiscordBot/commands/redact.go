@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"main/botHandler/botRouter"
+	"main/redact"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func RedactCommand(redactor *redact.Engine) *botRouter.Command {
+	/*
+		redactコマンドの定義
+
+		コマンド名: redact
+		説明: 出力時に伏せ字にする個人情報のルールを設定します
+		サブコマンド: rule_add, rule_remove, rules, officer_role
+	*/
+	permission := int64(discordgo.PermissionManageServer)
+	return &botRouter.Command{
+		Name:        "redact",
+		Description: "出力時に伏せ字にする個人情報のルールを設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rule_add",
+				Description: "伏せ字にするパターンを正規表現で追加します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "ルールの名前（伏せ字に表示されます）",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "pattern",
+						Description: "正規表現（例: M-\\d{6}）",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rule_remove",
+				Description: "追加したルールを削除します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "ルールの名前",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rules",
+				Description: "有効なルールを表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "officer_role",
+				Description: "伏せ字を復元できるロールを設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "復元を許可するロール",
+						Required:    true,
+					},
+				},
+			},
+		},
+		DefaultMemberPermissions: &permission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleRedact(s, i, redactor)
+		},
+	}
+}
+
+func handleRedact(s *discordgo.Session, i *discordgo.InteractionCreate, redactor *redact.Engine) error {
+	/*
+		redactコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "redact" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "rule_add":
+		rule := redact.Rule{Name: args["name"].StringValue(), Pattern: args["pattern"].StringValue()}
+		if err := redactor.AddRule(i.GuildID, rule); err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return responseText(s, i, fmt.Sprintf("ルール「%s」を追加しました（`%s`）", rule.Name, rule.Pattern))
+
+	case "rule_remove":
+		name := args["name"].StringValue()
+		if err := redactor.RemoveRule(i.GuildID, name); err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		return responseText(s, i, fmt.Sprintf("ルール「%s」を削除しました", name))
+
+	case "rules":
+		lines := []string{"組み込み: " + strings.Join(redact.BuiltinNames(), "、")}
+		for _, r := range redactor.Rules(i.GuildID) {
+			lines = append(lines, fmt.Sprintf("%s: `%s`", r.Name, r.Pattern))
+		}
+		return responseText(s, i, strings.Join(lines, "\n"))
+
+	case "officer_role":
+		role := args["role"].RoleValue(s, i.GuildID)
+		if err := redactor.SetOfficerRole(i.GuildID, role.ID); err != nil {
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, fmt.Sprintf("<@&%s> のメンバーが伏せ字を復元できるようにしました", role.ID))
+	}
+	return nil
+}
+
+func UnredactCommand(redactor *redact.Engine) *botRouter.Command {
+	/*
+		unredactコマンドの定義
+
+		コマンド名: unredact
+		説明: 復元キーから伏せ字の元の値を表示します（担当者のみ）
+		オプション: key (復元キー)
+	*/
+	return &botRouter.Command{
+		Name:        "unredact",
+		Description: "復元キーから伏せ字の元の値を表示します（担当者のみ）",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "key",
+				Description: "出力に表示された復元キー",
+				Required:    true,
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleUnredact(s, i, redactor)
+		},
+	}
+}
+
+func handleUnredact(s *discordgo.Session, i *discordgo.InteractionCreate, redactor *redact.Engine) error {
+	/*
+		unredactコマンドの実行
+
+		元の値は実行した本人にだけ表示する
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "unredact" {
+		return nil
+	}
+	key := strings.Trim(i.ApplicationCommandData().Options[0].StringValue(), "` ")
+
+	record, err := redactor.Reveal(i.GuildID, i.Member, key)
+	if errors.Is(err, redact.ErrNotOfficer) || errors.Is(err, redact.ErrKeyNotFound) {
+		return botRouter.UserError(err.Error())
+	}
+	if err != nil {
+		return botRouter.InternalError("復元キーを読み込めませんでした", err)
+	}
+
+	placeholders := make([]string, 0, len(record.Values))
+	for p := range record.Values {
+		placeholders = append(placeholders, p)
+	}
+	sort.Strings(placeholders)
+	lines := []string{fmt.Sprintf("復元キー `%s`（%s, %s）", record.Key, record.Source, record.CreatedAt.Format("2006/01/02 15:04"))}
+	for _, p := range placeholders {
+		lines = append(lines, fmt.Sprintf("%s → %s", p, record.Values[p]))
+	}
+
+	data := &discordgo.InteractionResponseData{
+		Content: strings.Join(lines, "\n"),
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}
+	// 本文に収まらなければファイルで渡す
+	if utf8.RuneCountInString(data.Content) > discordMessageLimit {
+		body, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return botRouter.InternalError("復元キーを読み込めませんでした", err)
+		}
+		data.Content = lines[0]
+		data.Files = []*discordgo.File{{Name: "redaction_" + record.Key + ".json", ContentType: "application/json", Reader: bytes.NewReader(body)}}
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
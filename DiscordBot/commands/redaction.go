@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"fmt"
+
+	"main/redact"
+)
+
+// 伏せ字にした内容と復元キーの説明（伏せ字が無ければ空）
+func redactionNote(ss *redact.Session, key string) string {
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n個人情報を伏せ字にしました（%s）。復元キー: `%s`", ss.Describe(), key)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,315 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/botHandler/botRouter"
+	"main/search"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+)
+
+const (
+	searchPageSize    = 5
+	searchSnippetSize = 120
+	// 検索語の最大文字数（埋め込みのタイトルは embedTitleLimit 文字まで）
+	searchQueryMaxLength = 200
+	embedTitleLimit      = 256
+	// ページ送りできる期間
+	searchResultTTL = 15 * time.Minute
+)
+
+// ページ送りのために保持する検索結果
+type searchResult struct {
+	UserID    string
+	Text      string
+	Condition string
+	Documents []*search.Document
+	CreatedAt time.Time
+}
+
+// 検索結果をIDごとに保持する
+type searchResults struct {
+	mu      sync.Mutex
+	results map[string]*searchResult
+}
+
+func (r *searchResults) put(res *searchResult) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, old := range r.results {
+		if time.Since(old.CreatedAt) > searchResultTTL {
+			delete(r.results, id)
+		}
+	}
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	r.results[id] = res
+	return id
+}
+
+func (r *searchResults) get(id string) (*searchResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res, ok := r.results[id]
+	if !ok || time.Since(res.CreatedAt) > searchResultTTL {
+		return nil, false
+	}
+	return res, true
+}
+
+func SearchCommand(searcher *search.Searcher) *botRouter.Command {
+	/*
+		searchコマンドの定義
+
+		コマンド名: search
+		説明: このサーバーのメッセージを検索します
+		オプション: query (検索語), user, channel, after, before
+	*/
+	results := &searchResults{results: make(map[string]*searchResult)}
+	return &botRouter.Command{
+		Name:        "search",
+		Description: "このサーバーのメッセージを検索します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "query",
+				Description: "検索語（空白で区切るとすべてを含むメッセージを探します）",
+				Required:    true,
+				MaxLength:   searchQueryMaxLength,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "発言したユーザー",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "対象のチャンネル",
+				ChannelTypes: []discordgo.ChannelType{
+					discordgo.ChannelTypeGuildText,
+					discordgo.ChannelTypeGuildNews,
+					discordgo.ChannelTypeGuildPublicThread,
+					discordgo.ChannelTypeGuildPrivateThread,
+					discordgo.ChannelTypeGuildNewsThread,
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "after",
+				Description: "この日時以降のメッセージ（例: 2025-04-01）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "before",
+				Description: "この日時より前のメッセージ（例: 2025-05-01）",
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleSearch(s, i, searcher, results)
+		},
+		ComponentExecutor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleSearchPage(s, i, results)
+		},
+	}
+}
+
+func handleSearch(s *discordgo.Session, i *discordgo.InteractionCreate, searcher *search.Searcher, results *searchResults) error {
+	/*
+		searchコマンドの実行
+
+		結果は実行した本人にだけ表示し、本人が閲覧できるチャンネルのメッセージに限る
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "search" {
+		return nil
+	}
+
+	userID := i.Member.User.ID
+	q := &search.Query{GuildID: i.GuildID}
+	var condition []string
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "query":
+			q.Text = opt.StringValue()
+		case "user":
+			u := opt.UserValue(nil)
+			q.AuthorID = u.ID
+			condition = append(condition, "<@"+u.ID+">")
+		case "channel":
+			q.ChannelID = opt.ChannelValue(nil).ID
+			condition = append(condition, "<#"+q.ChannelID+">")
+		case "after", "before":
+			t, err := parseCrawlDate(opt.StringValue())
+			if err != nil {
+				return botRouter.UserError(err.Error())
+			}
+			if opt.Name == "after" {
+				q.After = t
+				condition = append(condition, t.Format("2006/01/02 15:04")+"以降")
+			} else {
+				q.Before = t
+				condition = append(condition, t.Format("2006/01/02 15:04")+"より前")
+			}
+		}
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		return botRouter.UserError("検索語を入力してください")
+	}
+
+	// 索引は起動時に裏で作り始めるため、できあがるまでは待ってもらう
+	if !searcher.Prepare(s, i.GuildID) {
+		return botRouter.UserError("検索の準備中です（メッセージを取り込んでいます）。しばらくしてからもう一度お試しください。")
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		return err
+	}
+
+	q.CanView = readableChecker(s, userID)
+	res := &searchResult{
+		UserID:    userID,
+		Text:      q.Text,
+		Condition: strings.Join(condition, " / "),
+		Documents: searcher.Search(q),
+		CreatedAt: time.Now(),
+	}
+	if len(res.Documents) == 0 {
+		editWithError(s, i, fmt.Sprintf("「%s」に一致するメッセージは見つかりませんでした。", q.Text))
+		return nil
+	}
+
+	id := results.put(res)
+	embeds, components := renderSearchPage(id, res, 0)
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	return err
+}
+
+// ページ送りのボタンを処理する（CustomID: search:<結果ID>:<ページ>）
+func handleSearchPage(s *discordgo.Session, i *discordgo.InteractionCreate, results *searchResults) error {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 {
+		return nil
+	}
+	page, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil
+	}
+
+	userID := ""
+	if i.Member != nil {
+		userID = i.Member.User.ID
+	}
+	res, ok := results.get(parts[1])
+	if !ok || res.UserID != userID {
+		return botRouter.UserError("検索結果の有効期限が切れました。もう一度 /search を実行してください。")
+	}
+
+	embeds, components := renderSearchPage(parts[1], res, page)
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     embeds,
+			Components: components,
+		},
+	})
+}
+
+// 1ページ分の検索結果とページ送りのボタンを作る
+func renderSearchPage(id string, res *searchResult, page int) ([]*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	pages := (len(res.Documents) + searchPageSize - 1) / searchPageSize
+	if page < 0 {
+		page = 0
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       truncateRunes(fmt.Sprintf("「%s」の検索結果", res.Text), embedTitleLimit),
+		Description: res.Condition,
+		Color:       0x5865F2,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("%d / %d ページ（%d件）", page+1, pages, len(res.Documents)),
+		},
+	}
+	end := page*searchPageSize + searchPageSize
+	if end > len(res.Documents) {
+		end = len(res.Documents)
+	}
+	for _, d := range res.Documents[page*searchPageSize : end] {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("%s・%s", d.Author, d.Timestamp.Local().Format("2006/01/02 15:04")),
+			Value: fmt.Sprintf("<#%s> %s\n[メッセージへ移動](%s)",
+				d.ChannelID, search.Snippet(d.Content, res.Text, searchSnippetSize), d.URL()),
+		})
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "前へ",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("search:%s:%d", id, page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "次へ",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("search:%s:%d", id, page+1),
+					Disabled: page >= pages-1,
+				},
+			},
+		},
+	}
+	return []*discordgo.MessageEmbed{embed}, components
+}
+
+// canReadChannelでスレッドも含めて判定する関数を返す（結果はチャンネルごとに覚えておく）
+func readableChecker(s *discordgo.Session, userID string) func(channelID string) bool {
+	cache := make(map[string]bool)
+	return func(channelID string) bool {
+		ok, found := cache[channelID]
+		if !found {
+			ok = canReadChannel(s, channelID, userID)
+			cache[channelID] = ok
+		}
+		return ok
+	}
+}
+
+// ユーザーがチャンネルの履歴を読めるかを判定する関数を返す（結果はチャンネルごとに覚えておく）
+func viewableChecker(s *discordgo.Session, userID string) func(channelID string) bool {
+	need := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	cache := make(map[string]bool)
+	return func(channelID string) bool {
+		if ok, found := cache[channelID]; found {
+			return ok
+		}
+		perms, err := s.State.UserChannelPermissions(userID, channelID)
+		if err != nil {
+			perms, err = s.UserChannelPermissions(userID, channelID)
+		}
+		ok := err == nil && perms&need == need
+		cache[channelID] = ok
+		return ok
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
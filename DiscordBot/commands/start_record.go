@@ -1,30 +1,171 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 
+	"main/attendance"
 	"main/botHandler/botRouter"
-	"main/service"
+	"main/recording"
+	"main/redact"
+	"main/transcript"
+	"main/usage"
+	"main/voice"
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
 )
 
-func RecordCommand() *botRouter.Command {
+// 録音時間を指定しなかったときの最大録音時間
+const defaultRecordDuration = 60 * time.Minute
+
+// 利用量の記録に使う書き起こしのモデル名（scripts/transcribe.py をローカルで動かす）
+const transcriptionModel = "whisper-local"
+
+// 録音コマンドの設定
+type RecordConfig struct {
+	Recordings      *recording.Manager
+	Attendance      *attendance.Tracker
+	Transcripts     *transcript.Store
+	Redactor        *redact.Engine
+	ResultChannelID string        // 書き起こし結果の送信先（空ならコマンドを実行したチャンネル）
+	Usage           *usage.Ledger // 書き起こした音声の長さの記録と月間の上限（nilなら記録しない）
+}
+
+func RecordCommand(cfg *RecordConfig) *botRouter.Command {
+	minRecordMinutes := 1.0
 	return &botRouter.Command{
 		Name:        "start_record",
 		Description: "録音を開始します",
-		Options:     []*discordgo.ApplicationCommandOption{},
-		Executor:    recordVoice,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "minutes",
+				Description: "最大録音時間（分）",
+				MinValue:    &minRecordMinutes,
+				MaxValue:    180,
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return recordVoice(s, i, cfg)
+		},
+	}
+}
+
+// Opusの1フレーム（48kHzで20ms）のサンプル数
+const opusFrameSamples = 960
+
+// 無音を表すOpusフレーム
+var opusSilenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// 話者ごとの録音ファイル
+type recordedTrack struct {
+	SSRC   uint32
+	Path   string
+	Offset time.Duration // 録音開始から最初の発話までの時間
+}
+
+// whisperが返す発言区間
+type whisperSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// SSRCとユーザーIDの対応
+type speakerMap struct {
+	mu    sync.Mutex
+	users map[uint32]string
+}
+
+func (m *speakerMap) onSpeakingUpdate(vc *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[uint32(vs.SSRC)] = vs.UserID
+}
+
+func (m *speakerMap) userID(ssrc uint32) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users[ssrc]
+}
+
+// ボイスコネクションの話者の通知を、録音中のセッションのspeakerMapに振り分ける
+// discordgoのVoiceConnectionには登録したハンドラーを外す方法が無いため、
+// 接続ごとに1度だけ登録し、録音のたびにハンドラーが増えないようにする。
+// ハンドラーは接続が持っているので、閉じた接続への参照を捨てれば接続ごと解放される
+type speakingRouter struct {
+	mu         sync.Mutex
+	registered map[*discordgo.VoiceConnection]bool
+	current    map[*discordgo.VoiceConnection]*speakerMap
+}
+
+var speakingUpdates = &speakingRouter{
+	registered: make(map[*discordgo.VoiceConnection]bool),
+	current:    make(map[*discordgo.VoiceConnection]*speakerMap),
+}
+
+// 接続の話者の通知をmに送る。返した関数を呼ぶと送るのをやめる
+func (r *speakingRouter) attach(s *discordgo.Session, v *discordgo.VoiceConnection, m *speakerMap) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(s)
+	if !r.registered[v] {
+		r.registered[v] = true
+		v.AddHandler(r.dispatch)
+	}
+	r.current[v] = m
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.current[v] == m {
+			delete(r.current, v)
+		}
+		r.prune(s)
+	}
+}
+
+// 切断した接続を忘れる
+func (r *speakingRouter) forget(v *discordgo.VoiceConnection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.registered, v)
+	delete(r.current, v)
+}
+
+// セッションが持っていない（閉じた）接続を忘れる
+// r.muをロックして呼ぶ
+func (r *speakingRouter) prune(s *discordgo.Session) {
+	for v := range r.registered {
+		v.RLock()
+		guildID := v.GuildID
+		v.RUnlock()
+		if voice.Connection(s, guildID) != v {
+			delete(r.registered, v)
+			delete(r.current, v)
+		}
+	}
+}
+
+func (r *speakingRouter) dispatch(vc *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
+	r.mu.Lock()
+	m := r.current[vc]
+	r.mu.Unlock()
+	if m != nil {
+		m.onSpeakingUpdate(vc, vs)
 	}
 }
 
@@ -41,105 +182,279 @@ func createPionRTPPacket(p *discordgo.Packet) *rtp.Packet {
 	}
 }
 
-func handleVoice(c chan *discordgo.Packet) string {
-	files := make(map[uint32]media.Writer)
-	filePaths := make(map[uint32]string)
+// 書き込み中の話者の録音
+type trackWriter struct {
+	file          media.Writer
+	lastTimestamp uint32 // 最後に書き込んだパケットのRTPタイムスタンプ
+}
+
+// 前のパケットとの間の無音を埋めるフレーム数
+// 話していない間はパケットが届かないため、RTPタイムスタンプの差から求める
+// 順序の入れ替わりや、録音時間を超える不自然な差は埋めない
+func silenceFrames(last, next uint32, limit time.Duration) int {
+	gap := next - last
+	if gap > 1<<31 || gap <= opusFrameSamples {
+		return 0
+	}
+	frames := int(gap/opusFrameSamples) - 1
+	if time.Duration(frames)*20*time.Millisecond > limit {
+		return 0
+	}
+	return frames
+}
+
+// 停止を要求されるまで音声を受信し、話者ごとにOggファイルをdirへ書き込む
+// 発話の間の無音も書き込み、書き起こしの時刻が録音の時刻とずれないようにする
+// OpusRecvはdiscordgoが送信し続けるため、こちらからは閉じない
+func handleVoice(ctx context.Context, c <-chan *discordgo.Packet, dir string, startedAt time.Time) []*recordedTrack {
+	writers := make(map[uint32]*trackWriter)
+	var tracks []*recordedTrack
 
-	// 相対パスに変更
-	storageDir := filepath.Join("commands", "vc_storage")
+	defer func() {
+		for _, w := range writers {
+			w.file.Close()
+		}
+	}()
 
-	// ディレクトリが存在しなければ作成
-	if _, err := os.Stat(storageDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(storageDir, os.ModePerm); err != nil {
-			fmt.Printf("failed to create vc_storage directory: %v\n", err)
-			return ""
+	for {
+		var p *discordgo.Packet
+		select {
+		case <-ctx.Done():
+			return tracks
+		case packet, ok := <-c:
+			if !ok {
+				return tracks
+			}
+			p = packet
 		}
-	}
 
-	for p := range c {
-		file, ok := files[p.SSRC]
+		w, ok := writers[p.SSRC]
 		if !ok {
 			id := uuid.New()
-			fileName := filepath.Join(storageDir, fmt.Sprintf("%s.ogg", id.String()))
+			fileName := filepath.Join(dir, fmt.Sprintf("%s.ogg", id.String()))
 
-			var err error
-			file, err = oggwriter.New(fileName, 48000, 2)
+			file, err := oggwriter.New(fileName, 48000, 2)
 			if err != nil {
 				fmt.Printf("failed to create file %s, giving up on recording: %v\n", fileName, err)
-				return ""
+				return tracks
 			}
-			files[p.SSRC] = file
-			filePaths[p.SSRC] = fileName
+			w = &trackWriter{file: file}
+			writers[p.SSRC] = w
+			tracks = append(tracks, &recordedTrack{
+				SSRC:   p.SSRC,
+				Path:   fileName,
+				Offset: time.Since(startedAt),
+			})
 			fmt.Printf("recording started: %s\n", fileName)
+		} else if p.Timestamp-w.lastTimestamp > 1<<31 {
+			// 遅れて届いた古いパケットは捨てる（書き込むとファイル内の時刻が戻る）
+			continue
+		} else {
+			for n := 1; n <= silenceFrames(w.lastTimestamp, p.Timestamp, time.Since(startedAt)); n++ {
+				silence := createPionRTPPacket(&discordgo.Packet{
+					SSRC:      p.SSRC,
+					Timestamp: w.lastTimestamp + uint32(n*opusFrameSamples),
+					Opus:      opusSilenceFrame,
+				})
+				if err := w.file.WriteRTP(silence); err != nil {
+					fmt.Printf("failed to write silence for SSRC %d: %v\n", p.SSRC, err)
+					break
+				}
+			}
 		}
 
 		rtp := createPionRTPPacket(p)
-		err := file.WriteRTP(rtp)
+		err := w.file.WriteRTP(rtp)
 		if err != nil {
 			fmt.Printf("failed to write to file for SSRC %d: %v\n", p.SSRC, err)
 		}
+		w.lastTimestamp = p.Timestamp
 	}
-
-	for ssrc, f := range files {
-		f.Close()
-		filePath := filePaths[ssrc]
-		return transcribeAudio(filePath)
-	}
-	return ""
 }
 
-func transcribeAudio(filePath string) string {
+func transcribeAudio(filePath string) ([]whisperSegment, error) {
 	// Pythonとtranscribe.pyの絶対パスを指定
 	scriptPath, _ := filepath.Abs("./scripts/dist/transcribe.exe")
 
 	fmt.Printf("Running transcription on: %s\n", filePath)
 
-	cmd := exec.Command(scriptPath, filePath)
-	out, err := cmd.CombinedOutput()
+	cmd := exec.Command(scriptPath, "--json", filePath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
 
 	fmt.Printf("---- Transcribe Output ----\n%s\n", string(out))
 	if err != nil {
-		fmt.Printf("transcription error: %v\n", err)
+		return nil, fmt.Errorf("transcription error: %v: %s", err, stderr.String())
 	}
 
-	return string(out)
+	// 進捗表示などが混ざっても、JSONの行だけを読む
+	var result struct {
+		Segments []whisperSegment `json:"segments"`
+	}
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &result) == nil {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("transcription output is not JSON: %s", string(out))
+	}
+	return result.Segments, nil
 }
 
-func recordVoice(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Interaction.ApplicationCommandData().Name == "start_record" {
-		vs, err := s.State.VoiceState(i.GuildID, i.Interaction.Member.User.ID)
-		if err != nil || vs == nil {
-			responseText(s, i, "ボイスチャンネルに接続していません")
-			return
+// 話者ごとの録音を書き起こし、時刻順の発言にまとめる
+// 書き起こした音声の長さは、最後の発言区間の終わりまでとしてギルドの利用量に記録する
+func buildTranscript(s *discordgo.Session, guildID string, tracks []*recordedTrack, speakers *speakerMap, ledger *usage.Ledger) []transcript.Segment {
+	var segments []transcript.Segment
+	for _, tr := range tracks {
+		result, err := transcribeAudio(tr.Path)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if ledger != nil && len(result) > 0 {
+			audio := time.Duration(result[len(result)-1].End * float64(time.Second))
+			if err := ledger.AddAudio(guildID, transcriptionModel, audio); err != nil {
+				fmt.Printf("failed to record transcription usage: %v\n", err)
+			}
 		}
 
-		responseText(s, i, "録音を開始します <#"+vs.ChannelID+">")
-		v, err := s.ChannelVoiceJoin(i.GuildID, vs.ChannelID, true, false)
-		if err != nil {
-			responseText(s, i, "ボイスチャンネルに入ってください")
-			return
+		userID := speakers.userID(tr.SSRC)
+		name := speakerName(s, guildID, userID, tr.SSRC)
+		for _, seg := range result {
+			if seg.Text == "" {
+				continue
+			}
+			segments = append(segments, transcript.Segment{
+				Start:   tr.Offset + time.Duration(seg.Start*float64(time.Second)),
+				End:     tr.Offset + time.Duration(seg.End*float64(time.Second)),
+				Speaker: name,
+				UserID:  userID,
+				Text:    seg.Text,
+			})
 		}
+	}
+	return segments
+}
 
-		go func() {
-			time.Sleep(10 * time.Second)
-			close(v.OpusRecv)
-			v.Close()
-		}()
+// 話者の表示名を返す
+func speakerName(s *discordgo.Session, guildID, userID string, ssrc uint32) string {
+	if userID == "" {
+		return fmt.Sprintf("話者%d", ssrc)
+	}
+	if m, err := s.State.Member(guildID, userID); err == nil {
+		if m.Nick != "" {
+			return m.Nick
+		}
+		if m.User != nil {
+			return m.User.Username
+		}
+	}
+	if u, err := s.User(userID); err == nil {
+		return u.Username
+	}
+	return userID
+}
+
+func recordVoice(s *discordgo.Session, i *discordgo.InteractionCreate, cfg *RecordConfig) error {
+	if i.Interaction.ApplicationCommandData().Name != "start_record" {
+		return nil
+	}
+	vs, err := s.State.VoiceState(i.GuildID, i.Interaction.Member.User.ID)
+	if err != nil || vs == nil {
+		return botRouter.UserError("ボイスチャンネルに接続していません")
+	}
 
-		result := handleVoice(v.OpusRecv)
-		outputText := strings.Split(result, "\n")[2]
+	// 書き起こしの月間の上限に達していれば録音しない
+	if err := checkQuota(cfg.Usage, i.GuildID, usage.Transcription); err != nil {
+		return err
+	}
 
-		// Create a MessageService instance
-		messageService := service.NewMessageService(s)
-		channelId := "1387679644001505400"
-		if result == "" {
-			// Handle empty result
-			messageService.SendMessage(channelId, "録音の書き起こしができませんでした。")
-		} else {
-			// Send the transcription result
-			messageService.SendMessage(channelId, "書き起こし結果:\n```\n"+outputText+"\n```")
+	maxDuration := defaultRecordDuration
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "minutes" {
+			maxDuration = time.Duration(opt.IntValue()) * time.Minute
 		}
 	}
+
+	// ギルドごとに1つだけ録音セッションを持つ
+	sess, ctx, err := cfg.Recordings.Start(i.GuildID, vs.ChannelID, maxDuration)
+	if err != nil {
+		return botRouter.UserError(err.Error())
+	}
+	defer cfg.Recordings.Finish(sess)
+
+	responseText(s, i, "録音を開始します <#"+vs.ChannelID+">（/stop_record で停止）")
+	// 録音中でも再生できるよう、ミュートせずに参加する
+	v, err := voice.Join(s, i.GuildID, vs.ChannelID)
+	if err != nil {
+		return botRouter.InternalError("ボイスチャンネルに接続できませんでした", err)
+	}
+
+	speakers := &speakerMap{users: make(map[uint32]string)}
+	detach := speakingUpdates.attach(s, v, speakers)
+	defer detach()
+
+	// 会議が開始されていなければ、録音の間の出席を記録する
+	if m, err := cfg.Attendance.StartMeeting(s, i.GuildID, vs.ChannelID, "録音 "+sess.StartedAt.Format("2006/01/02 15:04"), "recording"); err == nil {
+		defer func() {
+			if active := cfg.Attendance.ActiveMeeting(m.GuildID); active != nil && active.ID == m.ID {
+				cfg.Attendance.EndMeeting(m.GuildID)
+			}
+		}()
+	}
+
+	// 話者ごとの録音は書き起こしが終われば要らないため、セッションごとの一時ディレクトリに置く
+	dir, err := os.MkdirTemp("", "recording-")
+	if err != nil {
+		return botRouter.InternalError("録音の準備に失敗しました", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sess.SetState(recording.StateRecording)
+	tracks := handleVoice(ctx, v.OpusRecv, dir, sess.StartedAt)
+	sess.SetState(recording.StateTranscribing)
+
+	t := &transcript.Transcript{
+		ID:        uuid.New().String(),
+		GuildID:   i.GuildID,
+		ChannelID: vs.ChannelID,
+		StartedAt: sess.StartedAt,
+		Segments:  buildTranscript(s, i.GuildID, tracks, speakers, cfg.Usage),
+	}
+	if ch, err := s.State.Channel(vs.ChannelID); err == nil {
+		t.Channel = ch.Name
+	}
+	t.Sort()
+
+	channelID := cfg.ResultChannelID
+	if channelID == "" {
+		channelID = i.ChannelID
+	}
+	if len(t.Segments) == 0 {
+		_, err := s.ChannelMessageSend(channelID, "録音の書き起こしができませんでした。")
+		return err
+	}
+
+	// 書き起こしの保存・出力の前に個人情報を伏せ字にする
+	redaction := cfg.Redactor.NewSession(i.GuildID, "transcript")
+	for n := range t.Segments {
+		t.Segments[n].Text = redaction.Redact(t.Segments[n].Text)
+	}
+	if t.RedactionKey, err = redaction.Save(); err != nil {
+		fmt.Printf("failed to save redaction key: %v\n", err)
+	}
+
+	if err := cfg.Transcripts.Save(t); err != nil {
+		fmt.Printf("failed to save transcript: %v\n", err)
+	}
+	sendTranscript(s, channelID, t)
+	return nil
 }
 
 func responseText(s *discordgo.Session, i *discordgo.InteractionCreate, contentText string) error {
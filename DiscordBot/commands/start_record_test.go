@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSilenceFrames(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		last, next uint32
+		want       int
+	}{
+		{"連続したフレーム", 960, 1920, 0},
+		{"1フレーム欠け", 960, 2880, 1},
+		{"2秒の無音", 0, 960 + 2*48000, 100},
+		{"タイムスタンプの折り返し", 1<<32 - 960, 960, 1},
+		{"順序の入れ替わり", 9600, 960, 0},
+		{"同じタイムスタンプ", 960, 960, 0},
+		{"録音時間を超える差", 0, 48000 * 60 * 10, 0},
+	} {
+		if got := silenceFrames(tc.last, tc.next, 5*time.Minute); got != tc.want {
+			t.Errorf("%s: silenceFrames(%d, %d) = %d, want %d", tc.name, tc.last, tc.next, got, tc.want)
+		}
+	}
+}
+
+func TestSpeakingRouterForgetsClosedConnections(t *testing.T) {
+	s := &discordgo.Session{VoiceConnections: make(map[string]*discordgo.VoiceConnection)}
+	r := &speakingRouter{
+		registered: make(map[*discordgo.VoiceConnection]bool),
+		current:    make(map[*discordgo.VoiceConnection]*speakerMap),
+	}
+	v1 := &discordgo.VoiceConnection{GuildID: "g"}
+	s.VoiceConnections["g"] = v1
+
+	// 同じ接続で録音を繰り返しても登録は1つ
+	for n := 0; n < 3; n++ {
+		m := &speakerMap{users: make(map[uint32]string)}
+		detach := r.attach(s, v1, m)
+		r.dispatch(v1, &discordgo.VoiceSpeakingUpdate{SSRC: 10, UserID: "u1"})
+		if got := m.userID(10); got != "u1" {
+			t.Errorf("%d 回目の録音の話者 = %q, want u1", n, got)
+		}
+		detach()
+	}
+	if len(r.registered) != 1 || len(r.current) != 0 {
+		t.Errorf("registered = %d, current = %d, want 1, 0", len(r.registered), len(r.current))
+	}
+
+	// 切断した接続は録音の終了時に忘れる
+	m := &speakerMap{users: make(map[uint32]string)}
+	detach := r.attach(s, v1, m)
+	delete(s.VoiceConnections, "g")
+	detach()
+	if len(r.registered) != 0 {
+		t.Errorf("切断した接続が残っています: %d", len(r.registered))
+	}
+
+	// 新しい接続では古い接続を残さない
+	v2 := &discordgo.VoiceConnection{GuildID: "g"}
+	s.VoiceConnections["g"] = v2
+	r.attach(s, v2, m)
+	if len(r.registered) != 1 || !r.registered[v2] {
+		t.Errorf("registered = %v, want v2 only", r.registered)
+	}
+	r.forget(v2)
+	if len(r.registered) != 0 || len(r.current) != 0 {
+		t.Errorf("forget後も残っています: registered = %d, current = %d", len(r.registered), len(r.current))
+	}
+}
+
+func TestHandleVoiceWritesIntoDir(t *testing.T) {
+	dir := t.TempDir()
+	c := make(chan *discordgo.Packet, 2)
+	c <- &discordgo.Packet{SSRC: 1, Sequence: 1, Timestamp: 960, Opus: opusSilenceFrame}
+	c <- &discordgo.Packet{SSRC: 2, Sequence: 1, Timestamp: 960, Opus: opusSilenceFrame}
+	close(c)
+
+	tracks := handleVoice(context.Background(), c, dir, time.Now())
+	if len(tracks) != 2 {
+		t.Fatalf("got %d tracks, want 2", len(tracks))
+	}
+	for _, tr := range tracks {
+		if filepath.Dir(tr.Path) != dir {
+			t.Errorf("track %d written to %s, want under %s", tr.SSRC, tr.Path, dir)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+
+	"main/botHandler/botRouter"
+	"main/recording"
+)
+
+func StopRecordCommand(recordings *recording.Manager) *botRouter.Command {
+	/*
+		stop_recordコマンドの定義
+
+		コマンド名: stop_record
+		説明: 録音を停止して書き起こします
+		オプション: なし
+	*/
+	return &botRouter.Command{
+		Name:        "stop_record",
+		Description: "録音を停止して書き起こします",
+		Options:     []*discordgo.ApplicationCommandOption{},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return stopRecord(s, i, recordings)
+		},
+	}
+}
+
+func stopRecord(s *discordgo.Session, i *discordgo.InteractionCreate, recordings *recording.Manager) error {
+	/*
+		stop_recordコマンドの実行
+
+		録音を止めるだけで、ボイスチャンネルには接続したままにする
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "stop_record" {
+		return nil
+	}
+	if _, err := recordings.Stop(i.GuildID); err != nil {
+		return botRouter.UserError(err.Error())
+	}
+	return responseText(s, i, "録音を停止しました。書き起こしが終わると結果を送信します")
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"main/botHandler/botRouter"
+	"main/summary"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 既定のバックエンドに戻すときの選択肢
+const defaultBackendChoice = "default"
+
+func SummaryBackendCommand(selector *summary.Selector) *botRouter.Command {
+	/*
+		summary_backendコマンドの定義
+
+		コマンド名: summary_backend
+		説明: このサーバーで使う要約のバックエンドを設定します
+		オプション: backend (省略時は現在の設定を表示)
+	*/
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "既定（" + selector.Fallback() + "）", Value: defaultBackendChoice},
+	}
+	for _, name := range selector.Names() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	permission := int64(discordgo.PermissionManageServer)
+	return &botRouter.Command{
+		Name:        "summary_backend",
+		Description: "このサーバーで使う要約のバックエンドを設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "backend",
+				Description: "使用するバックエンド（省略時は現在の設定を表示）",
+				Choices:     choices,
+			},
+		},
+		DefaultMemberPermissions: &permission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleSummaryBackend(s, i, selector)
+		},
+	}
+}
+
+func handleSummaryBackend(s *discordgo.Session, i *discordgo.InteractionCreate, selector *summary.Selector) error {
+	/*
+		summary_backendコマンドの実行
+
+		バックエンドを切り替え、設定後のバックエンドを返す
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "summary_backend" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return responseText(s, i, fmt.Sprintf("要約のバックエンド: %s（利用可能: %s）",
+			selector.For(i.GuildID).Name(), strings.Join(selector.Names(), ", ")))
+	}
+
+	name := options[0].StringValue()
+	if name == defaultBackendChoice {
+		name = ""
+	}
+	if err := selector.Set(i.GuildID, name); err != nil {
+		if err == summary.ErrUnknownBackend {
+			return botRouter.UserError(err.Error())
+		}
+		return botRouter.InternalError("設定の保存に失敗しました", err)
+	}
+	return responseText(s, i, fmt.Sprintf("要約のバックエンドを %s に設定しました", selector.For(i.GuildID).Name()))
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
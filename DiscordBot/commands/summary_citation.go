@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"main/exporter"
+	"main/summary"
+)
+
+// 要約に渡した発言の番号と元のメッセージの対応
+type citations struct {
+	records map[int]*exporter.Record // 発言番号 -> メッセージ
+	invalid map[int]bool             // 要約が引用した存在しない番号
+}
+
+// 会話の行に発言番号を付ける（スレッドの区切りの行には付けない）
+// 番号は1から始まり、要約はこの番号で根拠の発言を引用する
+func numberLines(records []*exporter.Record) ([]summary.Line, *citations) {
+	byID := make(map[string]*exporter.Record, len(records))
+	for _, r := range records {
+		byID[r.ID] = r
+	}
+	cites := &citations{records: make(map[int]*exporter.Record), invalid: make(map[int]bool)}
+	var lines []summary.Line
+	for _, l := range exporter.TextLines(records) {
+		text := l.Text
+		if r, ok := byID[l.ID]; ok {
+			n := len(cites.records) + 1
+			cites.records[n] = r
+			// スレッド内の字下げは残し、その後ろに番号を付ける
+			body := strings.TrimLeft(text, " ")
+			text = text[:len(text)-len(body)] + summary.CitationMarker(n) + body
+		}
+		lines = append(lines, summary.Line{ID: l.ID, Text: text})
+	}
+	return lines, cites
+}
+
+// 本文の引用を元のメッセージへのリンクにする
+// 存在しない番号の引用は取り除き、後で知らせるために記録する
+func (c *citations) link(text string) string {
+	if c == nil {
+		return text
+	}
+	return summary.ReplaceCitations(text, func(numbers []int) string {
+		var links []string
+		for _, n := range numbers {
+			r, ok := c.records[n]
+			if !ok {
+				c.invalid[n] = true
+				continue
+			}
+			if r.URL == "" {
+				links = append(links, fmt.Sprintf("#%d", n))
+				continue
+			}
+			links = append(links, fmt.Sprintf("[#%d](%s)", n, r.URL))
+		}
+		if len(links) == 0 {
+			return ""
+		}
+		return " " + strings.Join(links, " ")
+	})
+}
+
+// 本文の各項目の引用をリンクにする
+func (c *citations) linkAll(items []string) []string {
+	linked := make([]string, len(items))
+	for n, item := range items {
+		linked[n] = c.link(item)
+	}
+	return linked
+}
+
+// 本文から引用を取り除き、引用された元のメッセージを返す（存在しない番号は記録する）
+func (c *citations) sources(text string) (string, []*exporter.Record) {
+	stripped, numbers := summary.ExtractCitations(text)
+	if c == nil {
+		return stripped, nil
+	}
+	var found []*exporter.Record
+	for _, n := range numbers {
+		if r, ok := c.records[n]; ok {
+			found = append(found, r)
+		} else {
+			c.invalid[n] = true
+		}
+	}
+	return stripped, found
+}
+
+// 存在しない番号の引用を取り除いたことの注記（無ければ空）
+func (c *citations) note() string {
+	if c == nil || len(c.invalid) == 0 {
+		return ""
+	}
+	numbers := make([]int, 0, len(c.invalid))
+	for n := range c.invalid {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	labels := make([]string, len(numbers))
+	for i, n := range numbers {
+		labels[i] = fmt.Sprintf("#%d", n)
+	}
+	return fmt.Sprintf("\n注意: 要約が会話に無い発言番号（%s）を引用していたため、その引用を取り除きました。該当する内容は元の会話で確認してください。", strings.Join(labels, ", "))
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"main/exporter"
+)
+
+func TestNumberLines(t *testing.T) {
+	records := []*exporter.Record{
+		{ID: "1", Author: "佐藤", Content: "会場はどこにしますか", URL: "https://discord.com/channels/g/c/1"},
+		{ID: "2", Author: "鈴木", Content: "駅前にしましょう", ThreadID: "t", Thread: "会場", ThreadKind: exporter.SectionThread},
+		{ID: "3", Author: "佐藤", Content: "予約します", URL: "https://discord.com/channels/g/c/3"},
+	}
+	lines, cites := numberLines(records)
+
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	want := []string{
+		"[#1] 佐藤: 会場はどこにしますか",
+		"[スレッド「会場」]",
+		"  [#2] 鈴木: 駅前にしましょう",
+		"[スレッド「会場」ここまで]",
+		"[#3] 佐藤: 予約します",
+	}
+	if strings.Join(texts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines =\n%s\nwant\n%s", strings.Join(texts, "\n"), strings.Join(want, "\n"))
+	}
+	if lines[0].ID != "1" || lines[1].ID != "" || lines[2].ID != "2" {
+		t.Errorf("line IDs = %q %q %q, want 1, none, 2", lines[0].ID, lines[1].ID, lines[2].ID)
+	}
+
+	for _, tc := range []struct {
+		name string
+		text string
+		want string
+	}{
+		{"リンクにする", "駅前に決まった [#1, #3]", "駅前に決まった [#1](https://discord.com/channels/g/c/1) [#3](https://discord.com/channels/g/c/3)"},
+		{"URLの無い発言は番号だけ", "駅前に決まった [#2]", "駅前に決まった #2"},
+		{"存在しない番号は取り除く", "駅前に決まった [#7]", "駅前に決まった"},
+		{"番号だけの括弧は残す", "[2025] 年度の会場", "[2025] 年度の会場"},
+	} {
+		if got := cites.link(tc.text); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if note := cites.note(); !strings.Contains(note, "#7") {
+		t.Errorf("note = %q, want the missing citation #7", note)
+	}
+
+	text, found := cites.sources("会場を予約する [#3][#9]")
+	if text != "会場を予約する" || len(found) != 1 || found[0].ID != "3" {
+		t.Errorf("sources = %q %v, want the message 3", text, found)
+	}
+	if !cites.invalid[9] {
+		t.Error("the missing citation #9 was not recorded")
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/exporter"
+	"main/messagestore"
+	"main/summary"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// メッセージリンク（https://discord.com/channels/<ギルド>/<チャンネル>/<メッセージ>）
+var messageLinkPattern = regexp.MustCompile(`^https://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/channels/(\d+)/(\d+)/(\d+)$`)
+
+// 「30m」「2h」「3d」「1w」のような期間
+var relativeSincePattern = regexp.MustCompile(`^(\d+)\s*(m|h|d|w)$`)
+
+// summaryで要約するメッセージの範囲
+type summaryRange struct {
+	ChannelID string
+	Since     time.Time
+	FromID    string // このメッセージ以降（含む）
+	AfterID   string // このメッセージより後（含まない）
+	Last      int    // 新しいものから数えた件数（0なら上限なし）
+	UserID    string // 空なら全員
+	SinceLast bool
+}
+
+// コマンドのオプションから範囲を作る
+// 開始位置（since・from・since_last）は1つだけ指定できる
+func parseSummaryRange(i *discordgo.InteractionCreate, history *summary.History) (*summaryRange, error) {
+	r := &summaryRange{ChannelID: i.ChannelID}
+	starts := 0
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "since":
+			t, err := parseSince(opt.StringValue(), time.Now())
+			if err != nil {
+				return nil, err
+			}
+			r.Since = t
+			starts++
+		case "from":
+			guildID, channelID, messageID, err := parseMessageLink(opt.StringValue())
+			if err != nil {
+				return nil, err
+			}
+			if guildID != "" && guildID != i.GuildID {
+				return nil, fmt.Errorf("このサーバーのメッセージリンクを指定してください")
+			}
+			if channelID != "" {
+				r.ChannelID = channelID
+			}
+			r.FromID = messageID
+			starts++
+		case "last":
+			r.Last = int(opt.IntValue())
+		case "user":
+			r.UserID = opt.UserValue(nil).ID
+		case "since_last":
+			r.SinceLast = opt.BoolValue()
+			if r.SinceLast {
+				starts++
+			}
+		}
+	}
+	if starts > 1 {
+		return nil, fmt.Errorf("since・from・since_last はどれか1つだけ指定してください")
+	}
+
+	if r.SinceLast {
+		last := history.Last(r.ChannelID)
+		if last == nil {
+			return nil, fmt.Errorf("このチャンネルはまだ要約したことがありません。since や last で範囲を指定してください")
+		}
+		r.AfterID = last.MessageID
+	}
+	return r, nil
+}
+
+// 期間（30m・2h・3d・1w）または日時を開始時刻にする
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if m := relativeSincePattern.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{
+			"m": time.Minute,
+			"h": time.Hour,
+			"d": 24 * time.Hour,
+			"w": 7 * 24 * time.Hour,
+		}[m[2]]
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+	if t, err := parseCrawlDate(value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("since の形式が正しくありません: %s（例: 2h, 3d, 1w, 2025-04-01）", value)
+}
+
+// メッセージリンクからギルド・チャンネル・メッセージのIDを取り出す（IDだけの指定も受け付ける）
+func parseMessageLink(value string) (guildID, channelID, messageID string, err error) {
+	value = strings.TrimSpace(value)
+	if m := messageLinkPattern.FindStringSubmatch(value); m != nil {
+		return m[1], m[2], m[3], nil
+	}
+	if _, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return "", "", value, nil
+	}
+	return "", "", "", fmt.Errorf("メッセージリンクの形式が正しくありません: %s", value)
+}
+
+// 古い順のメッセージから範囲に含まれるものを古い順に返す
+// 件数の指定がある場合は新しいものから数え、Botのメッセージは数えない
+func (r *summaryRange) pick(all []*discordgo.Message) []*discordgo.Message {
+	var messages []*discordgo.Message
+	for n := len(all) - 1; n >= 0; n-- {
+		m := all[n]
+		if r.AfterID != "" && !messagestore.IDLess(r.AfterID, m.ID) {
+			break
+		}
+		if r.FromID != "" && messagestore.IDLess(m.ID, r.FromID) {
+			break
+		}
+		if !r.Since.IsZero() && m.Timestamp.Before(r.Since) {
+			break
+		}
+		if m.Author == nil || m.Author.Bot {
+			continue
+		}
+		if r.UserID != "" && m.Author.ID != r.UserID {
+			continue
+		}
+		messages = append(messages, m)
+		if r.Last > 0 && len(messages) >= r.Last {
+			break
+		}
+	}
+
+	// 古い順に並べ替える
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+// 指定された条件の説明文を返す
+func (r *summaryRange) describe() string {
+	parts := []string{"<#" + r.ChannelID + ">"}
+	switch {
+	case !r.Since.IsZero():
+		parts = append(parts, r.Since.Format("2006/01/02 15:04")+"以降")
+	case r.FromID != "":
+		parts = append(parts, "指定したメッセージ以降")
+	case r.SinceLast:
+		parts = append(parts, "前回の要約以降")
+	}
+	if r.Last > 0 {
+		parts = append(parts, fmt.Sprintf("直近%d件", r.Last))
+	}
+	if r.UserID != "" {
+		parts = append(parts, "<@"+r.UserID+">")
+	}
+	return strings.Join(parts, " / ")
+}
+
+// 最も古いメッセージと最も新しいメッセージを返す
+func recordSpan(records []*exporter.Record) (first, last *exporter.Record) {
+	for _, rec := range records {
+		if first == nil || messagestore.IDLess(rec.ID, first.ID) {
+			first = rec
+		}
+		if last == nil || messagestore.IDLess(last.ID, rec.ID) {
+			last = rec
+		}
+	}
+	return first, last
+}
+
+// 実際に要約したメッセージの範囲（最初と最後のメッセージへのリンク付き）
+func summarizedRange(records []*exporter.Record) string {
+	first, last := recordSpan(records)
+	if first == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s（%s）〜 %s（%s）の %d 件",
+		first.Timestamp.Local().Format("2006/01/02 15:04"), first.URL,
+		last.Timestamp.Local().Format("2006/01/02 15:04"), last.URL,
+		len(records))
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 4, 2, 10, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"分", "30m", now.Add(-30 * time.Minute), false},
+		{"時間", "2h", now.Add(-2 * time.Hour), false},
+		{"日", "3d", now.Add(-3 * 24 * time.Hour), false},
+		{"週", "1w", now.Add(-7 * 24 * time.Hour), false},
+		{"大文字と空白", " 2 H ", now.Add(-2 * time.Hour), false},
+		{"日付", "2025-04-01", time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local), false},
+		{"日時", "2025/04/01 09:30", time.Date(2025, 4, 1, 9, 30, 0, 0, time.Local), false},
+		{"単位が無い", "30", time.Time{}, true},
+		{"知らない単位", "3y", time.Time{}, true},
+		{"空", "", time.Time{}, true},
+	} {
+		got, err := parseSince(tc.value, now)
+		if (err != nil) != tc.wantErr || !got.Equal(tc.want) {
+			t.Errorf("%s: parseSince(%q) = %v, %v, want %v (error %v)", tc.name, tc.value, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestParseMessageLink(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		value   string
+		want    [3]string // ギルド・チャンネル・メッセージ
+		wantErr bool
+	}{
+		{"リンク", "https://discord.com/channels/1/2/3", [3]string{"1", "2", "3"}, false},
+		{"PTBのリンク", "https://ptb.discord.com/channels/1/2/3", [3]string{"1", "2", "3"}, false},
+		{"古いドメイン", " https://discordapp.com/channels/1/2/3 ", [3]string{"1", "2", "3"}, false},
+		{"IDだけ", "1300000000000000000", [3]string{"", "", "1300000000000000000"}, false},
+		{"チャンネルのリンク", "https://discord.com/channels/1/2", [3]string{}, true},
+		{"別のサイト", "https://example.com/channels/1/2/3", [3]string{}, true},
+		{"数字でない", "abc", [3]string{}, true},
+	} {
+		guildID, channelID, messageID, err := parseMessageLink(tc.value)
+		if got := [3]string{guildID, channelID, messageID}; (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%s: parseMessageLink(%q) = %v, %v, want %v (error %v)", tc.name, tc.value, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestSummaryRangePick(t *testing.T) {
+	base := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	var all []*discordgo.Message
+	for n := 1; n <= 9; n++ {
+		author := &discordgo.User{ID: "u1"}
+		if n%2 == 0 {
+			author = &discordgo.User{ID: "u2"}
+		}
+		if n == 5 {
+			author = &discordgo.User{ID: "bot", Bot: true}
+		}
+		all = append(all, &discordgo.Message{ID: fmt.Sprint(n), Author: author, Timestamp: base.Add(time.Duration(n) * time.Hour)})
+	}
+
+	for _, tc := range []struct {
+		name string
+		rng  summaryRange
+		want string
+	}{
+		{"すべて（Botを除く）", summaryRange{}, "[1 2 3 4 6 7 8 9]"},
+		{"日時以降", summaryRange{Since: base.Add(7 * time.Hour)}, "[7 8 9]"},
+		{"メッセージ以降（含む）", summaryRange{FromID: "7"}, "[7 8 9]"},
+		{"メッセージより後（含まない）", summaryRange{AfterID: "7"}, "[8 9]"},
+		{"新しいものから件数（Botは数えない）", summaryRange{Last: 3}, "[7 8 9]"},
+		{"ユーザー", summaryRange{UserID: "u2"}, "[2 4 6 8]"},
+		{"ユーザーと件数", summaryRange{UserID: "u2", Last: 2}, "[6 8]"},
+		{"Botを挟む件数", summaryRange{Last: 5}, "[4 6 7 8 9]"},
+		{"範囲に無い", summaryRange{AfterID: "9"}, "[]"},
+	} {
+		var ids []string
+		for _, m := range tc.rng.pick(all) {
+			ids = append(ids, m.ID)
+		}
+		if got := fmt.Sprint(ids); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"main/botHandler/botRouter"
+	"main/summary"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// スタイルと言語の表示名（「決定事項とアクションアイテム / English」など）
+func describeFormat(f summaryFormat) string {
+	style, lang := f.Style, f.Language
+	if s, ok := summary.StyleByName(f.Style); ok {
+		style = s.Label
+	}
+	if l, ok := summary.LanguageByCode(f.Language); ok {
+		lang = l.Label
+	}
+	return style + " / " + lang
+}
+
+// 要約のスタイルの選択肢
+func styleChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(summary.Styles))
+	for _, s := range summary.Styles {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: s.Label, Value: s.Name})
+	}
+	return choices
+}
+
+// 要約を出力する言語の選択肢
+func languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(summary.Languages))
+	for _, l := range summary.Languages {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: l.Label, Value: l.Code})
+	}
+	return choices
+}
+
+func SummaryTemplateCommand(templates *summary.Templates) *botRouter.Command {
+	/*
+		summary_templateコマンドの定義
+
+		コマンド名: summary_template
+		説明: このサーバーで使う要約のスタイルごとの指示（テンプレート）を設定します
+		サブコマンド: show, set, reset
+	*/
+	permission := int64(discordgo.PermissionManageServer)
+	styleOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "style",
+		Description: "要約のスタイル",
+		Required:    true,
+		Choices:     styleChoices(),
+	}
+	return &botRouter.Command{
+		Name:        "summary_template",
+		Description: "このサーバーで使う要約のスタイルごとの指示（テンプレート）を設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "スタイルのテンプレートを表示します",
+				Options:     []*discordgo.ApplicationCommandOption{styleOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "スタイルのテンプレートを上書きします",
+				Options: []*discordgo.ApplicationCommandOption{
+					styleOption,
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "template",
+						Description: "要約の指示（{channel} {guild} {from} {to} {messages} {language} が使えます。\\n で改行）",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reset",
+				Description: "スタイルのテンプレートを既定に戻します",
+				Options:     []*discordgo.ApplicationCommandOption{styleOption},
+			},
+		},
+		DefaultMemberPermissions: &permission,
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleSummaryTemplate(s, i, templates)
+		},
+	}
+}
+
+func handleSummaryTemplate(s *discordgo.Session, i *discordgo.InteractionCreate, templates *summary.Templates) error {
+	/*
+		summary_templateコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "summary_template" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+	style := args["style"].StringValue()
+
+	switch sub.Name {
+	case "show":
+		tmpl, custom, err := templates.Get(i.GuildID, style)
+		if err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		state := "既定"
+		if custom {
+			state = "このサーバーで上書き"
+		}
+		text := fmt.Sprintf("スタイル `%s` のテンプレート（%s）\n```\n%s\n```\n使える変数: %s", style, state, tmpl, summary.VariablesHelp())
+		return responseText(s, i, truncateRunes(text, discordMessageLimit))
+
+	case "set":
+		// スラッシュコマンドでは改行を入力できないため \n を改行として扱う
+		tmpl := strings.ReplaceAll(args["template"].StringValue(), `\n`, "\n")
+		if err := summary.ValidateTemplate(tmpl); err != nil {
+			return botRouter.UserError(err.Error())
+		}
+		if err := templates.Set(i.GuildID, style, tmpl); err != nil {
+			if errors.Is(err, summary.ErrUnknownStyle) {
+				return botRouter.UserError(err.Error())
+			}
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, truncateRunes(fmt.Sprintf("スタイル `%s` のテンプレートを設定しました\n```\n%s\n```", style, tmpl), discordMessageLimit))
+
+	case "reset":
+		if err := templates.Reset(i.GuildID, style); err != nil {
+			if errors.Is(err, summary.ErrUnknownStyle) {
+				return botRouter.UserError(err.Error())
+			}
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, fmt.Sprintf("スタイル `%s` のテンプレートを既定に戻しました", style))
+	}
+	return nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
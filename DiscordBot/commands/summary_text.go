@@ -1,112 +1,431 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"main/botHandler/botRouter"
-	"net/http"
+	"main/exporter"
+	"main/httpclient"
+	"main/messagestore"
+	"main/redact"
+	"main/render"
+	"main/summary"
+	"main/task"
+	"main/usage"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-const fastAPIURL = "https://st-kdaz.onrender.com/items/"
+const (
+	// 要約を待つ時間の上限（起動に時間がかかるサーバーや長い会話があるため長めにする）
+	summaryTimeout = 10 * time.Minute
+	// 進捗表示を更新する間隔
+	summaryProgressInterval = 3 * time.Second
+)
+
+// 要約コマンドの設定
+type SummaryConfig struct {
+	Messages    *messagestore.Store
+	Redactor    *redact.Engine
+	History     *summary.History   // チャンネルごとに最後に要約したメッセージ
+	Summarizers *summary.Selector  // ギルドごとの要約バックエンド
+	Pipeline    *summary.Pipeline  // 長い会話の分割と並行実行の設定
+	Tasks       *task.Store        // アクションアイテムから作るタスク（nilなら作らない）
+	Templates   *summary.Templates // ギルドごとに上書きしたスタイルのテンプレート
+	Cache       *summary.Cache     // 同じ範囲の要約の再利用（nilなら毎回要約する）
+	Usage       *usage.Ledger      // ギルドごとの利用量と月間の上限（nilなら記録しない）
+}
+
+// 要約のスタイルと出力する言語
+type summaryFormat struct {
+	Style    string
+	Language string
+}
 
 // 命名を変更
-func SummariesCommand() *botRouter.Command {
+func SummariesCommand(cfg *SummaryConfig) *botRouter.Command {
+	/*
+		コマンド名: summary
+		説明: 会話を要約します
+		オプション: since, last, from, user, since_last, include_threads, style, language
+	*/
+	minLast := 1.0
 	return &botRouter.Command{
 		Name:        "summary",
-		Description: "このチャンネルまたはスレッドの会話全体をFastAPIサーバに送信して要約を受け取ります",
-		Executor:    handleSummaries,
+		Description: "このチャンネルまたはスレッドの会話を要約します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "since",
+				Description: "この期間・日時以降の会話（例: 2h, 3d, 1w, 2025-04-01）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "last",
+				Description: "直近のメッセージの件数",
+				MinValue:    &minLast,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "from",
+				Description: "このメッセージ以降の会話（メッセージリンク）",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "このユーザーのメッセージだけを要約する",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "since_last",
+				Description: "前回の要約以降の会話だけを要約する",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "include_threads",
+				Description: "スレッド・フォーラム投稿の会話も含める",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "style",
+				Description: "要約のスタイル（省略時は標準）",
+				Choices:     styleChoices(),
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "language",
+				Description: "要約を出力する言語（省略時は日本語）",
+				Choices:     languageChoices(),
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleSummaries(s, i, cfg)
+		},
 	}
 }
 
 // 命名を変更
-func handleSummaries(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	channelID := i.ChannelID
+func handleSummaries(s *discordgo.Session, i *discordgo.InteractionCreate, cfg *SummaryConfig) error {
+	/*
+		summaryコマンドの実行
 
-	// 3秒以内に一時応答を返す
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-	})
-	if err != nil {
-		log.Printf("初期応答失敗: %v\n", err)
-		return
+		範囲を絞り込んだ会話を要約し、要約した範囲と一緒に返す
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "summary" {
+		return nil
 	}
 
-	// 全メッセージ取得（100件ずつ繰り返し）
-	messages, err := fetchAllMessages(s, channelID)
+	rng, err := parseSummaryRange(i, cfg.History)
 	if err != nil {
-		log.Printf("メッセージ取得失敗: %v\n", err)
-		editWithError(s, i, "メッセージの取得に失敗しました。")
-		return
+		return botRouter.UserError(err.Error())
+	}
+	if rng.ChannelID != i.ChannelID && !canReadChannel(s, rng.ChannelID, i.Member.User.ID) {
+		return botRouter.UserError("指定したメッセージのチャンネルを閲覧する権限がありません。")
+	}
+	scope := &conversationScope{
+		GuildID:   i.GuildID,
+		ChannelID: rng.ChannelID,
+		ViewerID:  i.Member.User.ID,
+		Pick:      rng.pick,
+	}
+	format := summaryFormat{Style: summary.StyleStandard, Language: summary.DefaultLanguage}
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "include_threads":
+			scope.IncludeThreads = opt.BoolValue()
+		case "style":
+			format.Style = opt.StringValue()
+		case "language":
+			format.Language = opt.StringValue()
+		}
 	}
 
-	// 古い順に並べ替え
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	// 他のチャンネル・スレッドの要約は、このチャンネルを見られる人に公開しないよう本人にだけ送る
+	// スレッドを含める場合も、非公開スレッドが混ざることがあるため同じ扱いにする
+	private := rng.ChannelID != i.ChannelID || scope.IncludeThreads
+	response := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}
+	if private {
+		response.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	// 3秒以内に一時応答を返す
+	err = s.InteractionRespond(i.Interaction, response)
+	if err != nil {
+		return err
 	}
 
-	// ユーザーメッセージのみ抽出
-	var buffer bytes.Buffer
-	for _, msg := range messages {
-		if msg.Author.Bot {
-			continue
+	// 要約を待つ間に、対象の範囲と進み具合を一時応答に表示する
+	var header string
+	started := func(records []*exporter.Record) {
+		header = fmt.Sprintf("要約範囲: %s\n%s", rng.describe(), summarizedRange(records))
+		if format.Style != summary.StyleStandard || format.Language != summary.DefaultLanguage {
+			header += "\nスタイル: " + describeFormat(format)
 		}
-		buffer.WriteString(fmt.Sprintf("%s: %s\n", msg.Author.Username, msg.Content))
+		editWithError(s, i, header+"\n要約しています…")
+	}
+	var mu sync.Mutex
+	lastEdit := time.Now()
+	progress := func(p summary.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(lastEdit) < summaryProgressInterval {
+			return
+		}
+		lastEdit = time.Now()
+		editWithError(s, i, header+"\n"+formatSummaryProgress(p))
+	}
+
+	out, err := summarizeConversation(s, cfg, scope, format, "summary", started, progress)
+	if err != nil {
+		return err
+	}
+	if len(out.Records) == 0 {
+		return botRouter.UserError("指定した範囲に要約するメッセージが見つかりませんでした。\n" + rng.describe())
 	}
+	if out.Partials > 0 {
+		header += fmt.Sprintf("\n（会話が長いため %d 個の区間に分けて要約しました）", out.Partials)
+	}
+	if out.Cached {
+		header += "\n（同じ範囲の前回の要約を再利用しました）"
+	}
+	resp := summaryResponse(header, out)
+	resp.FileName = fmt.Sprintf("summary_%s", time.Now().Format("20060102_150405"))
+	resp.Ephemeral = private
 
-	if buffer.Len() == 0 {
-		log.Println("ユーザーのメッセージが見つかりませんでした。")
-		editWithError(s, i, "要約するためのメッセージが見つかりませんでした。")
-		return
+	// 次回の since_last のために、要約した最新のメッセージを記録する
+	// 特定のユーザーの発言だけを要約したときは、他の人の発言が要約されていないため記録しない
+	if rng.UserID == "" {
+		if err := cfg.History.Record(rng.ChannelID, out.NewestID(), i.Member.User.ID); err != nil {
+			log.Printf("要約の記録に失敗しました: %v\n", err)
+		}
 	}
 
-	// FastAPIに送信するJSON
-	payload := map[string]string{"description": buffer.String()}
-	log.Printf("FastAPIに送信するJSON内容: %v\n", payload["description"])
+	// 結果を埋め込みで送信（長ければ分割、さらに長ければファイルにする）
+	return render.Interaction(s, i, resp)
+}
+
+// 要約の結果
+type summaryOutcome struct {
+	Document  *summary.Document
+	Format    summaryFormat
+	Note      string // 伏せ字の注記
+	Records   []*exporter.Record
+	Citations *citations   // 発言番号と元のメッセージの対応
+	Partials  int          // 分割して要約した区間の数（分割しなければ0）
+	Cached    bool         // 覚えていた要約を再利用したか
+	Tasks     []*task.Task // アクションアイテムから作ったタスク（アクションアイテムと同じ順）
+}
 
-	jsonData, err := json.Marshal(payload)
+// 要約に含めた最新のメッセージ
+func (o *summaryOutcome) NewestID() string {
+	if _, newest := recordSpan(o.Records); newest != nil {
+		return newest.ID
+	}
+	return ""
+}
+
+// 会話を集めて個人情報を伏せ字にし、ギルドで選ばれているバックエンドで要約する
+// startedは要約を始める前に対象のメッセージを渡して呼ばれる。対象が無ければRecordsが空の結果を返す
+func summarizeConversation(s *discordgo.Session, cfg *SummaryConfig, scope *conversationScope, format summaryFormat, source string, started func([]*exporter.Record), progress func(summary.Progress)) (*summaryOutcome, error) {
+	// 保存済みのメッセージを最新にしてから古い順に取得
+	var root *exporter.Section
+	err := botRouter.Retry(func() (err error) {
+		root, err = collectConversation(s, cfg.Messages, scope)
+		return err
+	})
 	if err != nil {
-		log.Printf("JSONエンコード失敗: %v\n", err)
-		editWithError(s, i, "要約リクエストの準備に失敗しました。")
-		return
+		return nil, botRouter.AsCommandError(err).WithMessage("メッセージの取得に失敗しました。")
+	}
+
+	// ユーザーメッセージのみ抽出（外部のAPIに送る前に個人情報を伏せ字にする）
+	// スレッドの区切りと返信先を残し、会話の流れが分かる形にする
+	redaction := cfg.Redactor.NewSession(scope.GuildID, source)
+	root.Apply(exporter.SkipBots(), redact.RecordFilter(redaction), exporter.SkipEmpty())
+	records := root.Flatten()
+	if len(records) == 0 {
+		return &summaryOutcome{}, nil
+	}
+	if started != nil {
+		started(records)
 	}
 
-	// FastAPIへPOSTリクエスト
-	resp, err := http.Post(fastAPIURL, "application/json", bytes.NewBuffer(jsonData))
+	// 発言に番号を付け、要約から元のメッセージを引用できるようにする
+	lines, cites := numberLines(records)
+
+	summarizer := cfg.Summarizers.For(scope.GuildID)
+	base, err := summaryRequest(s, cfg, scope, format, records)
 	if err != nil {
-		log.Printf("FastAPIへのリクエスト失敗: %v\n", err)
-		editWithError(s, i, "FastAPIとの通信に失敗しました。")
-		return
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("FastAPIから異常なステータスコード: %d\n", resp.StatusCode)
-		editWithError(s, i, "FastAPIからの応答に問題がありました。")
-		return
+	// 同じ範囲・スタイル・モデルの要約があれば、バックエンドを呼ばずに使う（上限に達していても使える）
+	cacheKey := summary.NewCacheKey(scope.ChannelID, base, summarizer.Model(), lines)
+	digest := summary.CacheDigest(base, lines)
+	summarized, cached := cfg.Cache.Get(cacheKey, digest)
+	if cached {
+		log.Printf("%s の %d 行の会話は保存済みの要約を使います\n", summarizer.Name(), len(lines))
+		if cfg.Usage != nil {
+			if err := cfg.Usage.AddCacheHit(scope.GuildID); err != nil {
+				log.Printf("要約の利用量の記録に失敗しました: %v\n", err)
+			}
+		}
+	} else {
+		if err := checkQuota(cfg.Usage, scope.GuildID, usage.Summary); err != nil {
+			return nil, err
+		}
+		// 長い会話は区間に分けて要約する
+		log.Printf("%s で %d 行の会話を要約します\n", summarizer.Name(), len(lines))
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		defer cancel()
+		var sz summary.Summarizer = summarizer
+		if cfg.Usage != nil {
+			sz = &meteredSummarizer{Summarizer: summarizer, ledger: cfg.Usage, guildID: scope.GuildID}
+		}
+		if summarized, err = cfg.Pipeline.Run(ctx, sz, base, lines, progress); err != nil {
+			return nil, summarizeError(err)
+		}
+		log.Printf("%s からの返り値（要約結果）:\n%s\n", summarizer.Name(), summarized.Summary)
+		cfg.Cache.Put(cacheKey, digest, lines, summarized)
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	key, err := redaction.Save()
 	if err != nil {
-		log.Printf("レスポンス読み込み失敗: %v\n", err)
-		editWithError(s, i, "FastAPIの応答を受信できませんでした。")
-		return
+		return nil, botRouter.InternalError("伏せ字の対応表を保存できませんでした。", err)
+	}
+	doc := summary.ParseDocument(summarized.Summary)
+	out := &summaryOutcome{
+		Document:  doc,
+		Format:    format,
+		Note:      redactionNote(redaction, key),
+		Records:   records,
+		Citations: cites,
+		Partials:  len(summarized.Partials),
+		Cached:    cached,
+	}
+	// 再利用した要約のアクションアイテムは前回タスクにしているため、完了済みのものを作り直さない
+	if !cached {
+		out.Tasks = trackActionItems(cfg.Tasks, scope, source, doc.ActionItems, records, cites)
 	}
-	summary := string(bodyBytes)
+	return out, nil
+}
 
-	log.Printf("FastAPIからの返り値（要約結果）:\n%s\n", summary)
+// ギルドのテンプレートに範囲やチャンネル名を埋め込み、バックエンドへの依頼の元にする
+func summaryRequest(s *discordgo.Session, cfg *SummaryConfig, scope *conversationScope, format summaryFormat, records []*exporter.Record) (summary.Request, error) {
+	req := summary.Request{GuildID: scope.GuildID, Style: format.Style, Language: format.Language}
+	style, ok := summary.StyleByName(format.Style)
+	if !ok {
+		return req, botRouter.UserError(summary.ErrUnknownStyle.Error() + ": " + format.Style)
+	}
+	tmpl := style.Template
+	if cfg.Templates != nil {
+		var err error
+		if tmpl, _, err = cfg.Templates.Get(scope.GuildID, format.Style); err != nil {
+			return req, botRouter.UserError(err.Error())
+		}
+	}
 
-	// 結果を送信
-	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &summary,
-	})
+	vars := map[string]string{
+		"channel":  "#" + channelName(s, scope.ChannelID),
+		"guild":    scope.GuildID,
+		"messages": fmt.Sprint(len(records)),
+		"language": "日本語",
+	}
+	if g, err := s.State.Guild(scope.GuildID); err == nil {
+		vars["guild"] = g.Name
+	}
+	if lang, ok := summary.LanguageByCode(format.Language); ok {
+		vars["language"] = lang.Label
+	}
+	if first, last := recordSpan(records); first != nil {
+		vars["from"] = first.Timestamp.Local().Format("2006/01/02 15:04")
+		vars["to"] = last.Timestamp.Local().Format("2006/01/02 15:04")
+	}
+	req.Instructions = summary.RenderTemplate(tmpl, vars)
+	return req, nil
+}
+
+// チャンネルの名前（取得できなければID）
+func channelName(s *discordgo.Session, channelID string) string {
+	ch, err := s.State.Channel(channelID)
 	if err != nil {
-		log.Printf("編集応答送信失敗: %v\n", err)
+		if ch, err = s.Channel(channelID); err != nil {
+			return channelID
+		}
+	}
+	return ch.Name
+}
+
+// 言語ごとのタイトルと見出し（要約の本文と揃える）
+var summaryHeadings = map[string][4]string{
+	"ja": {"要約", "話題", "決定事項", "アクションアイテム"},
+	"en": {"Summary", "Topics", "Decisions", "Action items"},
+	"vi": {"Tóm tắt", "Chủ đề", "Quyết định", "Việc cần làm"},
+}
+
+// 要約を応答の形にする（範囲などの説明は本文、要約は埋め込みに載せる）
+// 要約の引用は元のメッセージへのリンクにし、存在しない番号の引用は取り除いて本文で知らせる
+func summaryResponse(header string, out *summaryOutcome) *render.Response {
+	doc := out.Document
+	cites := out.Citations
+	headings, ok := summaryHeadings[out.Format.Language]
+	if !ok {
+		headings = summaryHeadings[summary.DefaultLanguage]
+	}
+	resp := &render.Response{
+		Title:       headings[0],
+		Description: cites.link(doc.Overview),
+		Sections: []render.Section{
+			{Title: headings[1], Items: cites.linkAll(doc.Topics)},
+			{Title: headings[2], Items: cites.linkAll(doc.Decisions)},
+			{Title: headings[3], Items: actionItemLines(doc.ActionItems, out.Tasks, cites)},
+		},
 	}
+	resp.Content = header + out.Note + cites.note()
+	return resp
+}
+
+// 要約の進捗表示
+func formatSummaryProgress(p summary.Progress) string {
+	switch p.Stage {
+	case summary.StageChunk:
+		return fmt.Sprintf("区間ごとに要約しています… %d/%d", p.Done, p.Total)
+	case summary.StageCombine:
+		if p.Total > 1 {
+			return fmt.Sprintf("部分要約をまとめています… %d/%d", p.Done, p.Total)
+		}
+		return "部分要約をまとめています…"
+	}
+	return "要約しています…"
+}
+
+// 要約バックエンドのエラーをユーザーへの案内に変える
+func summarizeError(err error) error {
+	// 要約サーバーが続けて失敗している間は、待たせずにすぐ知らせる
+	var unavailable *httpclient.UnavailableError
+	if errors.As(err, &unavailable) {
+		return botRouter.UserError(fmt.Sprintf("要約サーバー（%s）が応答していないため、呼び出しを止めています。%s後にもう一度お試しください。", unavailable.Upstream, unavailable.RetryIn()))
+	}
+	var httpErr *summary.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Temporary() {
+			return botRouter.TransientError("要約サーバーからの応答に問題がありました。", err)
+		}
+		return botRouter.InternalError("要約サーバーからの応答に問題がありました。", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return botRouter.TransientError("要約サーバーからの応答がありませんでした。", err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return botRouter.TransientError("要約サーバーとの通信に失敗しました。", err)
+	}
+	return botRouter.InternalError("要約に失敗しました。", err)
 }
 
 // エラーメッセージを編集して送信
@@ -119,30 +438,18 @@ func editWithError(s *discordgo.Session, i *discordgo.InteractionCreate, message
 	}
 }
 
-// 追記項目全件取得関数
-func fetchAllMessages(s *discordgo.Session, channelID string) ([]*discordgo.Message, error) {
-	var allMessages []*discordgo.Message
-	var lastMessageID string
-
-	for {
-		messages, err := s.ChannelMessages(channelID, 100, lastMessageID, "", "")
-		if err != nil {
-			return nil, err
-		}
-		if len(messages) == 0 {
-			break
-		}
-
-		allMessages = append(allMessages, messages...)
-		lastMessageID = messages[len(messages)-1].ID
-
-		if len(messages) < 100 {
-			break
-		}
+// チャンネルを同期し、保存済みの全メッセージを古い順に返す
+func syncedMessages(s *discordgo.Session, store *messagestore.Store, channelID string) ([]*discordgo.Message, error) {
+	if err := store.Sync(s, channelID); err != nil {
+		return nil, err
+	}
+	messages, err := store.Messages(channelID)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("合計 %d 件のメッセージを取得しました。\n", len(allMessages))
-	return allMessages, nil
+	log.Printf("合計 %d 件のメッセージを取得しました。\n", len(messages))
+	return messages, nil
 }
 
 /* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
@@ -0,0 +1,460 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"main/botHandler/botRouter"
+	"main/exporter"
+	"main/render"
+	"main/summary"
+	"main/task"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TaskCommand(store *task.Store) *botRouter.Command {
+	/*
+		taskコマンドの定義
+
+		コマンド名: task
+		説明: 要約のアクションアイテムから作ったタスクを管理します
+		サブコマンド: list, done, assign, report
+	*/
+	minID := 1.0
+	idOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        "id",
+		Description: "list で表示されるタスクの番号",
+		Required:    true,
+		MinValue:    &minID,
+	}
+	return &botRouter.Command{
+		Name:        "task",
+		Description: "要約のアクションアイテムから作ったタスクを管理します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "未完了のタスクを表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "このユーザーが担当するタスクだけを表示する",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "include_done",
+						Description: "完了したタスクも表示する",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "done",
+				Description: "タスクを完了にします",
+				Options:     []*discordgo.ApplicationCommandOption{idOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "assign",
+				Description: "タスクの担当者を設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					idOption,
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "担当者",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "report",
+				Description: "毎週月曜日の朝に未完了のタスクを投稿するチャンネルを設定します（サーバー管理者のみ）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "投稿先のチャンネル（省略すると投稿をやめる）",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+					},
+				},
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleTask(s, i, store)
+		},
+	}
+}
+
+func handleTask(s *discordgo.Session, i *discordgo.InteractionCreate, store *task.Store) error {
+	/*
+		taskコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "task" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "list":
+		assigneeID := ""
+		if opt, ok := args["user"]; ok {
+			assigneeID = opt.UserValue(nil).ID
+		}
+		includeDone := false
+		if opt, ok := args["include_done"]; ok {
+			includeDone = opt.BoolValue()
+		}
+		// 読めないチャンネルから作ったタスクは表示しない
+		var lines []string
+		for _, t := range store.List(i.GuildID, assigneeID, includeDone) {
+			if canReadChannel(s, t.Source.ChannelID, i.Member.User.ID) {
+				lines = append(lines, formatTask(t))
+			}
+		}
+		if len(lines) == 0 {
+			return respondTask(s, i, "未完了のタスクはありません", true)
+		}
+		return respondTask(s, i, truncateRunes(strings.Join(lines, "\n"), discordMessageLimit), true)
+
+	case "done":
+		id := int(args["id"].IntValue())
+		current, err := findTask(s, store, i, id)
+		if err != nil {
+			return err
+		}
+		if !canChangeTask(i, current) {
+			return botRouter.UserError(fmt.Sprintf("タスク `#%d` は %s の担当です。担当者かサーバー管理者が完了にしてください。", id, current.Assignee()))
+		}
+		t, err := store.Done(i.GuildID, id, i.Member.User.ID)
+		if err != nil {
+			return taskError(err)
+		}
+		return respondTask(s, i, fmt.Sprintf("タスク `#%d` を完了にしました: %s", t.ID, t.Text), privateTask(s, i, t))
+
+	case "assign":
+		id := int(args["id"].IntValue())
+		user := args["user"].UserValue(nil)
+		current, err := findTask(s, store, i, id)
+		if err != nil {
+			return err
+		}
+		if !canChangeTask(i, current) {
+			return botRouter.UserError(fmt.Sprintf("タスク `#%d` は %s の担当です。担当者かサーバー管理者が担当者を変更してください。", id, current.Assignee()))
+		}
+		t, err := store.Assign(i.GuildID, id, user.ID)
+		if err != nil {
+			return taskError(err)
+		}
+		return respondTask(s, i, fmt.Sprintf("タスク `#%d` の担当者を <@%s> にしました: %s", t.ID, user.ID, t.Text), privateTask(s, i, t))
+
+	case "report":
+		if !canManageServer(i) {
+			return botRouter.UserError("報告先はサーバー管理者だけが設定できます。")
+		}
+		channelID := ""
+		if opt, ok := args["channel"]; ok {
+			channelID = opt.ChannelValue(nil).ID
+		}
+		r, err := store.SetReportChannel(i.GuildID, channelID)
+		if err != nil {
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		if r == nil {
+			return responseText(s, i, "未完了タスクの報告をやめました")
+		}
+		return responseText(s, i, fmt.Sprintf("毎週月曜日の朝に <#%s> へ未完了のタスクを投稿します（次回 %s）", r.ChannelID, r.NextRunAt.Format("2006/01/02 15:04")))
+	}
+	return nil
+}
+
+// 実行した人が読めるチャンネルから作ったタスクを探す
+func findTask(s *discordgo.Session, store *task.Store, i *discordgo.InteractionCreate, id int) (*task.Task, error) {
+	for _, t := range store.List(i.GuildID, "", true) {
+		if t.ID == id && canReadChannel(s, t.Source.ChannelID, i.Member.User.ID) {
+			return t, nil
+		}
+	}
+	return nil, botRouter.UserError(task.ErrNotFound.Error())
+}
+
+// 元のメッセージが実行したチャンネル以外の非公開のチャンネルにあるタスクか
+func privateTask(s *discordgo.Session, i *discordgo.InteractionCreate, t *task.Task) bool {
+	return t.Source.ChannelID != i.ChannelID && !publicChannel(s, t.Source.ChannelID)
+}
+
+// タスクの内容を返す（privateなら実行した人にだけ見せる）
+func respondTask(s *discordgo.Session, i *discordgo.InteractionCreate, content string, private bool) error {
+	data := &discordgo.InteractionResponseData{Content: content}
+	if private {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+func taskError(err error) error {
+	if errors.Is(err, task.ErrNotFound) || errors.Is(err, task.ErrAlreadyDone) {
+		return botRouter.UserError(err.Error())
+	}
+	return botRouter.InternalError("タスクの保存に失敗しました", err)
+}
+
+// 担当者の決まったタスクは、担当者かサーバー管理者だけが完了にしたり担当者を変えたりできる
+func canChangeTask(i *discordgo.InteractionCreate, t *task.Task) bool {
+	return t.AssigneeID == "" || t.AssigneeID == i.Member.User.ID || canManageServer(i)
+}
+
+func canManageServer(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionManageServer != 0
+}
+
+// 一覧に表示する1行
+func formatTask(t *task.Task) string {
+	line := fmt.Sprintf("`#%d` %s — %s", t.ID, t.Text, t.Assignee())
+	if due := formatDue(t); due != "" {
+		line += "（期限 " + due + "）"
+	}
+	if !t.Open() {
+		line = "~~" + line + "~~ 完了"
+	}
+	if t.Source.URL != "" {
+		line += fmt.Sprintf(" [元のメッセージ](%s)", t.Source.URL)
+	}
+	return line
+}
+
+func formatDue(t *task.Task) string {
+	if t.Due.IsZero() {
+		return t.DueText
+	}
+	return fmt.Sprintf("%s(%s)", t.Due.Format("1/2"), []string{"日", "月", "火", "水", "木", "金", "土"}[t.Due.Weekday()])
+}
+
+// 要約のアクションアイテムからタスクを作る
+// 担当者の名前は会話の発言者と照らし合わせ、期限は元のメッセージの日時から決める。
+// 元のメッセージは要約が引用した発言を優先する。
+// 返すスライスはアクションアイテムと同じ順で、作れなかった項目はnilになる
+func trackActionItems(store *task.Store, scope *conversationScope, command string, items []summary.ActionItem, records []*exporter.Record, cites *citations) []*task.Task {
+	if store == nil || len(items) == 0 || len(records) == 0 {
+		return nil
+	}
+	tracked := make([]*task.Task, len(items))
+	for n, item := range items {
+		text, cited := cites.sources(item.Task)
+		if text == "" {
+			continue
+		}
+		item.Task = text
+		source := sourceRecord(records, item)
+		if len(cited) > 0 {
+			source = cited[0]
+		}
+		t := &task.Task{
+			GuildID:      scope.GuildID,
+			ChannelID:    source.ChannelID,
+			Text:         item.Task,
+			AssigneeID:   matchAuthor(records, item.Assignee),
+			AssigneeName: item.Assignee,
+			DueText:      item.Due,
+			Source: task.Source{
+				Command:   command,
+				ChannelID: source.ChannelID,
+				MessageID: source.ID,
+				URL:       source.URL,
+			},
+			CreatedBy: scope.ViewerID,
+		}
+		if due, ok := task.ParseDue(item.Due, source.Timestamp.Local()); ok {
+			t.Due = due
+		}
+		added, _, err := store.Create(t)
+		if err != nil {
+			log.Printf("タスクの保存に失敗しました: %v\n", err)
+			continue
+		}
+		tracked[n] = added
+	}
+	return tracked
+}
+
+// アクションアイテムの元になったメッセージを探す
+// 内容の重なり（2文字の組）が最も多いメッセージを選び、担当者本人の発言を優先する。
+// 見つからなければ範囲の最新のメッセージにする
+func sourceRecord(records []*exporter.Record, item summary.ActionItem) *exporter.Record {
+	grams := bigrams(item.Task)
+	var best *exporter.Record
+	bestScore := 0
+	for _, r := range records {
+		score := 0
+		for g := range bigrams(r.Content) {
+			if grams[g] {
+				score += 2
+			}
+		}
+		if score > 0 && item.Assignee != "" && sameName(r.Author, item.Assignee) {
+			score++
+		}
+		if score > bestScore || (score == bestScore && score > 0 && r.Timestamp.After(best.Timestamp)) {
+			best, bestScore = r, score
+		}
+	}
+	if best == nil {
+		_, best = recordSpan(records)
+	}
+	return best
+}
+
+func bigrams(text string) map[string]bool {
+	runes := []rune(strings.Join(strings.Fields(text), ""))
+	grams := make(map[string]bool)
+	for n := 0; n+1 < len(runes); n++ {
+		grams[string(runes[n:n+2])] = true
+	}
+	return grams
+}
+
+// 要約に書かれた担当者の名前に合う発言者のIDを返す（見つからなければ空）
+func matchAuthor(records []*exporter.Record, name string) string {
+	if name == "" {
+		return ""
+	}
+	if strings.HasPrefix(name, "<@") && strings.HasSuffix(name, ">") {
+		return strings.TrimPrefix(strings.Trim(name, "<@>"), "!")
+	}
+	for _, r := range records {
+		if sameName(r.Author, name) {
+			return r.AuthorID
+		}
+	}
+	return ""
+}
+
+// 敬称や表記の揺れを無視して名前を比べる（2文字以上なら部分一致も認める）
+func sameName(author, name string) bool {
+	normalize := func(s string) string {
+		s = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s, "@")))
+		for _, honorific := range []string{"さん", "くん", "君", "先生", "様"} {
+			s = strings.TrimSuffix(s, honorific)
+		}
+		return s
+	}
+	a, b := normalize(author), normalize(name)
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	return utf8.RuneCountInString(b) >= 2 && (strings.Contains(a, b) || strings.Contains(b, a))
+}
+
+// 要約の「アクションアイテム」の項目（タスクにしたものは番号を添える）
+func actionItemLines(items []summary.ActionItem, tracked []*task.Task, cites *citations) []string {
+	lines := make([]string, 0, len(items))
+	for n, item := range items {
+		if n >= len(tracked) || tracked[n] == nil {
+			lines = append(lines, cites.link(item.String()))
+			continue
+		}
+		lines = append(lines, formatTask(tracked[n]))
+	}
+	return lines
+}
+
+// 期限が近いタスクを担当者にDMで知らせる
+// 担当者がいないかDMを送れなければ、タスクを作ったチャンネルで知らせる
+func TaskReminder(s *discordgo.Session) task.RemindFunc {
+	return func(t *task.Task) error {
+		body := fmt.Sprintf("タスク `#%d` の期限が近づいています（%s）\n%s", t.ID, t.Due.Format("2006/01/02 15:04"), t.Text)
+		if t.Source.URL != "" {
+			body += "\n元のメッセージ: " + t.Source.URL
+		}
+		body += fmt.Sprintf("\n終わったら `/task done id:%d` で完了にしてください。", t.ID)
+
+		if t.AssigneeID != "" {
+			dm, err := s.UserChannelCreate(t.AssigneeID)
+			if err == nil {
+				guild := t.GuildID
+				if g, err := s.State.Guild(t.GuildID); err == nil {
+					guild = g.Name
+				}
+				_, err = s.ChannelMessageSend(dm.ID, fmt.Sprintf("【%s】", guild)+body)
+			}
+			if err == nil {
+				return nil
+			}
+			log.Printf("タスクの担当者にDMを送れませんでした (#%d): %v\n", t.ID, err)
+		}
+		_, err := s.ChannelMessageSend(t.ChannelID, t.Assignee()+" "+body)
+		return err
+	}
+}
+
+// 未完了のタスクを担当者ごとにまとめて投稿する
+// 報告先は誰でも読めるとは限らないため、非公開のチャンネルから作ったタスクは件数だけを載せる
+func TaskReport(s *discordgo.Session) task.ReportFunc {
+	return func(guildID, channelID string, open []*task.Task) error {
+		resp := &render.Response{
+			Title:    "未完了のタスク",
+			FileName: "tasks",
+		}
+		hidden := 0
+		var listed []*task.Task
+		for _, t := range open {
+			if t.Source.ChannelID == channelID || publicChannel(s, t.Source.ChannelID) {
+				listed = append(listed, t)
+			} else {
+				hidden++
+			}
+		}
+		open = listed
+
+		if len(open) == 0 && hidden == 0 {
+			resp.Description = "未完了のタスクはありません"
+			return render.Channel(s, channelID, resp)
+		}
+		resp.Description = fmt.Sprintf("%d 件のタスクが完了していません。終わったものは `/task done` で完了にしてください。", len(open)+hidden)
+		if hidden > 0 {
+			resp.Description += fmt.Sprintf("\nうち %d 件は非公開のチャンネルのタスクのため、`/task list` で確認してください。", hidden)
+		}
+
+		var order []string
+		groups := make(map[string][]string)
+		for _, t := range open {
+			assignee := t.Assignee()
+			if _, ok := groups[assignee]; !ok {
+				order = append(order, assignee)
+			}
+			groups[assignee] = append(groups[assignee], formatTask(t))
+		}
+		for _, assignee := range order {
+			// 埋め込みのタイトルではメンションが表示されないため本文に書く
+			resp.Sections = append(resp.Sections, render.Section{Text: "**担当: " + assignee + "**", Items: groups[assignee]})
+		}
+		return render.Channel(s, channelID, resp)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"main/exporter"
+	"main/summary"
+	"main/task"
+)
+
+func TestTrackActionItems(t *testing.T) {
+	store, err := task.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-04-02 は水曜日
+	base := time.Date(2025, 4, 2, 10, 0, 0, 0, time.Local)
+	records := []*exporter.Record{
+		{ID: "101", ChannelID: "c", AuthorID: "u1", Author: "佐藤", Timestamp: base, Content: "来週の会場の予約がまだです", URL: "https://discord.com/channels/g/c/101"},
+		{ID: "102", ChannelID: "c", AuthorID: "u2", Author: "鈴木", Timestamp: base.Add(time.Hour), Content: "議事録は金曜までに共有します", URL: "https://discord.com/channels/g/c/102"},
+		{ID: "103", ChannelID: "c", AuthorID: "u1", Author: "佐藤", Timestamp: base.Add(2 * time.Hour), Content: "よろしくお願いします", URL: "https://discord.com/channels/g/c/103"},
+	}
+	_, cites := numberLines(records)
+	scope := &conversationScope{GuildID: "g", ChannelID: "c", ViewerID: "viewer"}
+
+	items := []summary.ActionItem{
+		{Task: "議事録を共有する [#2]", Assignee: "鈴木さん", Due: "金曜"},
+		{Task: "会場を予約する", Assignee: "佐藤"},
+		{Task: "[#3]"},
+		{Task: "資料を送る [#9]", Assignee: "<@u3>"},
+		{Task: "議事録を共有する", Assignee: "鈴木さん"},
+	}
+	tracked := trackActionItems(store, scope, "summary", items, records, cites)
+	if len(tracked) != len(items) {
+		t.Fatalf("got %d results, want %d", len(tracked), len(items))
+	}
+
+	for _, tc := range []struct {
+		name       string
+		n          int
+		text       string
+		assigneeID string
+		sourceID   string
+		due        time.Time
+	}{
+		{"引用した発言を元にする", 0, "議事録を共有する", "u2", "102", time.Date(2025, 4, 4, 23, 59, 0, 0, time.Local)},
+		{"内容の近い発言を元にする", 1, "会場を予約する", "u1", "101", time.Time{}},
+		{"存在しない番号の引用", 3, "資料を送る", "u3", "103", time.Time{}},
+	} {
+		got := tracked[tc.n]
+		if got == nil {
+			t.Errorf("%s: no task", tc.name)
+			continue
+		}
+		if got.Text != tc.text || got.AssigneeID != tc.assigneeID || got.Source.MessageID != tc.sourceID || !got.Due.Equal(tc.due) {
+			t.Errorf("%s: got text=%q assignee=%q source=%q due=%v, want %q %q %q %v",
+				tc.name, got.Text, got.AssigneeID, got.Source.MessageID, got.Due, tc.text, tc.assigneeID, tc.sourceID, tc.due)
+		}
+		if got.GuildID != "g" || got.CreatedBy != "viewer" || got.Source.Command != "summary" || got.Source.ChannelID != "c" {
+			t.Errorf("%s: got %+v, want the scope and command recorded", tc.name, got)
+		}
+	}
+	if tracked[2] != nil {
+		t.Errorf("引用だけの項目: got %+v, want no task", tracked[2])
+	}
+	if tracked[4] == nil || tracked[4].ID != tracked[0].ID {
+		t.Errorf("同じ内容の項目: got %+v, want the existing task #%d", tracked[4], tracked[0].ID)
+	}
+	if !cites.invalid[9] {
+		t.Errorf("the missing citation [#9] was not recorded")
+	}
+	if got := store.List("g", "", false); len(got) != 3 {
+		t.Errorf("store has %d tasks, want 3", len(got))
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
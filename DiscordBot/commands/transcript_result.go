@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"main/transcript"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 書き起こし結果を送信する
+// 本文には概要だけを載せ、全文は各形式のファイルとして添付する
+func sendTranscript(s *discordgo.Session, channelID string, t *transcript.Transcript) {
+	var content strings.Builder
+	fmt.Fprintf(&content, "書き起こし結果（発言 %d 件 / 参加者: %s）\n",
+		len(t.Segments), strings.Join(t.Speakers(), "、"))
+
+	// 短ければ本文にも載せる
+	var preview strings.Builder
+	for _, seg := range t.Segments {
+		fmt.Fprintf(&preview, "%s: %s\n", seg.Speaker, seg.Text)
+	}
+	inline := "```\n" + preview.String() + "```\n"
+
+	var files []*discordgo.File
+	var skipped []string
+	total := 0
+	for _, f := range transcript.Formats {
+		data, err := f.Render(t)
+		if err != nil {
+			fmt.Printf("failed to render transcript as %s: %v\n", f.Ext, err)
+			continue
+		}
+		// 添付できないファイルは保存済みの書き起こしから取得してもらう
+		if total+len(data) > discordFileSizeLimit {
+			skipped = append(skipped, f.Ext)
+			continue
+		}
+		total += len(data)
+		files = append(files, &discordgo.File{
+			Name:        fmt.Sprintf("transcript_%s.%s", t.StartedAt.Format("20060102_1504"), f.Ext),
+			ContentType: f.ContentType,
+			Reader:      bytes.NewReader(data),
+		})
+	}
+
+	if t.RedactionKey != "" {
+		content.WriteString("個人情報を伏せ字にしました。復元キー: `" + t.RedactionKey + "`\n")
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&content, "サイズの上限を超えたため添付していない形式: %s（書き起こしID: `%s`）\n",
+			strings.Join(skipped, "、"), t.ID)
+	}
+	if utf8.RuneCountInString(content.String())+utf8.RuneCountInString(inline) <= discordMessageLimit {
+		content.WriteString(inline)
+	} else if len(files) > 0 {
+		content.WriteString("全文は添付ファイルを参照してください。\n")
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: truncateRunes(content.String(), discordMessageLimit),
+		Files:   files,
+	})
+	if err != nil {
+		fmt.Printf("failed to send transcript: %v\n", err)
+	}
+}
+
+// 文字数の上限に収まるよう切り詰める
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
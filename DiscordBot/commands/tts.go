@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"fmt"
+
+	"main/botHandler/botRouter"
+	"main/tts"
+	"main/voice"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TTSCommand(r *tts.Reader) *botRouter.Command {
+	/*
+		ttsコマンドの定義
+
+		コマンド名: tts
+		説明: テキストチャンネルのメッセージをボイスチャンネルで読み上げます
+		サブコマンド: on, off, voice, dict_add, dict_remove
+	*/
+	minSpeed := 0.5
+	return &botRouter.Command{
+		Name:        "tts",
+		Description: "テキストチャンネルのメッセージをボイスチャンネルで読み上げます",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "on",
+				Description: "このチャンネルの読み上げを開始します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "off",
+				Description: "読み上げを終了します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "voice",
+				Description: "自分のメッセージを読み上げる声を設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "speaker",
+						Description: "話者（VOICEVOXの話者IDなど）",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "speed",
+						Description: "話速（標準は1.0）",
+						MinValue:    &minSpeed,
+						MaxValue:    2.0,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "dict_add",
+				Description: "名前や単語の読み方を登録します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "word",
+						Description: "表記",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reading",
+						Description: "読み方",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "dict_remove",
+				Description: "登録した読み方を削除します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "word",
+						Description: "表記",
+						Required:    true,
+					},
+				},
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleTTS(s, i, r)
+		},
+	}
+}
+
+func handleTTS(s *discordgo.Session, i *discordgo.InteractionCreate, r *tts.Reader) error {
+	/*
+		ttsコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "tts" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "on":
+		if voice.Connection(s, i.GuildID) == nil {
+			vs, err := s.State.VoiceState(i.GuildID, i.Interaction.Member.User.ID)
+			if err != nil || vs == nil {
+				return botRouter.UserError("ボイスチャンネルに入ってください")
+			}
+			if _, err := voice.Join(s, i.GuildID, vs.ChannelID); err != nil {
+				return botRouter.InternalError("ボイスチャンネルに接続できませんでした", err)
+			}
+		}
+		r.Enable(i.GuildID, i.ChannelID)
+		return responseText(s, i, "<#"+i.ChannelID+"> の読み上げを開始します")
+
+	case "off":
+		if !r.Disable(i.GuildID) {
+			return botRouter.UserError("読み上げは開始されていません")
+		}
+		return responseText(s, i, "読み上げを終了しました")
+
+	case "voice":
+		v := tts.Voice{Speaker: args["speaker"].StringValue(), Speed: 1.0}
+		if speed, ok := args["speed"]; ok {
+			v.Speed = speed.FloatValue()
+		}
+		if err := r.Settings().SetVoice(i.Interaction.Member.User.ID, v); err != nil {
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, fmt.Sprintf("声を設定しました（話者: %s, 話速: %.2f）", v.Speaker, v.Speed))
+
+	case "dict_add":
+		word, reading := args["word"].StringValue(), args["reading"].StringValue()
+		if err := r.Settings().AddWord(i.GuildID, word, reading); err != nil {
+			return botRouter.InternalError("辞書の保存に失敗しました", err)
+		}
+		return responseText(s, i, fmt.Sprintf("「%s」の読み方を「%s」に設定しました", word, reading))
+
+	case "dict_remove":
+		word := args["word"].StringValue()
+		removed, err := r.Settings().RemoveWord(i.GuildID, word)
+		if err != nil {
+			return botRouter.InternalError("辞書の保存に失敗しました", err)
+		}
+		if !removed {
+			return botRouter.UserError(fmt.Sprintf("「%s」は登録されていません", word))
+		}
+		return responseText(s, i, fmt.Sprintf("「%s」の読み方を削除しました", word))
+	}
+	return nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"main/botHandler/botRouter"
+	"main/summary"
+	"main/usage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func UsageCommand(ledger *usage.Ledger) *botRouter.Command {
+	/*
+		usageコマンドの定義
+
+		コマンド名: usage
+		説明: 要約と書き起こしの利用量を表示します
+		サブコマンド: show, quota
+	*/
+	minZero := 0.0
+	return &botRouter.Command{
+		Name:        "usage",
+		Description: "要約と書き起こしの利用量を表示します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "show",
+				Description: "月ごとの利用量と推定料金を表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "month",
+						Description: "表示する月（例: 2025-04、省略時は今月）",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "quota",
+				Description: "月間の上限を設定します（0で上限なし、サーバー管理者のみ）",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "tokens",
+						Description: "要約のトークン数",
+						MinValue:    &minZero,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "audio_minutes",
+						Description: "書き起こす音声の長さ（分）",
+						MinValue:    &minZero,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "cost",
+						Description: "推定料金（米ドル、要約と書き起こしの合計）",
+						MinValue:    &minZero,
+					},
+				},
+			},
+		},
+		Executor: func(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+			return handleUsage(s, i, ledger)
+		},
+	}
+}
+
+func handleUsage(s *discordgo.Session, i *discordgo.InteractionCreate, ledger *usage.Ledger) error {
+	/*
+		usageコマンドの実行
+
+		サブコマンドごとに処理を振り分ける
+	*/
+	if i.Interaction.ApplicationCommandData().Name != "usage" {
+		return nil
+	}
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil
+	}
+	sub := options[0]
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range sub.Options {
+		args[opt.Name] = opt
+	}
+
+	switch sub.Name {
+	case "show":
+		month := usage.MonthKey(time.Now())
+		if opt, ok := args["month"]; ok {
+			t, err := time.ParseInLocation("2006-01", strings.TrimSpace(opt.StringValue()), time.Local)
+			if err != nil {
+				return botRouter.UserError("月は 2025-04 の形で指定してください")
+			}
+			month = usage.MonthKey(t)
+		}
+		report := formatUsage(month, ledger.Month(i.GuildID, month), ledger.Quota(i.GuildID))
+		return responseText(s, i, truncateRunes(report, discordMessageLimit))
+
+	case "quota":
+		if !canManageServer(i) {
+			return botRouter.UserError("上限を設定できるのはサーバー管理者だけです")
+		}
+		q := ledger.Quota(i.GuildID)
+		if opt, ok := args["tokens"]; ok {
+			q.Tokens = int(opt.IntValue())
+		}
+		if opt, ok := args["audio_minutes"]; ok {
+			q.AudioMinutes = opt.FloatValue()
+		}
+		if opt, ok := args["cost"]; ok {
+			q.Cost = opt.FloatValue()
+		}
+		if err := ledger.SetQuota(i.GuildID, q); err != nil {
+			return botRouter.InternalError("設定の保存に失敗しました", err)
+		}
+		return responseText(s, i, "月間の上限: "+formatQuota(q))
+	}
+	return nil
+}
+
+// 月の利用量の報告
+func formatUsage(month string, m *usage.Month, q usage.Quota) string {
+	total := m.Total()
+	lines := []string{fmt.Sprintf("**%s の利用量**", month)}
+	if total.Calls == 0 && m.CacheHits == 0 {
+		lines = append(lines, "利用はありません")
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("トークン数: %d（入力 %d / 出力 %d）", total.Tokens(), total.InputTokens, total.OutputTokens),
+			fmt.Sprintf("書き起こした音声: %.1f 分", total.AudioMinutes()),
+			fmt.Sprintf("推定料金: $%.4f", total.Cost),
+			fmt.Sprintf("要約の再利用: %d 回", m.CacheHits),
+		)
+		for _, name := range m.ModelNames() {
+			a := m.Models[name]
+			line := fmt.Sprintf("- `%s` %d 回", name, a.Calls)
+			if a.Tokens() > 0 {
+				line += fmt.Sprintf(" / %d トークン", a.Tokens())
+			}
+			if a.AudioSeconds > 0 {
+				line += fmt.Sprintf(" / %.1f 分", a.AudioMinutes())
+			}
+			line += fmt.Sprintf(" / $%.4f", a.Cost)
+			lines = append(lines, line)
+		}
+	}
+	if q.Limited() {
+		lines = append(lines, "月間の上限: "+formatQuota(q))
+		if q.Tokens > 0 {
+			lines = append(lines, fmt.Sprintf("- トークン数 %.0f%%", 100*float64(total.Tokens())/float64(q.Tokens)))
+		}
+		if q.AudioMinutes > 0 {
+			lines = append(lines, fmt.Sprintf("- 音声 %.0f%%", 100*total.AudioMinutes()/q.AudioMinutes))
+		}
+		if q.Cost > 0 {
+			lines = append(lines, fmt.Sprintf("- 推定料金 %.0f%%", 100*total.Cost/q.Cost))
+		}
+	}
+	lines = append(lines, "（APIがトークン数を返さないバックエンドは文字数から見積もるため、実際の請求とは異なることがあります）")
+	return strings.Join(lines, "\n")
+}
+
+// 上限の説明
+func formatQuota(q usage.Quota) string {
+	if !q.Limited() {
+		return "なし"
+	}
+	var parts []string
+	if q.Tokens > 0 {
+		parts = append(parts, fmt.Sprintf("トークン数 %d", q.Tokens))
+	}
+	if q.AudioMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("音声 %.1f 分", q.AudioMinutes))
+	}
+	if q.Cost > 0 {
+		parts = append(parts, fmt.Sprintf("推定料金 $%.2f", q.Cost))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// 今月の上限に達していれば、利用者への案内を返す（ledgerがnilなら確かめない）
+func checkQuota(ledger *usage.Ledger, guildID, kind string) error {
+	if ledger == nil {
+		return nil
+	}
+	var quotaErr *usage.QuotaError
+	if err := ledger.Check(guildID, kind); errors.As(err, &quotaErr) {
+		return botRouter.UserError(fmt.Sprintf("%s。%s から再び利用できます（上限はサーバー管理者が /usage quota で変更できます）。", quotaErr.Error(), quotaErr.ResetAt.Format("2006/01/02")))
+	}
+	return nil
+}
+
+// 要約の呼び出しごとにトークン数を記録する
+// APIが報告したトークン数があればそれを使い、無ければ文字数から見積もる
+type meteredSummarizer struct {
+	summary.Summarizer
+	ledger  *usage.Ledger
+	guildID string
+}
+
+func (m *meteredSummarizer) Summarize(ctx context.Context, req *summary.Request) (string, error) {
+	var text string
+	var used *summary.Usage
+	var err error
+	if us, ok := m.Summarizer.(summary.UsageSummarizer); ok {
+		text, used, err = us.SummarizeWithUsage(ctx, req)
+	} else {
+		text, err = m.Summarizer.Summarize(ctx, req)
+	}
+	if err != nil {
+		return "", err
+	}
+	input := summary.EstimateTokens(summary.Prompt(req)) + summary.EstimateTokens(req.Text)
+	output := summary.EstimateTokens(text)
+	if used != nil {
+		input, output = used.InputTokens, used.OutputTokens
+	}
+	if err := m.ledger.AddTokens(m.guildID, m.Model(), input, output); err != nil {
+		log.Printf("要約の利用量の記録に失敗しました: %v\n", err)
+	}
+	return text, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
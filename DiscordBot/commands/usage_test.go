@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/summary"
+	"main/usage"
+)
+
+// トークン数を報告するバックエンド
+type reportingSummarizer struct {
+	summary.Fake
+	usage *summary.Usage
+}
+
+func (r reportingSummarizer) Model() string {
+	return "gpt-4o-mini"
+}
+
+func (r reportingSummarizer) SummarizeWithUsage(ctx context.Context, req *summary.Request) (string, *summary.Usage, error) {
+	text, err := r.Summarize(ctx, req)
+	return text, r.usage, err
+}
+
+func TestMeteredSummarizerRecordsUsage(t *testing.T) {
+	req := &summary.Request{Text: "太郎: 明日の会議は10時からです"}
+	estimated := func(text string) (int, int) {
+		return summary.EstimateTokens(summary.Prompt(req)) + summary.EstimateTokens(req.Text), summary.EstimateTokens(text)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		summarizer summary.Summarizer
+		reported   *summary.Usage
+	}{
+		{"報告されたトークン数を使う", reportingSummarizer{usage: &summary.Usage{InputTokens: 1000, OutputTokens: 200}}, &summary.Usage{InputTokens: 1000, OutputTokens: 200}},
+		{"報告が無ければ見積もる", reportingSummarizer{}, nil},
+		{"報告できないバックエンドは見積もる", summary.Fake{}, nil},
+	} {
+		ledger, err := usage.NewLedger(t.TempDir(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &meteredSummarizer{Summarizer: tc.summarizer, ledger: ledger, guildID: "guild"}
+		text, err := m.Summarize(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input, output := estimated(text)
+		if tc.reported != nil {
+			input, output = tc.reported.InputTokens, tc.reported.OutputTokens
+		}
+		got := ledger.Month("guild", usage.MonthKey(time.Now())).Models[tc.summarizer.Model()]
+		if got == nil || got.Calls != 1 || got.InputTokens != input || got.OutputTokens != output {
+			t.Errorf("%s: recorded %+v, want %d input and %d output tokens", tc.name, got, input, output)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
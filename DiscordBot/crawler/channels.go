@@ -0,0 +1,158 @@
+package crawler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	KindChannel   = "channel"
+	KindThread    = "thread"
+	KindForumPost = "forum_post"
+)
+
+// 取得対象のチャンネル・スレッド・フォーラム投稿
+type Channel struct {
+	ID         string
+	Name       string
+	ParentID   string
+	ParentName string
+	Kind       string
+}
+
+// ギルド内でBotが読めるチャンネル・スレッド・フォーラム投稿を列挙する
+// スレッドはアクティブなものとアーカイブ済みのものの両方を含む
+func ListChannels(s *discordgo.Session, guildID string) ([]*Channel, error) {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string]*discordgo.Channel)
+	var list []*Channel
+	for _, ch := range channels {
+		if !CanRead(s, ch.ID) {
+			continue
+		}
+		switch ch.Type {
+		case discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews:
+			parents[ch.ID] = ch
+			list = append(list, &Channel{ID: ch.ID, Name: ch.Name, Kind: KindChannel})
+		case discordgo.ChannelTypeGuildForum:
+			// フォーラム自体にはメッセージがなく、投稿（スレッド）だけを取得する
+			parents[ch.ID] = ch
+		}
+	}
+
+	seen := make(map[string]bool)
+	addThread := func(th *discordgo.Channel) {
+		parent, ok := parents[th.ParentID]
+		if !ok || seen[th.ID] {
+			return
+		}
+		seen[th.ID] = true
+		kind := KindThread
+		if parent.Type == discordgo.ChannelTypeGuildForum {
+			kind = KindForumPost
+		}
+		list = append(list, &Channel{ID: th.ID, Name: th.Name, ParentID: parent.ID, ParentName: parent.Name, Kind: kind})
+	}
+
+	active, err := s.GuildThreadsActive(guildID)
+	if err == nil {
+		for _, th := range active.Threads {
+			addThread(th)
+		}
+	}
+	for _, parent := range parents {
+		for _, th := range archivedThreads(s, parent.ID) {
+			addThread(th)
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].ParentName != list[j].ParentName {
+			return list[i].ParentName < list[j].ParentName
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// 指定したチャンネルのスレッド（フォーラムなら投稿）をアーカイブ済みも含めて作成順に返す
+func ChannelThreads(s *discordgo.Session, guildID, channelID string) ([]*discordgo.Channel, error) {
+	active, err := s.GuildThreadsActive(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var threads []*discordgo.Channel
+	add := func(th *discordgo.Channel) {
+		if th.ParentID != channelID || seen[th.ID] {
+			return
+		}
+		seen[th.ID] = true
+		threads = append(threads, th)
+	}
+	for _, th := range active.Threads {
+		add(th)
+	}
+	for _, th := range archivedThreads(s, channelID) {
+		add(th)
+	}
+
+	// IDは作成日時の順に増える
+	sort.Slice(threads, func(i, j int) bool {
+		if len(threads[i].ID) != len(threads[j].ID) {
+			return len(threads[i].ID) < len(threads[j].ID)
+		}
+		return threads[i].ID < threads[j].ID
+	})
+	return threads, nil
+}
+
+// 公開・非公開のアーカイブ済みスレッドを返す
+// 非公開スレッドの一覧は権限がないと取得できないため、取得できた分だけを返す
+func archivedThreads(s *discordgo.Session, channelID string) []*discordgo.Channel {
+	var threads []*discordgo.Channel
+	for _, list := range []func(string, *time.Time, int, ...discordgo.RequestOption) (*discordgo.ThreadsList, error){
+		s.ThreadsArchived,
+		s.ThreadsPrivateArchived,
+	} {
+		var before *time.Time
+		for {
+			res, err := list(channelID, before, PageSize)
+			if err != nil {
+				break
+			}
+			threads = append(threads, res.Threads...)
+			if !res.HasMore || len(res.Threads) == 0 {
+				break
+			}
+			last := res.Threads[len(res.Threads)-1]
+			if last.ThreadMetadata == nil {
+				break
+			}
+			ts := last.ThreadMetadata.ArchiveTimestamp
+			before = &ts
+		}
+	}
+	return threads
+}
+
+// Botがチャンネルの履歴を読めるか
+func CanRead(s *discordgo.Session, channelID string) bool {
+	perms, err := s.State.UserChannelPermissions(s.State.User.ID, channelID)
+	if err != nil {
+		return true // 判定できない場合は取得を試みる
+	}
+	need := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	return perms&need == need
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"main/storage"
+)
+
+// 中断した取得を再開するための位置
+type Checkpoint struct {
+	ChannelID string    `json:"channel_id"`
+	LastID    string    `json:"last_id"` // 処理済みの最新メッセージ
+	Count     int       `json:"count"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 取得の途中経過をファイルに保存する
+// 大きなチャンネルの取得が中断しても、次回は処理済みのページの続きから取得する
+type Checkpoints struct {
+	file *storage.JSONFile
+
+	mu    sync.Mutex
+	items map[string]*Checkpoint
+}
+
+// 保存済みのチェックポイントを読み込んで返す
+func NewCheckpoints(dir string) (*Checkpoints, error) {
+	c := &Checkpoints{
+		file:  storage.NewJSONFile(dir, "checkpoints.json"),
+		items: make(map[string]*Checkpoint),
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := c.file.Load(&c.items); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// 途中経過を返す（無ければnil）
+func (c *Checkpoints) Get(key string) *Checkpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cp, ok := c.items[key]; ok {
+		copied := *cp
+		return &copied
+	}
+	return nil
+}
+
+// チャンネルを古い順に取得する
+// keyのチェックポイントがあればその続きから始め、ページを処理するたびに位置を保存する。
+// 最後まで取得できたらチェックポイントを消す。
+func (c *Checkpoints) Crawl(ctx context.Context, s *discordgo.Session, key, channelID string, handle PageFunc) error {
+	afterID := "0"
+	if cp := c.Get(key); cp != nil {
+		afterID = cp.LastID
+	}
+
+	err := Forward(ctx, s, channelID, afterID, func(page []*discordgo.Message) error {
+		if err := handle(page); err != nil {
+			return err
+		}
+		return c.advance(key, channelID, page[len(page)-1].ID, len(page))
+	})
+	if err != nil {
+		return err
+	}
+	return c.remove(key)
+}
+
+// 保存の順序が入れ替わらないよう、更新から書き込みまでをロックしたまま行う
+func (c *Checkpoints) advance(key, channelID, lastID string, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.items[key]
+	if !ok {
+		cp = &Checkpoint{ChannelID: channelID}
+		c.items[key] = cp
+	}
+	cp.LastID = lastID
+	cp.Count += n
+	cp.UpdatedAt = time.Now()
+	return c.file.Save(c.items)
+}
+
+func (c *Checkpoints) remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; !ok {
+		return nil
+	}
+	delete(c.items, key)
+	return c.file.Save(c.items)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
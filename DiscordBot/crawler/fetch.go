@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// REST APIで一度に取得できる件数
+const PageSize = 100
+
+const (
+	maxAttempts   = 6
+	serverBackoff = time.Second
+)
+
+// 1ページ分のメッセージを取得する
+//
+// 429が返った場合はRetry-Afterの間待ってから再試行する。
+// discordgoの自動再試行は中断できないため無効にし、待機中もctxのキャンセルを受け付ける。
+// バケットごとの残り回数はdiscordgoのレートリミッターが管理し、上限に達したバケットへの要求はリセットまで待たされる。
+func fetchPage(ctx context.Context, s *discordgo.Session, channelID, beforeID, afterID string) ([]*discordgo.Message, error) {
+	backoff := serverBackoff
+	var err error
+	for n := 0; n < maxAttempts; n++ {
+		var page []*discordgo.Message
+		page, err = s.ChannelMessages(channelID, PageSize, beforeID, afterID, "",
+			discordgo.WithContext(ctx), discordgo.WithRetryOnRatelimit(false))
+		if err == nil {
+			return page, nil
+		}
+
+		wait, ok := retryWait(err)
+		if !ok {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil, fmt.Errorf("メッセージの取得を %d 回試行しましたが失敗しました: %w", maxAttempts, err)
+}
+
+// 再試行できるエラーなら待ち時間を返す（0なら指数的に待つ）
+func retryWait(err error) (time.Duration, bool) {
+	var rateLimit *discordgo.RateLimitError
+	if errors.As(err, &rateLimit) && rateLimit.TooManyRequests != nil {
+		return rateLimit.RetryAfter, true
+	}
+	var rest *discordgo.RESTError
+	if errors.As(err, &rest) && rest.Response != nil {
+		switch code := rest.Response.StatusCode; {
+		case code == http.StatusTooManyRequests:
+			if sec, err := strconv.ParseFloat(rest.Response.Header.Get("Retry-After"), 64); err == nil {
+				return time.Duration(sec * float64(time.Second)), true
+			}
+			return 0, true
+		case code >= http.StatusInternalServerError:
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
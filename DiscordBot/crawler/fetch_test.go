@@ -0,0 +1,289 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	"main/internal/discordtest"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ページの中も、ページの間も古い順に並んでいることを確かめる
+func checkAscending(t *testing.T, ids []string, want int) {
+	t.Helper()
+	if len(ids) != want {
+		t.Fatalf("取得した件数 = %d, want %d", len(ids), want)
+	}
+	for n, id := range ids {
+		if id != discordtest.MessageID(n+1) {
+			t.Fatalf("%d 件目のID = %s, want %s", n, id, discordtest.MessageID(n+1))
+		}
+	}
+}
+
+func TestForwardOrdersOldestFirst(t *testing.T) {
+	const total = 2*PageSize + 37
+	f := discordtest.NewServer()
+	f.AddChannel("c", total)
+	s := discordtest.NewSession(t, f)
+
+	var ids []string
+	var sizes []int
+	err := Forward(context.Background(), s, "c", "", func(page []*discordgo.Message) error {
+		sizes = append(sizes, len(page))
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAscending(t, ids, total)
+	if fmt.Sprint(sizes) != fmt.Sprint([]int{PageSize, PageSize, 37}) {
+		t.Errorf("ページの件数 = %v", sizes)
+	}
+
+	// 前のページの最後のIDから続きを取得し、空のページで終わる
+	calls := f.Requests()
+	wantAfter := []string{"0", discordtest.MessageID(PageSize), discordtest.MessageID(2 * PageSize), discordtest.MessageID(total)}
+	if len(calls) != len(wantAfter) {
+		t.Fatalf("リクエスト数 = %d, want %d", len(calls), len(wantAfter))
+	}
+	for n, c := range calls {
+		if c.After != wantAfter[n] || c.Before != "" {
+			t.Errorf("%d 回目のリクエスト after=%q before=%q, want after=%q", n, c.After, c.Before, wantAfter[n])
+		}
+	}
+}
+
+func TestForwardFromAfterID(t *testing.T) {
+	f := discordtest.NewServer()
+	f.AddChannel("c", 250)
+	s := discordtest.NewSession(t, f)
+
+	var ids []string
+	err := Forward(context.Background(), s, "c", discordtest.MessageID(200), func(page []*discordgo.Message) error {
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 50 || ids[0] != discordtest.MessageID(201) || ids[49] != discordtest.MessageID(250) {
+		t.Errorf("取得したID = %d 件 (%s .. %s)", len(ids), ids[0], ids[len(ids)-1])
+	}
+}
+
+func TestBackwardOrdersNewestFirst(t *testing.T) {
+	const total = 3*PageSize + 1
+	f := discordtest.NewServer()
+	f.AddChannel("c", total)
+	s := discordtest.NewSession(t, f)
+
+	var ids []string
+	err := Backward(context.Background(), s, "c", "", func(page []*discordgo.Message) error {
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != total {
+		t.Fatalf("取得した件数 = %d, want %d", len(ids), total)
+	}
+	if !sort.SliceIsSorted(ids, func(a, b int) bool { return ids[a] > ids[b] }) {
+		t.Error("新しい順に並んでいません")
+	}
+	if ids[0] != discordtest.MessageID(total) || ids[total-1] != discordtest.MessageID(1) {
+		t.Errorf("最初と最後のID = %s, %s", ids[0], ids[total-1])
+	}
+}
+
+func TestFetchPageRetriesRateLimit(t *testing.T) {
+	f := discordtest.NewServer()
+	f.AddChannel("c", 10)
+	f.RateLimit(2)
+	s := discordtest.NewSession(t, f)
+
+	page, err := fetchPage(context.Background(), s, "c", "", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 10 {
+		t.Errorf("取得した件数 = %d, want 10", len(page))
+	}
+	if n := len(f.Requests()); n != 3 {
+		t.Errorf("リクエスト数 = %d, want 3（429を2回再試行）", n)
+	}
+}
+
+func TestFetchPageStopsOnClientError(t *testing.T) {
+	f := discordtest.NewServer()
+	s := discordtest.NewSession(t, f)
+
+	if _, err := fetchPage(context.Background(), s, "missing", "", "0"); err == nil {
+		t.Error("存在しないチャンネルでエラーになりません")
+	}
+	if n := len(f.Requests()); n != 1 {
+		t.Errorf("リクエスト数 = %d, want 1（再試行しない）", n)
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	const total = 5*PageSize + 20
+	f := discordtest.NewServer()
+	f.AddChannel("c", total)
+	s := discordtest.NewSession(t, f)
+	dir := t.TempDir()
+
+	cps, err := NewCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3ページ目の処理で失敗させる
+	errStop := errors.New("stop")
+	var ids []string
+	pages := 0
+	err = cps.Crawl(context.Background(), s, "export:c", "c", func(page []*discordgo.Message) error {
+		pages++
+		if pages == 3 {
+			return errStop
+		}
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("err = %v, want errStop", err)
+	}
+
+	// 処理済みの2ページ分の位置が保存され、読み込み直しても残っている
+	cps, err = NewCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := cps.Get("export:c")
+	if cp == nil {
+		t.Fatal("チェックポイントが保存されていません")
+	}
+	if cp.LastID != discordtest.MessageID(2*PageSize) || cp.Count != 2*PageSize || cp.ChannelID != "c" {
+		t.Errorf("チェックポイント = %+v", cp)
+	}
+
+	// 再開すると続きから取得し、最後まで取得したらチェックポイントを消す
+	before := len(f.Requests())
+	err = cps.Crawl(context.Background(), s, "export:c", "c", func(page []*discordgo.Message) error {
+		for _, m := range page {
+			ids = append(ids, m.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := f.Requests()[before]; first.After != discordtest.MessageID(2*PageSize) {
+		t.Errorf("再開したリクエストのafter = %s, want %s", first.After, discordtest.MessageID(2*PageSize))
+	}
+	checkAscending(t, ids, total)
+	if cps.Get("export:c") != nil {
+		t.Error("完了したのにチェックポイントが残っています")
+	}
+	cps, err = NewCheckpoints(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cps.Get("export:c") != nil {
+		t.Error("完了したチェックポイントがファイルに残っています")
+	}
+}
+
+// 大きなチャンネルを最初から最後まで取得する
+func BenchmarkForward(b *testing.B) {
+	const total = 50000
+	f := discordtest.NewServer()
+	f.AddChannel("c", total)
+	s := discordtest.NewSession(b, f)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		count := 0
+		err := Forward(context.Background(), s, "c", "0", func(page []*discordgo.Message) error {
+			count += len(page)
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if count != total {
+			b.Fatalf("取得した件数 = %d, want %d", count, total)
+		}
+	}
+	b.ReportMetric(float64(total), "messages/op")
+}
+
+// チェックポイントを保存しながら取得する
+func BenchmarkCheckpointCrawl(b *testing.B) {
+	const total = 20000
+	f := discordtest.NewServer()
+	f.AddChannel("c", total)
+	s := discordtest.NewSession(b, f)
+	cps, err := NewCheckpoints(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err := cps.Crawl(context.Background(), s, "bench", "c", func(page []*discordgo.Message) error {
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(total), "messages/op")
+}
+
+// 複数のチャンネルを並行して取得する
+func BenchmarkRunForward(b *testing.B) {
+	const channels, perChannel = 8, 10000
+	f := discordtest.NewServer()
+	var ids []string
+	for n := 0; n < channels; n++ {
+		id := "c" + strconv.Itoa(n)
+		f.AddChannel(id, perChannel)
+		ids = append(ids, id)
+	}
+	s := discordtest.NewSession(b, f)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		errs := Run(context.Background(), DefaultWorkers, len(ids), func(ctx context.Context, i int) error {
+			return Forward(ctx, s, ids[i], "0", func(page []*discordgo.Message) error {
+				return nil
+			})
+		})
+		for _, err := range errs {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(channels*perChannel), "messages/op")
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
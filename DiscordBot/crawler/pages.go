@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 取得したページを受け取る
+// エラーを返すとその時点で取得を止める
+type PageFunc func(page []*discordgo.Message) error
+
+// afterIDより後のメッセージを古い順にページごとに渡す（"0"で最初から）
+// 件数が上限に満たないページがあっても、空のページが返るまで続ける
+func Forward(ctx context.Context, s *discordgo.Session, channelID, afterID string, handle PageFunc) error {
+	if afterID == "" {
+		afterID = "0"
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := fetchPage(ctx, s, channelID, "", afterID)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+
+		// afterを指定しても新しい順で返るため、古い順に並べ直す
+		for l, r := 0, len(page)-1; l < r; l, r = l+1, r-1 {
+			page[l], page[r] = page[r], page[l]
+		}
+		if err := handle(page); err != nil {
+			return err
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// beforeIDより前のメッセージを新しい順にページごとに渡す（空なら最新から）
+func Backward(ctx context.Context, s *discordgo.Session, channelID, beforeID string, handle PageFunc) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := fetchPage(ctx, s, channelID, beforeID, "")
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := handle(page); err != nil {
+			return err
+		}
+		beforeID = page[len(page)-1].ID
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// 同時に取得するチャンネル数の既定値
+const DefaultWorkers = 4
+
+// n件の処理を最大workers個ずつ並行して実行し、それぞれのエラーを返す
+// ctxがキャンセルされると、まだ始まっていない処理はctxのエラーになる
+func Run(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) []error {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	errs := make([]error, n)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(ctx, i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,159 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// よく使う予定の別名
+var scheduleAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 9 * * *",
+	"@weekly":  "0 9 * * 6",
+	"@monthly": "0 9 1 * *",
+}
+
+// cron形式（分 時 日 月 曜日）の予定
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // 該当する値のビット
+	domAny, dowAny                bool
+}
+
+// 各項目の範囲
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"分", 0, 59},
+	{"時", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"曜日", 0, 7}, // 0と7は日曜日
+}
+
+// cron形式の予定を読み込む
+// 「*」「,」による列挙、「-」による範囲、「/」による間隔と、@daily などの別名に対応する
+func ParseSchedule(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if alias, ok := scheduleAliases[spec]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("予定は「分 時 日 月 曜日」の5項目で指定してください（例: 0 9 * * 6）: %s", spec)
+	}
+
+	bits := make([]uint64, len(fields))
+	for n, f := range fields {
+		b, err := parseCronField(f, cronFields[n].min, cronFields[n].max)
+		if err != nil {
+			return nil, fmt.Errorf("%sの指定が正しくありません: %v", cronFields[n].name, err)
+		}
+		bits[n] = b
+	}
+	// 7は日曜日として扱う
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rng, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("間隔 %q", s)
+			}
+			part, step = rng, n
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			a, b, _ := strings.Cut(part, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("範囲 %q", part)
+			}
+			if hi, err = strconv.Atoi(b); err != nil {
+				return 0, fmt.Errorf("範囲 %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("値 %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%d〜%d の範囲で指定してください", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// tより後で最初に予定に合う時刻を返す（分単位）
+func (sc *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// 該当する日が無い指定（2月30日など）でも止まらないよう、5年先までで打ち切る
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if sc.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !sc.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if sc.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if sc.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// 日と曜日の両方が指定された場合は、cronと同じくどちらかに合えばよい
+func (sc *Schedule) dayMatches(t time.Time) bool {
+	dom := sc.dom&(1<<uint(t.Day())) != 0
+	dow := sc.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case sc.domAny && sc.dowAny:
+		return true
+	case sc.domAny:
+		return dow
+	case sc.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,71 @@
+package digest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// 2025-01-01 は水曜日
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"毎日", "0 9 * * *", at(1, 10, 0), at(2, 9, 0)},
+		{"同じ時刻の直前", "0 9 * * *", at(1, 8, 59), at(1, 9, 0)},
+		{"@daily", "@daily", at(1, 10, 0), at(2, 9, 0)},
+		{"@weekly は土曜日", "@weekly", at(1, 10, 0), at(4, 9, 0)},
+		{"@hourly", "@hourly", at(1, 10, 30), at(1, 11, 0)},
+		{"7は日曜日", "0 9 * * 7", at(1, 10, 0), at(5, 9, 0)},
+		{"0も日曜日", "0 9 * * 0", at(1, 10, 0), at(5, 9, 0)},
+		{"分の間隔", "*/15 * * * *", at(1, 10, 7), at(1, 10, 15)},
+		{"範囲の間隔", "0 9-17/4 * * *", at(1, 10, 0), at(1, 13, 0)},
+		{"値からの間隔", "30 10/6 * * *", at(1, 17, 0), at(1, 22, 30)},
+		{"列挙", "0 9,18 * * *", at(1, 10, 0), at(1, 18, 0)},
+		{"曜日の範囲", "0 9 * * 1-5", at(3, 10, 0), at(6, 9, 0)},
+		{"日だけ", "0 9 13 * *", at(1, 10, 0), at(13, 9, 0)},
+		{"日と曜日はどちらか（曜日が先）", "0 9 13 * 5", at(1, 10, 0), at(3, 9, 0)},
+		{"日と曜日はどちらか（日が先）", "0 9 13 * 5", at(10, 10, 0), at(13, 9, 0)},
+		{"月をまたぐ", "0 9 1 * *", at(15, 0, 0), time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"存在しない日", "0 0 30 2 *", at(1, 0, 0), time.Time{}},
+	} {
+		sc, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("%s: ParseSchedule(%q): %v", tc.name, tc.spec, err)
+			continue
+		}
+		if got := sc.Next(tc.from); !got.Equal(tc.want) {
+			t.Errorf("%s: Next(%v) = %v, want %v", tc.name, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 9 * *",
+		"0 9 * * * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 9 0 * *",
+		"0 9 * 13 *",
+		"0 9 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@yearly",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
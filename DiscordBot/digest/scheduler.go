@@ -0,0 +1,180 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"main/storage"
+)
+
+var ErrNotFound = errors.New("ダイジェストが見つかりません")
+
+// 予定を確認する間隔
+const checkInterval = 30 * time.Second
+
+// チャンネルのダイジェストの設定
+type Digest struct {
+	ID              string    `json:"id"`
+	GuildID         string    `json:"guild_id"`
+	ChannelID       string    `json:"channel_id"`        // 要約するチャンネル
+	TargetChannelID string    `json:"target_channel_id"` // 投稿先のチャンネル
+	Schedule        string    `json:"schedule"`          // cron形式
+	IncludeThreads  bool      `json:"include_threads"`
+	Style           string    `json:"style,omitempty"`    // 要約のスタイル（空なら標準）
+	Language        string    `json:"language,omitempty"` // 出力する言語（空なら日本語）
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+
+	LastMessageID string    `json:"last_message_id,omitempty"` // 前回のダイジェストに含めた最新のメッセージ
+	LastRunAt     time.Time `json:"last_run_at,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextRunAt     time.Time `json:"next_run_at"`
+}
+
+// ダイジェストを作成して投稿する
+// 含めた最新のメッセージのIDを返す（新しいメッセージが無ければ空）
+type RunFunc func(d *Digest) (string, error)
+
+// ダイジェストの設定を保存し、予定の時刻になったら実行する
+type Scheduler struct {
+	file *storage.JSONFile
+
+	mu      sync.Mutex
+	digests map[string]*Digest
+}
+
+// 保存済みの設定を読み込んでSchedulerを返す
+func NewScheduler(dir string) (*Scheduler, error) {
+	sc := &Scheduler{
+		file:    storage.NewJSONFile(dir, "digests.json"),
+		digests: make(map[string]*Digest),
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := sc.file.Load(&sc.digests); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// ダイジェストを追加する
+func (sc *Scheduler) Add(d *Digest) (*Digest, error) {
+	schedule, err := ParseSchedule(d.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	d.ID = uuid.New().String()[:8]
+	d.CreatedAt = time.Now()
+	d.NextRunAt = schedule.Next(d.CreatedAt)
+	if d.NextRunAt.IsZero() {
+		return nil, fmt.Errorf("予定に合う日時がありません: %s", d.Schedule)
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.digests[d.ID] = d
+	copied := *d
+	return &copied, sc.file.Save(sc.digests)
+}
+
+// ダイジェストを削除する
+func (sc *Scheduler) Remove(guildID, id string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	d, ok := sc.digests[id]
+	if !ok || d.GuildID != guildID {
+		return ErrNotFound
+	}
+	delete(sc.digests, id)
+	return sc.file.Save(sc.digests)
+}
+
+// ギルドのダイジェストを次の実行時刻の順に返す
+func (sc *Scheduler) List(guildID string) []*Digest {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var list []*Digest
+	for _, d := range sc.digests {
+		if d.GuildID == guildID {
+			copied := *d
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].NextRunAt.Before(list[j].NextRunAt) })
+	return list
+}
+
+// ctxが終わるまで予定を確認し、時刻を過ぎたダイジェストを実行する
+// 停止中に過ぎた予定は、起動後に1回だけ実行する
+func (sc *Scheduler) Start(ctx context.Context, run RunFunc) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		sc.runDue(run)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sc *Scheduler) runDue(run RunFunc) {
+	now := time.Now()
+	sc.mu.Lock()
+	var due []*Digest
+	for _, d := range sc.digests {
+		if !d.NextRunAt.IsZero() && !now.Before(d.NextRunAt) {
+			copied := *d
+			due = append(due, &copied)
+		}
+	}
+	sc.mu.Unlock()
+
+	for _, d := range due {
+		lastID, err := run(d)
+		if err != nil {
+			log.Printf("ダイジェストの作成に失敗しました (%s): %v\n", d.ID, err)
+		}
+		sc.finish(d.ID, lastID, err)
+	}
+}
+
+// 実行結果を記録し、次の実行時刻を決める
+func (sc *Scheduler) finish(id, lastID string, runErr error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	d, ok := sc.digests[id]
+	if !ok {
+		return // 実行中に削除された
+	}
+	d.LastRunAt = time.Now()
+	d.LastError = ""
+	if runErr != nil {
+		d.LastError = runErr.Error()
+	}
+	if lastID != "" {
+		d.LastMessageID = lastID
+	}
+	if schedule, err := ParseSchedule(d.Schedule); err == nil {
+		d.NextRunAt = schedule.Next(d.LastRunAt)
+	} else {
+		d.NextRunAt = time.Time{}
+	}
+	if err := sc.file.Save(sc.digests); err != nil {
+		log.Printf("ダイジェストの設定の保存に失敗しました: %v\n", err)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 出力前にRecordを加工する
+// falseを返したRecordは出力しない
+type Filter func(r *Record) bool
+
+var (
+	urlPattern         = regexp.MustCompile(`https?://[^\s]+`)
+	customEmojiPattern = regexp.MustCompile(`<a?:(\w+):\d+>`)
+)
+
+// 本文からURLを取り除く
+func StripURLs() Filter {
+	return func(r *Record) bool {
+		r.Content = strings.TrimSpace(urlPattern.ReplaceAllString(r.Content, ""))
+		return true
+	}
+}
+
+// カスタム絵文字を名前に置き換える
+func StripCustomEmoji() Filter {
+	return func(r *Record) bool {
+		r.Content = customEmojiPattern.ReplaceAllString(r.Content, ":$1:")
+		return true
+	}
+}
+
+// 本文も添付ファイルも埋め込みもないメッセージを除く
+func SkipEmpty() Filter {
+	return func(r *Record) bool {
+		return strings.TrimSpace(r.Content) != "" || len(r.Attachments) > 0 || len(r.Embeds) > 0
+	}
+}
+
+// Botのメッセージを除く
+func SkipBots() Filter {
+	return func(r *Record) bool {
+		return !r.Bot
+	}
+}
+
+// フィルターを順に適用する
+func Apply(records []*Record, filters ...Filter) []*Record {
+	var out []*Record
+	for _, r := range records {
+		keep := true
+		for _, f := range filters {
+			if !f(r) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,299 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 出力形式
+type Format string
+
+const (
+	FormatText     Format = "txt"
+	FormatCSV      Format = "csv"
+	FormatJSONL    Format = "jsonl"
+	FormatMarkdown Format = "md"
+	FormatHTML     Format = "html"
+)
+
+// 対応している出力形式
+var Formats = []Format{FormatText, FormatCSV, FormatJSONL, FormatMarkdown, FormatHTML}
+
+// 名前から出力形式を返す
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("未対応の出力形式です: %s", name)
+}
+
+// ファイルの拡張子
+func (f Format) Ext() string {
+	return string(f)
+}
+
+// Content-Type
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// チャンネルとスレッドの入れ子を指定した形式で書き出す
+// CSVとJSON Linesではスレッドの列を付けて1列に並べる
+func ExportSection(w io.Writer, f Format, title string, root *Section) error {
+	return Export(w, f, title, root.Flatten())
+}
+
+// Recordを指定した形式で書き出す
+func Export(w io.Writer, f Format, title string, records []*Record) error {
+	switch f {
+	case FormatText:
+		return writeText(w, records)
+	case FormatCSV:
+		return writeCSV(w, records)
+	case FormatJSONL:
+		return writeJSONL(w, records)
+	case FormatMarkdown:
+		return writeMarkdown(w, title, records)
+	case FormatHTML:
+		return writeHTML(w, title, records)
+	}
+	return fmt.Errorf("未対応の出力形式です: %s", f)
+}
+
+// テキスト形式の1行（スレッドの見出しなどメッセージでない行はIDが空）
+type TextLine struct {
+	ID   string
+	Text string
+}
+
+// テキスト形式で出力する行を返す
+// スレッドは見出しで囲んで字下げし、返信には返信先を付ける
+func TextLines(records []*Record) []TextLine {
+	var lines []TextLine
+	for _, blk := range groupByThread(records) {
+		indent := ""
+		if blk.ThreadID != "" {
+			lines = append(lines, TextLine{Text: fmt.Sprintf("[%s]", threadLabel(blk.Kind, blk.Thread))})
+			indent = "  "
+		}
+		for _, r := range blk.Records {
+			text := indent
+			if r.ReplyTo != "" {
+				text += fmt.Sprintf("(↳ %s への返信) ", replyTarget(r))
+			}
+			lines = append(lines, TextLine{ID: r.ID, Text: text + r.Author + ": " + r.Content})
+		}
+		if blk.ThreadID != "" {
+			lines = append(lines, TextLine{Text: fmt.Sprintf("[%sここまで]", threadLabel(blk.Kind, blk.Thread))})
+		}
+	}
+	return lines
+}
+
+func writeText(w io.Writer, records []*Record) error {
+	var b strings.Builder
+	for _, l := range TextLines(records) {
+		b.WriteString(l.Text + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCSV(w io.Writer, records []*Record) error {
+	// Excelで文字化けしないようBOMを付ける
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "timestamp", "author_id", "author", "bot", "edited", "reply_to", "content", "reactions", "attachments", "embeds", "url", "thread_id", "thread"})
+	for _, r := range records {
+		cw.Write([]string{
+			r.ID,
+			r.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			r.AuthorID,
+			r.Author,
+			strconv.FormatBool(r.Bot),
+			strconv.FormatBool(r.Edited),
+			r.ReplyTo,
+			r.Content,
+			joinReactions(r.Reactions),
+			joinAttachments(r.Attachments),
+			joinEmbeds(r.Embeds),
+			r.URL,
+			r.ThreadID,
+			r.Thread,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, records []*Record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, title string, records []*Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	day := ""
+	thread := ""
+	for _, r := range records {
+		if r.ThreadID != thread {
+			thread = r.ThreadID
+			if thread != "" {
+				fmt.Fprintf(&b, "### %s\n\n", threadLabel(r.ThreadKind, r.Thread))
+			} else {
+				// スレッドを抜けたら日付の見出しを付け直す
+				day = ""
+			}
+		}
+		if d := r.Timestamp.Local().Format("2006/01/02"); d != day && thread == "" {
+			day = d
+			fmt.Fprintf(&b, "## %s\n\n", day)
+		}
+		fmt.Fprintf(&b, "**%s** [%s](%s)", r.Author, r.Timestamp.Local().Format("15:04"), r.URL)
+		if r.Edited {
+			b.WriteString(" (編集済み)")
+		}
+		b.WriteString("\n")
+		if r.ReplyTo != "" {
+			fmt.Fprintf(&b, "> 返信先: %s\n", replyLabel(r))
+		}
+		if r.Content != "" {
+			b.WriteString(r.Content + "\n")
+		}
+		for _, a := range r.Attachments {
+			fmt.Fprintf(&b, "- 添付: [%s](%s)\n", a.Name, a.URL)
+		}
+		for _, e := range r.Embeds {
+			fmt.Fprintf(&b, "- 埋め込み: %s\n", embedLabel(e))
+		}
+		if len(r.Reactions) > 0 {
+			fmt.Fprintf(&b, "- リアクション: %s\n", joinReactions(r.Reactions))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"reply":     replyLabel,
+	"reactions": joinReactions,
+	"thread":    threadLabel,
+}).Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 860px; margin: 2em auto; color: #2e3338; }
+.message { border-bottom: 1px solid #e3e5e8; padding: .6em 0; }
+.author { font-weight: bold; }
+.time, .edited, .meta { color: #747f8d; font-size: .85em; }
+.reply { border-left: 3px solid #c4c9ce; padding-left: .5em; color: #4f5660; font-size: .9em; }
+.content { white-space: pre-wrap; margin: .3em 0; }
+.embed { border-left: 4px solid #5865f2; background: #f2f3f5; padding: .4em .6em; margin: .3em 0; }
+.thread { border-left: 3px solid #5865f2; margin: .6em 0 .6em 1em; padding-left: 1em; }
+.thread h2 { font-size: 1em; color: #4f5660; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Blocks}}{{if .ThreadID}}<section class="thread" id="thread-{{.ThreadID}}">
+<h2>{{thread .Kind .Thread}}</h2>
+{{end}}{{range .Records}}<div class="message" id="{{.ID}}">
+<span class="author">{{.Author}}</span>
+<a class="time" href="{{.URL}}">{{.Timestamp.Local.Format "2006/01/02 15:04"}}</a>{{if .Edited}} <span class="edited">(編集済み)</span>{{end}}
+{{if .ReplyTo}}<div class="reply">返信先: {{reply .}}</div>{{end}}
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .Attachments}}<div class="meta">添付: <a href="{{.URL}}">{{.Name}}</a></div>{{end}}
+{{range .Embeds}}<div class="embed">{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{if .Description}}<div>{{.Description}}</div>{{end}}</div>{{end}}
+{{if .Reactions}}<div class="meta">{{reactions .Reactions}}</div>{{end}}
+</div>
+{{end}}{{if .ThreadID}}</section>
+{{end}}{{end}}</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, title string, records []*Record) error {
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Title":  title,
+		"Blocks": groupByThread(records),
+	})
+}
+
+func replyTarget(r *Record) string {
+	if r.ReplyAuthor != "" {
+		return r.ReplyAuthor
+	}
+	return r.ReplyTo
+}
+
+func replyLabel(r *Record) string {
+	if r.ReplyAuthor != "" {
+		return r.ReplyAuthor + " のメッセージ (" + r.ReplyTo + ")"
+	}
+	return r.ReplyTo
+}
+
+func embedLabel(e Embed) string {
+	parts := []string{}
+	for _, p := range []string{e.Title, e.Description, e.URL} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+func joinReactions(reactions []Reaction) string {
+	parts := make([]string, len(reactions))
+	for i, r := range reactions {
+		parts[i] = fmt.Sprintf("%s×%d", r.Emoji, r.Count)
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinAttachments(attachments []Attachment) string {
+	parts := make([]string, len(attachments))
+	for i, a := range attachments {
+		parts[i] = a.URL
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinEmbeds(embeds []Embed) string {
+	parts := make([]string, len(embeds))
+	for i, e := range embeds {
+		parts[i] = embedLabel(e)
+	}
+	return strings.Join(parts, " | ")
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
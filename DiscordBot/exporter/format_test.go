@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "testdata の期待する出力を書き換える")
+
+// 出力を確かめるための会話
+// UTCでは日付をまたぐが、日本時間ではどちらも 2025/04/02 の発言
+func sampleRecords() []*Record {
+	at := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	edited := at("2025-04-02T00:40:00Z")
+	return []*Record{
+		{
+			ID: "1001", ChannelID: "c", AuthorID: "u1", Author: "太郎",
+			Timestamp: at("2025-04-01T23:30:00Z"),
+			Content:   "おはようございます <b>議題</b> は \"予算\" です",
+			Reactions: []Reaction{{Emoji: "👍", Count: 2}},
+			URL:       "https://discord.com/channels/g/c/1001",
+		},
+		{
+			ID: "1002", ChannelID: "c", AuthorID: "u2", Author: "花子",
+			Timestamp: at("2025-04-02T00:30:00Z"), Edited: true, EditedAt: &edited,
+			Content: "資料です, 確認してください", ReplyTo: "1001", ReplyAuthor: "太郎",
+			Attachments: []Attachment{{Name: "資料.pdf", URL: "https://cdn.example/資料.pdf"}},
+			URL:         "https://discord.com/channels/g/c/1002",
+		},
+		{
+			ID: "1003", ChannelID: "t", AuthorID: "bot", Author: "Bot", Bot: true,
+			Timestamp: at("2025-04-02T01:00:00Z"),
+			Embeds:    []Embed{{Title: "お知らせ", Description: "定例は10時から", URL: "https://example.com/n"}},
+			URL:       "https://discord.com/channels/g/t/1003",
+			ThreadID:  "t", Thread: "予算の相談", ThreadKind: SectionThread,
+		},
+		{
+			ID: "1004", ChannelID: "c", AuthorID: "u1", Author: "太郎",
+			Timestamp: at("2025-04-02T15:10:00Z"),
+			Content:   "明日もよろしく",
+			URL:       "https://discord.com/channels/g/c/1004",
+		},
+	}
+}
+
+func TestExportGolden(t *testing.T) {
+	// DockerfileのTZ（JST-9）と同じく日本時間で出力する
+	saved := time.Local
+	time.Local = time.FixedZone("JST", 9*60*60)
+	defer func() { time.Local = saved }()
+
+	for _, f := range Formats {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, f, "#general のメッセージ", sampleRecords()); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", "export."+f.Ext())
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v（go test -update で作成できます）", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s の出力が異なります\n--- got ---\n%s\n--- want ---\n%s", f, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(string(f)); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %q, %v", f, got, err)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("未対応の形式でエラーになりません")
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 出力するメッセージ
+type Record struct {
+	ID          string       `json:"id"`
+	ChannelID   string       `json:"channel_id"`
+	AuthorID    string       `json:"author_id"`
+	Author      string       `json:"author"`
+	Bot         bool         `json:"bot,omitempty"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Edited      bool         `json:"edited"`
+	EditedAt    *time.Time   `json:"edited_at,omitempty"`
+	Content     string       `json:"content"`
+	ReplyTo     string       `json:"reply_to,omitempty"`
+	ReplyAuthor string       `json:"reply_author,omitempty"`
+	Reactions   []Reaction   `json:"reactions,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Embeds      []Embed      `json:"embeds,omitempty"`
+	URL         string       `json:"url"`
+
+	// スレッド・フォーラム投稿内のメッセージの場合に設定される
+	ThreadID   string `json:"thread_id,omitempty"`
+	Thread     string `json:"thread,omitempty"`
+	ThreadKind string `json:"thread_kind,omitempty"`
+}
+
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+type Attachment struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type Embed struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// DiscordのメッセージからRecordを作る
+func FromMessage(guildID string, m *discordgo.Message) *Record {
+	r := &Record{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		Timestamp: m.Timestamp,
+		Edited:    m.EditedTimestamp != nil,
+		EditedAt:  m.EditedTimestamp,
+		Content:   m.ContentWithMentionsReplaced(),
+		URL:       "https://discord.com/channels/" + guildID + "/" + m.ChannelID + "/" + m.ID,
+	}
+	if m.Author != nil {
+		r.AuthorID = m.Author.ID
+		r.Author = m.Author.Username
+		r.Bot = m.Author.Bot
+	}
+	if m.Member != nil && m.Member.Nick != "" {
+		r.Author = m.Member.Nick
+	}
+
+	if m.MessageReference != nil {
+		r.ReplyTo = m.MessageReference.MessageID
+	}
+	if m.ReferencedMessage != nil && m.ReferencedMessage.Author != nil {
+		r.ReplyAuthor = m.ReferencedMessage.Author.Username
+	}
+
+	for _, reaction := range m.Reactions {
+		if reaction.Emoji == nil {
+			continue
+		}
+		name := reaction.Emoji.Name
+		if reaction.Emoji.ID != "" {
+			name = ":" + name + ":"
+		}
+		r.Reactions = append(r.Reactions, Reaction{Emoji: name, Count: reaction.Count})
+	}
+	for _, a := range m.Attachments {
+		r.Attachments = append(r.Attachments, Attachment{Name: a.Filename, URL: a.URL})
+	}
+	for _, e := range m.Embeds {
+		if e.Title == "" && e.Description == "" && e.URL == "" {
+			continue
+		}
+		r.Embeds = append(r.Embeds, Embed{Title: e.Title, Description: e.Description, URL: e.URL})
+	}
+	return r
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,117 @@
+package exporter
+
+const (
+	SectionChannel   = "channel"
+	SectionThread    = "thread"
+	SectionForumPost = "forum_post"
+)
+
+// チャンネルとその中のスレッド・フォーラム投稿を入れ子で表す
+type Section struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Kind      string     `json:"kind"`
+	StarterID string     `json:"starter_id,omitempty"` // スレッドを作成したメッセージ
+	Records   []*Record  `json:"records"`
+	Sections  []*Section `json:"sections,omitempty"`
+}
+
+// 含まれるメッセージの件数
+func (s *Section) Count() int {
+	n := len(s.Records)
+	for _, child := range s.Sections {
+		n += child.Count()
+	}
+	return n
+}
+
+// すべての区切りにフィルターを適用する
+// メッセージが残らなかったスレッドは取り除く
+func (s *Section) Apply(filters ...Filter) {
+	s.Records = Apply(s.Records, filters...)
+	children := s.Sections[:0]
+	for _, child := range s.Sections {
+		child.Apply(filters...)
+		if child.Count() > 0 {
+			children = append(children, child)
+		}
+	}
+	s.Sections = children
+}
+
+// 会話の流れに沿って1列に並べる
+// スレッドは作成元のメッセージの直後に置き、各メッセージにスレッドの情報を付ける
+func (s *Section) Flatten() []*Record {
+	byStarter := make(map[string][]*Section)
+	var rest []*Section
+	for _, child := range s.Sections {
+		if child.StarterID != "" {
+			byStarter[child.StarterID] = append(byStarter[child.StarterID], child)
+		} else {
+			rest = append(rest, child)
+		}
+	}
+
+	var out []*Record
+	for _, r := range s.Records {
+		out = append(out, r)
+		for _, child := range byStarter[r.ID] {
+			out = append(out, child.flattenAsThread()...)
+		}
+		delete(byStarter, r.ID)
+	}
+	// 作成元のメッセージが範囲外のスレッドは最後にまとめる
+	for _, child := range s.Sections {
+		if _, ok := byStarter[child.StarterID]; ok && child.StarterID != "" {
+			out = append(out, child.flattenAsThread()...)
+		}
+	}
+	for _, child := range rest {
+		out = append(out, child.flattenAsThread()...)
+	}
+	return out
+}
+
+func (s *Section) flattenAsThread() []*Record {
+	records := s.Flatten()
+	for _, r := range records {
+		if r.ThreadID == "" {
+			r.ThreadID = s.ID
+			r.Thread = s.Title
+			r.ThreadKind = s.Kind
+		}
+	}
+	return records
+}
+
+// 連続する同じスレッドのメッセージのまとまり（出力用）
+type block struct {
+	ThreadID string
+	Thread   string
+	Kind     string
+	Records  []*Record
+}
+
+func groupByThread(records []*Record) []*block {
+	var blocks []*block
+	for _, r := range records {
+		if n := len(blocks); n > 0 && blocks[n-1].ThreadID == r.ThreadID {
+			blocks[n-1].Records = append(blocks[n-1].Records, r)
+			continue
+		}
+		blocks = append(blocks, &block{ThreadID: r.ThreadID, Thread: r.Thread, Kind: r.ThreadKind, Records: []*Record{r}})
+	}
+	return blocks
+}
+
+// スレッドの見出し
+func threadLabel(kind, title string) string {
+	if kind == SectionForumPost {
+		return "フォーラム投稿「" + title + "」"
+	}
+	return "スレッド「" + title + "」"
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -2,14 +2,14 @@ module main
 
 go 1.20
 
-require github.com/bwmarrin/discordgo v0.27.1
-
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/pion/randutil v0.1.0 // indirect
-	github.com/sashabaranov/go-openai v1.40.1 // indirect
+	github.com/bwmarrin/discordgo v0.27.1
+	github.com/google/uuid v1.6.0
+	github.com/sashabaranov/go-openai v1.40.1
 )
 
+require github.com/pion/randutil v0.1.0 // indirect
+
 require (
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/joho/godotenv v1.5.1
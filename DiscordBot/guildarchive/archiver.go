@@ -0,0 +1,411 @@
+package guildarchive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"main/crawler"
+	"main/exporter"
+	"main/httpclient"
+	"main/redact"
+	"main/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var ErrRunning = errors.New("このサーバーのアーカイブはすでに実行中です")
+
+// 作成したzipを残しておく期間（送信に失敗したときの残りを消す）
+const archiveRetention = 24 * time.Hour
+
+// 再開されないまま放置された作業ディレクトリを残しておく期間
+const checkpointRetention = 7 * 24 * time.Hour
+
+// 分割したzip1つあたりの上限（ブーストなしのサーバーで1メッセージに添付できる大きさ）
+const defaultPartLimit = 10 * 1024 * 1024
+
+var errAttachmentTooLarge = errors.New("添付ファイルが大きすぎます")
+
+var unsafeNameChars = regexp.MustCompile(`[\\/:*?"<>|\s]+`)
+
+// アーカイブの進捗
+type Progress struct {
+	GuildID       string `json:"guild_id"`
+	ChannelsDone  int    `json:"channels_done"`
+	ChannelsTotal int    `json:"channels_total"`
+	Messages      int    `json:"messages"`
+	Current       string `json:"current"`
+	Resumed       bool   `json:"resumed"`
+}
+
+// アーカイブの結果
+type Result struct {
+	Paths         []string // 分割したzip（1つのファイルは partLimit 以下）
+	Size          int64
+	Channels      int
+	Messages      int
+	RedactionKeys []string // 伏せ字の復元キー（再開した場合は実行ごとに1つ）
+}
+
+// 作成したzipを削除する
+func (r *Result) Remove() {
+	for _, path := range r.Paths {
+		os.Remove(path)
+	}
+}
+
+// ギルド全体のメッセージと添付ファイルをzipにまとめる
+//
+// 取得したメッセージはチャンネルごとにJSON Linesとしてディスクへ追記し、
+// ページごとにチェックポイントを保存するため、中断しても続きから再開できる。
+// チャンネルは一定数ずつ並行して取得する。
+// 本文は伏せ字にしてから書き込み、zipは partLimit ごとに分割する。
+type Archiver struct {
+	session   *discordgo.Session
+	dir       string
+	redactor  *redact.Engine
+	client    *httpclient.Client
+	workers   int   // 同時に取得するチャンネル数
+	partLimit int64 // 分割したzip1つあたりの上限
+
+	mu      sync.Mutex
+	running map[string]*Progress
+}
+
+// Archiverを返す
+func NewArchiver(s *discordgo.Session, dir string, redactor *redact.Engine) *Archiver {
+	a := &Archiver{
+		session:   s,
+		dir:       dir,
+		redactor:  redactor,
+		client:    httpclient.New(httpclient.Config{Name: "attachments", Timeout: 5 * time.Minute}),
+		workers:   crawler.DefaultWorkers,
+		partLimit: defaultPartLimit,
+		running:   make(map[string]*Progress),
+	}
+	a.removeExpired(time.Now())
+	return a
+}
+
+// 保存期間を過ぎたzipと、再開されないまま放置された作業ディレクトリを削除する
+func (a *Archiver) removeExpired(now time.Time) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(a.dir, e.Name())
+		if !e.IsDir() {
+			if info, err := e.Info(); err == nil && now.Sub(info.ModTime()) > archiveRetention {
+				os.Remove(path)
+			}
+			continue
+		}
+		// 作業ディレクトリはページごとに更新されるチェックポイントの日時で判断する
+		if a.Status(e.Name()) != nil {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(path, "checkpoint.json"))
+		if err != nil || now.Sub(info.ModTime()) > checkpointRetention {
+			os.RemoveAll(path)
+		}
+	}
+}
+
+// 実行中のアーカイブの進捗を返す（実行中でなければnil）
+func (a *Archiver) Status(guildID string) *Progress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p, ok := a.running[guildID]; ok {
+		copied := *p
+		return &copied
+	}
+	return nil
+}
+
+// アーカイブを作成する
+// 前回中断したチェックポイントがあれば続きから再開する（restartなら最初から）
+func (a *Archiver) Run(ctx context.Context, guildID string, restart bool, progress func(Progress)) (*Result, error) {
+	a.mu.Lock()
+	if _, ok := a.running[guildID]; ok {
+		a.mu.Unlock()
+		return nil, ErrRunning
+	}
+	p := &Progress{GuildID: guildID}
+	a.running[guildID] = p
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.running, guildID)
+		a.mu.Unlock()
+	}()
+	a.removeExpired(time.Now())
+
+	report := func(update func(p *Progress)) {
+		a.mu.Lock()
+		update(p)
+		copied := *p
+		a.mu.Unlock()
+		if progress != nil {
+			progress(copied)
+		}
+	}
+
+	workDir := filepath.Join(a.dir, filepath.Base(guildID))
+	cpFile := storage.NewJSONFile(workDir, "checkpoint.json")
+	if restart {
+		os.RemoveAll(workDir)
+	}
+
+	cp, err := loadCheckpoint(cpFile)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		channels, err := listChannels(a.session, guildID)
+		if err != nil {
+			return nil, fmt.Errorf("チャンネル一覧の取得に失敗しました: %v", err)
+		}
+		cp = &checkpoint{GuildID: guildID, StartedAt: time.Now(), Channels: channels}
+		if g, err := a.session.State.Guild(guildID); err == nil {
+			cp.GuildName = g.Name
+		}
+		if err := cpFile.Save(cp); err != nil {
+			return nil, err
+		}
+	} else {
+		report(func(p *Progress) { p.Resumed = true })
+	}
+
+	total := 0
+	done := 0
+	for _, ch := range cp.Channels {
+		total += ch.Count
+		if ch.Done {
+			done++
+		}
+	}
+	report(func(p *Progress) {
+		p.ChannelsTotal = len(cp.Channels)
+		p.ChannelsDone = done
+		p.Messages = total
+	})
+
+	// 複数のチャンネルを並行して取得するため、チェックポイントの更新と保存はまとめてロックする
+	// 伏せ字の対応表はチェックポイントより先に保存し、中断しても書き込み済みの分を復元できるようにする
+	redaction := a.redactor.NewSession(guildID, "archive")
+	var cpMu sync.Mutex
+	commit := func(update func()) error {
+		cpMu.Lock()
+		defer cpMu.Unlock()
+		update()
+		key, err := redaction.Save()
+		if err != nil {
+			return err
+		}
+		if key != "" && !containsString(cp.RedactionKeys, key) {
+			cp.RedactionKeys = append(cp.RedactionKeys, key)
+		}
+		return cpFile.Save(cp)
+	}
+
+	var pending []*channelState
+	for _, ch := range cp.Channels {
+		if !ch.Done {
+			pending = append(pending, ch)
+		}
+	}
+	errs := crawler.Run(ctx, a.workers, len(pending), func(ctx context.Context, n int) error {
+		ch := pending[n]
+		report(func(p *Progress) { p.Current = channelLabel(ch) })
+
+		err := a.archiveChannel(ctx, guildID, workDir, ch, redaction, commit, func(n int) {
+			report(func(p *Progress) { p.Messages += n })
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := commit(func() {
+			if err != nil {
+				// 読めなかったチャンネルは記録して次へ進む
+				ch.Error = err.Error()
+			}
+			ch.Done = true
+		}); err != nil {
+			return err
+		}
+		report(func(p *Progress) { p.ChannelsDone++ })
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	base := filepath.Join(a.dir, fmt.Sprintf("%s_%s", filepath.Base(guildID), time.Now().Format("20060102_1504")))
+	paths, messages, err := writeZip(base, workDir, cp, a.partLimit)
+	if err != nil {
+		return nil, fmt.Errorf("zipの作成に失敗しました: %v", err)
+	}
+	result := &Result{Paths: paths, Channels: len(cp.Channels), Messages: messages, RedactionKeys: cp.RedactionKeys}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			result.Remove()
+			return nil, err
+		}
+		result.Size += info.Size()
+	}
+	os.RemoveAll(workDir)
+
+	return result, nil
+}
+
+// 1チャンネル分のメッセージを古い順に取得してファイルへ追記する
+// ページを書き込むたびにcommitでチェックポイントを更新し、addedで件数を知らせる
+func (a *Archiver) archiveChannel(ctx context.Context, guildID, workDir string, ch *channelState, redaction *redact.Session, commit func(update func()) error, added func(n int)) error {
+	path := filepath.Join(workDir, "messages", ch.ID+".jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// チェックポイント以降に書き込まれた中途半端なデータを捨てる
+	if err := f.Truncate(ch.Offset); err != nil {
+		return err
+	}
+	if _, err := f.Seek(ch.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	filter := redact.RecordFilter(redaction)
+	return crawler.Forward(ctx, a.session, ch.ID, ch.LastMessageID, func(page []*discordgo.Message) error {
+		for _, m := range page {
+			record := exporter.FromMessage(guildID, m)
+			filter(record)
+			for n, att := range record.Attachments {
+				if local, err := a.downloadAttachment(ctx, workDir, ch.ID, m.ID, att, m.Attachments[n].Size); err == nil {
+					record.Attachments[n].URL = local
+				}
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		err = commit(func() {
+			ch.Offset = offset
+			ch.LastMessageID = page[len(page)-1].ID
+			ch.Count += len(page)
+		})
+		if err != nil {
+			return err
+		}
+		added(len(page))
+		return nil
+	})
+}
+
+// 添付ファイルをダウンロードし、zip内での相対パスを返す
+// 分割したzipに収まらない大きさのファイルはダウンロードせず、元のURLを残す
+func (a *Archiver) downloadAttachment(ctx context.Context, workDir, channelID, messageID string, att exporter.Attachment, size int) (string, error) {
+	limit := maxEntrySize(a.partLimit)
+	if int64(size) > limit {
+		return "", errAttachmentTooLarge
+	}
+
+	rel := filepath.ToSlash(filepath.Join("attachments", channelID, messageID+"_"+safeName(att.Name)))
+	path := filepath.Join(workDir, filepath.FromSlash(rel))
+	if _, err := os.Stat(path); err == nil {
+		return rel, nil // 再開時はダウンロード済みのファイルを使う
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, att.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("添付ファイルの取得に失敗しました: %s", resp.Status)
+	}
+	if resp.ContentLength > limit {
+		return "", errAttachmentTooLarge
+	}
+
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, limit+1))
+	f.Close()
+	if err == nil && n > limit {
+		err = errAttachmentTooLarge
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return rel, os.Rename(tmp, path)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func channelLabel(ch *channelState) string {
+	if ch.ParentName != "" {
+		return "#" + ch.ParentName + " > " + ch.Name
+	}
+	return "#" + ch.Name
+}
+
+// ファイル名に使えない文字を置き換える
+func safeName(name string) string {
+	name = unsafeNameChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
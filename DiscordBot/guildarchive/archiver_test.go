@@ -0,0 +1,47 @@
+package guildarchive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoveExpired(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	a := &Archiver{dir: dir, running: map[string]*Progress{"running": {}}}
+
+	cases := []struct {
+		name string
+		path string // dirからの相対パス
+		age  time.Duration
+		keep bool
+	}{
+		{"新しいzip", "new.zip", time.Hour, true},
+		{"保存期間を過ぎたzip", "old_part1.zip", archiveRetention + time.Hour, false},
+		{"再開できる作業ディレクトリ", "recent/checkpoint.json", 24 * time.Hour, true},
+		{"放置された作業ディレクトリ", "stale/checkpoint.json", checkpointRetention + time.Hour, false},
+		{"実行中の作業ディレクトリ", "running/checkpoint.json", checkpointRetention + time.Hour, true},
+	}
+	for _, tc := range cases {
+		path := filepath.Join(dir, filepath.FromSlash(tc.path))
+		writeTestFile(t, path, "{}")
+		if err := os.Chtimes(path, now.Add(-tc.age), now.Add(-tc.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.removeExpired(now)
+
+	for _, tc := range cases {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(tc.path)))
+		if exists := err == nil; exists != tc.keep {
+			t.Errorf("%s: exists = %v, want %v", tc.name, exists, tc.keep)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,24 @@
+package guildarchive
+
+import (
+	"github.com/bwmarrin/discordgo"
+
+	"main/crawler"
+)
+
+// アーカイブ対象のチャンネル・スレッド・フォーラム投稿を列挙する
+func listChannels(s *discordgo.Session, guildID string) ([]*channelState, error) {
+	channels, err := crawler.ListChannels(s, guildID)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]*channelState, 0, len(channels))
+	for _, ch := range channels {
+		states = append(states, &channelState{ID: ch.ID, Name: ch.Name, ParentName: ch.ParentName, Kind: ch.Kind})
+	}
+	return states, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,44 @@
+package guildarchive
+
+import (
+	"time"
+
+	"main/storage"
+)
+
+// チャンネルごとの取得状況
+type channelState struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ParentName    string `json:"parent_name,omitempty"`
+	Kind          string `json:"kind"`
+	LastMessageID string `json:"last_message_id,omitempty"` // 書き込み済みの最新メッセージ
+	Offset        int64  `json:"offset"`                    // 書き込み済みのバイト数
+	Count         int    `json:"count"`
+	Done          bool   `json:"done"`
+	Error         string `json:"error,omitempty"`
+}
+
+// 中断したアーカイブを再開するためのチェックポイント
+type checkpoint struct {
+	GuildID       string          `json:"guild_id"`
+	GuildName     string          `json:"guild_name"`
+	StartedAt     time.Time       `json:"started_at"`
+	Channels      []*channelState `json:"channels"`
+	RedactionKeys []string        `json:"redaction_keys,omitempty"`
+}
+
+func loadCheckpoint(file *storage.JSONFile) (*checkpoint, error) {
+	var cp checkpoint
+	if err := file.Load(&cp); err != nil {
+		return nil, err
+	}
+	if cp.GuildID == "" {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,316 @@
+package guildarchive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 索引に載せるチャンネルの情報
+type indexEntry struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Parent   string   `json:"parent,omitempty"`
+	Kind     string   `json:"kind"`
+	Messages int      `json:"messages"`
+	Files    []string `json:"files"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// zipに入れる1ファイルの上限
+// 分割したzip1つに必ず収まるよう、上限の半分にする
+func maxEntrySize(partLimit int64) int64 {
+	return partLimit / 2
+}
+
+// 作業ディレクトリの内容を partLimit ごとに分割したzipにまとめ、作成したファイルのパスを返す
+// 分割しなければ base.zip、分割したら base_part1.zip, base_part2.zip, … になる
+// ファイルはディスクから順に書き込み、全体をメモリに載せない
+func writeZip(base, workDir string, cp *checkpoint, partLimit int64) ([]string, int, error) {
+	pw := &partWriter{base: base, limit: partLimit}
+	paths, messages, err := writeParts(pw, workDir, cp)
+	if err != nil {
+		pw.abort()
+		return nil, 0, err
+	}
+	return paths, messages, nil
+}
+
+func writeParts(pw *partWriter, workDir string, cp *checkpoint) ([]string, int, error) {
+	var index []indexEntry
+	messages := 0
+	chunk := maxEntrySize(pw.limit)
+
+	for _, ch := range cp.Channels {
+		name := safeName(ch.Name) + "_" + ch.ID
+		if ch.ParentName != "" {
+			name = "threads/" + safeName(ch.ParentName) + "/" + name
+		} else {
+			name = "channels/" + name
+		}
+		files, err := copyLines(pw, name, filepath.Join(workDir, "messages", ch.ID+".jsonl"), chunk)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, 0, err
+		}
+		index = append(index, indexEntry{
+			ID: ch.ID, Name: ch.Name, Parent: ch.ParentName, Kind: ch.Kind,
+			Messages: ch.Count, Files: files, Error: ch.Error,
+		})
+		messages += ch.Count
+	}
+
+	// 添付ファイル
+	attachments := filepath.Join(workDir, "attachments")
+	err := filepath.Walk(attachments, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasSuffix(p, ".part") {
+			return nil
+		}
+		rel, err := filepath.Rel(workDir, p)
+		if err != nil {
+			return err
+		}
+		return copyFile(pw, filepath.ToSlash(rel), p, info.Size())
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 索引
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.Encode(map[string]interface{}{
+		"guild_id":    cp.GuildID,
+		"guild_name":  cp.GuildName,
+		"archived_at": time.Now(),
+		"channels":    index,
+	})
+	if err := pw.write("index.json", buf.Bytes()); err != nil {
+		return nil, 0, err
+	}
+
+	buf.Reset()
+	fmt.Fprintf(&buf, "# %s アーカイブ\n\n", cp.GuildName)
+	fmt.Fprintf(&buf, "- 作成日時: %s\n- チャンネル数: %d\n- メッセージ数: %d\n\n", time.Now().Format("2006/01/02 15:04"), len(index), messages)
+	fmt.Fprintf(&buf, "個人情報は伏せ字にしています。zipが分割されている場合は、すべて同じフォルダに展開してください。\n\n")
+	fmt.Fprintf(&buf, "| 種類 | チャンネル | メッセージ数 | ファイル |\n|---|---|---|---|\n")
+	for _, e := range index {
+		label := e.Name
+		if e.Parent != "" {
+			label = e.Parent + " > " + e.Name
+		}
+		if e.Error != "" {
+			label += "（取得エラー）"
+		}
+		var links []string
+		for _, f := range e.Files {
+			links = append(links, fmt.Sprintf("[%s](%s)", f, f))
+		}
+		fmt.Fprintf(&buf, "| %s | %s | %d | %s |\n", e.Kind, label, e.Messages, strings.Join(links, " "))
+	}
+	if err := pw.write("index.md", buf.Bytes()); err != nil {
+		return nil, 0, err
+	}
+
+	paths, err := pw.finish()
+	return paths, messages, err
+}
+
+// JSON Linesのファイルを行の途中で切らずに chunk ごとのファイルに分けてzipへ書き込む
+// 分けなければ name.jsonl、分けたら name_1.jsonl, name_2.jsonl, … になる
+func copyLines(pw *partWriter, name, src string, chunk int64) ([]string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= chunk {
+		file := name + ".jsonl"
+		return []string{file}, copyFile(pw, file, src, info.Size())
+	}
+
+	var files []string
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		file := fmt.Sprintf("%s_%d.jsonl", name, len(files)+1)
+		files = append(files, file)
+		err := pw.write(file, buf.Bytes())
+		buf.Reset()
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if int64(buf.Len()+len(line)) > chunk {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			buf.Write(line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, flush()
+}
+
+func copyFile(pw *partWriter, name, src string, size int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := pw.create(name, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// 上限を超えないように、ファイルを複数のzipに振り分けて書き込む
+type partWriter struct {
+	base  string
+	limit int64
+
+	tmps    []string // 書き込み中・書き込み済みの一時ファイル
+	out     *os.File
+	counter *byteCounter
+	zw      *zip.Writer
+	entries int
+	central int64 // 最後に書く中央ディレクトリの大きさの見積もり
+}
+
+// ヘッダーや圧縮で増える分の見積もり（実際より多めに取る）
+const (
+	zipEntryOverhead = 30 + 24 + 64 // ローカルヘッダー、データ記述子、拡張フィールド
+	zipCentralEntry  = 46 + 64      // 中央ディレクトリの1件分
+	zipEndOverhead   = 22 + 64      // 中央ディレクトリの終端
+)
+
+// size バイトのファイルを書き込むためのWriterを返す
+// 今のzipに収まらなければ次のzipに切り替える
+func (pw *partWriter) create(name string, size int64) (io.Writer, error) {
+	need := zipEntryOverhead + int64(len(name)) + size + size/1000
+	if pw.zw != nil && pw.entries > 0 {
+		if err := pw.zw.Flush(); err != nil {
+			return nil, err
+		}
+		if pw.counter.n+need+pw.central+zipEndOverhead > pw.limit {
+			if err := pw.closePart(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if pw.zw == nil {
+		if err := pw.openPart(); err != nil {
+			return nil, err
+		}
+	}
+	pw.entries++
+	pw.central += zipCentralEntry + int64(len(name))
+	return pw.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+}
+
+func (pw *partWriter) write(name string, data []byte) error {
+	w, err := pw.create(name, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (pw *partWriter) openPart() error {
+	tmp := fmt.Sprintf("%s_part%d.zip.part", pw.base, len(pw.tmps)+1)
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	pw.tmps = append(pw.tmps, tmp)
+	pw.out = out
+	pw.counter = &byteCounter{w: out}
+	pw.zw = zip.NewWriter(pw.counter)
+	pw.entries = 0
+	pw.central = 0
+	return nil
+}
+
+func (pw *partWriter) closePart() error {
+	err := pw.zw.Close()
+	if cerr := pw.out.Close(); err == nil {
+		err = cerr
+	}
+	pw.zw = nil
+	pw.out = nil
+	return err
+}
+
+// 最後のzipを閉じ、一時ファイルを完成したファイル名に変える
+func (pw *partWriter) finish() ([]string, error) {
+	if pw.zw != nil {
+		if err := pw.closePart(); err != nil {
+			return nil, err
+		}
+	}
+	var paths []string
+	for n, tmp := range pw.tmps {
+		path := fmt.Sprintf("%s_part%d.zip", pw.base, n+1)
+		if len(pw.tmps) == 1 {
+			path = pw.base + ".zip"
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		pw.tmps[n] = path
+	}
+	return paths, nil
+}
+
+// 途中で失敗したら作りかけのzipを消す
+func (pw *partWriter) abort() {
+	if pw.zw != nil {
+		pw.closePart()
+	}
+	for _, tmp := range pw.tmps {
+		os.Remove(tmp)
+	}
+}
+
+// 書き込んだバイト数を数える
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,146 @@
+package guildarchive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 圧縮が効かないデータ
+func randomText(r *rand.Rand, n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteZipSplitsIntoParts(t *testing.T) {
+	const limit = 64 * 1024
+	r := rand.New(rand.NewSource(1))
+	workDir := t.TempDir()
+	cp := &checkpoint{GuildID: "guild", GuildName: "テスト"}
+
+	lines := map[string]int{}
+	for n, size := range []int{100, 200, 3000} {
+		id := fmt.Sprintf("c%d", n)
+		var b strings.Builder
+		for l := 0; l < size; l++ {
+			fmt.Fprintf(&b, "{\"id\":\"%s-%d\",\"content\":\"%s\"}\n", id, l, randomText(r, 40))
+		}
+		writeTestFile(t, filepath.Join(workDir, "messages", id+".jsonl"), b.String())
+		cp.Channels = append(cp.Channels, &channelState{ID: id, Name: "チャンネル" + id, Kind: "text", Count: size})
+		lines[id] = size
+	}
+	writeTestFile(t, filepath.Join(workDir, "attachments", "c0", "1_image.png"), randomText(r, 30*1024))
+	writeTestFile(t, filepath.Join(workDir, "attachments", "c0", "2_doc.pdf"), randomText(r, 20*1024))
+
+	paths, messages, err := writeZip(filepath.Join(t.TempDir(), "guild"), workDir, cp, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages != 3300 {
+		t.Errorf("messages = %d, want 3300", messages)
+	}
+	if len(paths) < 2 {
+		t.Fatalf("got %d parts, want the archive to be split", len(paths))
+	}
+
+	// ファイルごとの行数
+	fileLines := map[string]int{}
+	entries := map[string]bool{}
+	var index struct {
+		Channels []indexEntry `json:"channels"`
+	}
+	for n, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > limit {
+			t.Errorf("%s: %d bytes, over the limit %d", path, info.Size(), limit)
+		}
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for _, f := range zr.File {
+			entries[f.Name] = true
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			switch {
+			case f.Name == "index.json":
+				if n != len(paths)-1 {
+					t.Errorf("index.json in part %d, want the last part", n+1)
+				}
+				if err := json.Unmarshal(data, &index); err != nil {
+					t.Fatal(err)
+				}
+			case strings.HasSuffix(f.Name, ".jsonl"):
+				if len(data) > 0 && data[len(data)-1] != '\n' {
+					t.Errorf("%s is cut in the middle of a line", f.Name)
+				}
+				fileLines[f.Name] = bytes.Count(data, []byte("\n"))
+			}
+		}
+		zr.Close()
+	}
+	if len(index.Channels) != len(lines) {
+		t.Fatalf("index has %d channels, want %d", len(index.Channels), len(lines))
+	}
+	for _, e := range index.Channels {
+		got := 0
+		for _, f := range e.Files {
+			got += fileLines[f]
+		}
+		if got != lines[e.ID] {
+			t.Errorf("%s: %d lines in the archive, want %d", e.ID, got, lines[e.ID])
+		}
+	}
+	for _, name := range []string{"index.json", "index.md", "attachments/c0/1_image.png", "attachments/c0/2_doc.pdf"} {
+		if !entries[name] {
+			t.Errorf("%s is missing", name)
+		}
+	}
+}
+
+func TestWriteZipSinglePart(t *testing.T) {
+	workDir := t.TempDir()
+	writeTestFile(t, filepath.Join(workDir, "messages", "c.jsonl"), "{\"id\":\"1\"}\n")
+	cp := &checkpoint{GuildID: "guild", Channels: []*channelState{{ID: "c", Name: "general", Kind: "text", Count: 1}}}
+
+	base := filepath.Join(t.TempDir(), "guild")
+	paths, _, err := writeZip(base, workDir, cp, defaultPartLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != base+".zip" {
+		t.Errorf("paths = %v, want [%s.zip]", paths, base)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
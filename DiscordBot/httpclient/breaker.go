@@ -0,0 +1,117 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 回路が開いているため送らなかったことを表す
+var ErrCircuitOpen = errors.New("接続先が応答しないため送信を止めています")
+
+// 接続先が続けて失敗しているため、要求を送らなかったときのエラー
+type UnavailableError struct {
+	Upstream string
+	RetryAt  time.Time // この時刻以降に試しに送る
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %v（%s 以降に再開します）", e.Upstream, ErrCircuitOpen, e.RetryAt.Format("15:04:05"))
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// あと何秒で再開するか（利用者への案内用）
+func (e *UnavailableError) RetryIn() time.Duration {
+	d := time.Until(e.RetryAt).Round(time.Second)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// 回路の状態
+const (
+	stateClosed   = "closed"    // 通常どおり送る
+	stateOpen     = "open"      // 送らずに失敗させる
+	stateHalfOpen = "half-open" // 試しに1回だけ送る
+)
+
+// 1回の送信の結果
+type outcome int
+
+const (
+	succeeded outcome = iota // 接続先が応答した
+	failed                   // 通信の失敗または5xxの応答
+	abandoned                // 呼び出し側の取り消しなどで結果が分からない（成功にも失敗にも数えない）
+)
+
+// 接続先ごとのサーキットブレーカー
+// 続けて threshold 回失敗したら openFor の間は送らず、その後1回だけ試して成功すれば元に戻す
+type breaker struct {
+	name      string
+	threshold int
+	openFor   time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trying   bool // 半開の状態で試しに送っている
+}
+
+// 送ってよいか
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.openFor {
+			return &UnavailableError{Upstream: b.name, RetryAt: b.openedAt.Add(b.openFor)}
+		}
+		b.state = stateHalfOpen
+		b.trying = false
+		fallthrough
+	case stateHalfOpen:
+		if b.trying {
+			// 試しに送った要求の結果が出るまでは送らない
+			return &UnavailableError{Upstream: b.name, RetryAt: time.Now().Add(time.Second)}
+		}
+		b.trying = true
+	}
+	return nil
+}
+
+// 結果を記録する。回路を開いたときはtrueを返す
+// allow が nil を返したら、どの結果でも必ず1回呼ぶ（半開の状態で試しに送る枠を解放する）
+func (b *breaker) done(result outcome) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch result {
+	case abandoned:
+		// 回路の状態は変えず、半開なら次の要求で改めて試す
+		b.trying = false
+		return false
+	case succeeded:
+		b.state = stateClosed
+		b.failures = 0
+		b.trying = false
+		return false
+	}
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		opened := b.state != stateOpen
+		b.state = stateOpen
+		b.openedAt = time.Now()
+		b.trying = false
+		return opened
+	}
+	return false
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
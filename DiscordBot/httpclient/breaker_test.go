@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBreaker() *breaker {
+	return &breaker{name: "test", threshold: 3, openFor: time.Minute, state: stateClosed}
+}
+
+// 回路を開いてから openFor が過ぎたことにする
+func expire(b *breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-b.openFor - time.Second)
+	b.mu.Unlock()
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := newTestBreaker()
+	for n := 1; n <= 3; n++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("attempt %d rejected: %v", n, err)
+		}
+		if opened := b.done(failed); opened != (n == 3) {
+			t.Errorf("attempt %d: opened = %v", n, opened)
+		}
+	}
+	err := b.allow()
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() = %v, want UnavailableError", err)
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := newTestBreaker()
+	for _, result := range []outcome{failed, failed, succeeded, failed, failed} {
+		if err := b.allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.done(result)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("opened after non-consecutive failures: %v", err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		result outcome
+		state  string
+		next   bool // 次の要求を送れるか
+	}{
+		{"試しの成功で閉じる", succeeded, stateClosed, true},
+		{"試しの失敗で開き直す", failed, stateOpen, false},
+		{"取り消しは半開のまま次で試す", abandoned, stateHalfOpen, true},
+	} {
+		b := newTestBreaker()
+		for n := 0; n < 3; n++ {
+			b.allow()
+			b.done(failed)
+		}
+		expire(b)
+
+		if err := b.allow(); err != nil {
+			t.Fatalf("%s: probe rejected: %v", tc.name, err)
+		}
+		if err := b.allow(); err == nil {
+			t.Errorf("%s: second request allowed while probing", tc.name)
+		}
+		b.done(tc.result)
+		if b.state != tc.state {
+			t.Errorf("%s: state = %s, want %s", tc.name, b.state, tc.state)
+		}
+		if err := b.allow(); (err == nil) != tc.next {
+			t.Errorf("%s: next allow() = %v, want allowed %v", tc.name, err, tc.next)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
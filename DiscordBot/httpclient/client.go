@@ -0,0 +1,261 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 既定値
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultRetries          = 2
+	DefaultBackoff          = 500 * time.Millisecond
+	DefaultFailureThreshold = 5
+	DefaultOpenFor          = 30 * time.Second
+
+	// Retry-After で指定されても、これより長くは待たない
+	maxRetryWait = 30 * time.Second
+)
+
+// 外部サービスごとの設定
+type Config struct {
+	Name string // 外部サービスの名前（ログと統計に使う）
+
+	// 1回の試行の上限（0なら DefaultTimeout、負ならタイムアウトしない）
+	// 音声のように本文を読み続ける用途では負にして、contextで止める
+	Timeout time.Duration
+	// 再試行する回数（0なら DefaultRetries、負なら再試行しない）
+	Retries int
+	// 最初の再試行までの待ち時間（再試行のたびに倍にする）
+	Backoff time.Duration
+	// POSTなども再試行してよいか（音声合成のように同じ要求を何度送っても結果が変わらないAPI）
+	// falseでも Idempotency-Key ヘッダーを付けた要求は再試行する
+	Idempotent bool
+
+	// 続けて何回失敗したら回路を開くか
+	FailureThreshold int
+	// 回路を開いてから、試しに1回送るまでの時間
+	OpenFor time.Duration
+}
+
+// タイムアウト・再試行・サーキットブレーカー・ログ・統計を備えたHTTPクライアント
+// 外部サービスとの通信はすべてこれを通す
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu       sync.Mutex
+	breakers map[string]*breaker // 接続先のホストごと
+}
+
+// Clientを返す
+func New(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Timeout < 0 {
+		cfg.Timeout = 0
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = DefaultRetries
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = DefaultOpenFor
+	}
+	return &Client{
+		cfg:      cfg,
+		http:     &http.Client{Timeout: cfg.Timeout},
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// 要求を送る
+//
+// 接続先が続けて失敗している間は送らずに *UnavailableError を返す。
+// 冪等な要求は、通信の失敗と 429・502・503・504 の応答を待ち時間を延ばしながら再試行する。
+// 再試行しても失敗した応答はそのまま返すので、ステータスコードの確認は呼び出し側で行う
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	b := c.breaker(req.URL.Host)
+	stats := statsFor(c.upstream(req))
+	retries := 0
+	if c.retryable(req) {
+		retries = c.cfg.Retries
+	}
+
+	wait := c.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		// 本文を用意してから回路を確かめ、送らずに終わるときに試しに送る枠を持ったままにしない
+		if attempt > 1 {
+			if err := c.rewind(req); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.allow(); err != nil {
+			stats.reject()
+			log.Printf("[http %s] %s %s → 送信しませんでした: %v\n", c.upstream(req), req.Method, RedactURL(req.URL), err)
+			return nil, err
+		}
+
+		started := time.Now()
+		resp, err := c.http.Do(req)
+		elapsed := time.Since(started)
+		err = redactError(err)
+		if opened := b.done(result(req, resp, err)); opened {
+			stats.open(time.Now().Add(c.cfg.OpenFor))
+			log.Printf("[http %s] 失敗が続いたため %s への送信を %s 止めます\n", c.upstream(req), req.URL.Host, c.cfg.OpenFor)
+		}
+		stats.record(resp, err, elapsed, attempt > 1)
+		logAttempt(c.upstream(req), req, resp, err, elapsed, attempt)
+
+		if attempt > retries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		delay := retryAfter(resp, wait)
+		if resp != nil {
+			// 再試行する前に本文を読み捨てて接続を再利用する
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		wait *= 2
+	}
+}
+
+// 送信の結果をサーキットブレーカーに記録する形にする
+// 呼び出し側の取り消しは、接続先の失敗にも成功にも数えない
+func result(req *http.Request, resp *http.Response, err error) outcome {
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		return abandoned
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		return failed
+	}
+	return succeeded
+}
+
+// ログと統計に使う接続先の名前
+func (c *Client) upstream(req *http.Request) string {
+	if c.cfg.Name != "" {
+		return c.cfg.Name
+	}
+	return req.URL.Host
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{name: c.cfg.Name, threshold: c.cfg.FailureThreshold, openFor: c.cfg.OpenFor}
+		if b.name == "" {
+			b.name = host
+		}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// 再試行してよい要求か
+func (c *Client) retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // 本文を送り直せない
+	}
+	if c.cfg.Idempotent || req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// 再試行のために本文を最初から読めるようにする
+func (c *Client) rewind(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// 次の試行までの待ち時間（Retry-After があればそれに従う）
+// 同時に失敗した要求が一斉に再試行しないよう、待ち時間をばらつかせる
+func retryAfter(resp *http.Response, wait time.Duration) time.Duration {
+	if resp != nil {
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+				d := time.Duration(sec) * time.Second
+				if d > maxRetryWait {
+					d = maxRetryWait
+				}
+				return d
+			}
+		}
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// エラーに含まれるURLから秘密の値を伏せる
+func redactError(err error) error {
+	var urlErr *url.Error
+	if err == nil || !errors.As(err, &urlErr) {
+		return err
+	}
+	if u, perr := url.Parse(urlErr.URL); perr == nil {
+		urlErr.URL = RedactURL(u)
+	}
+	return err
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
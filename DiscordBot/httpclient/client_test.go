@@ -0,0 +1,175 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 指定した順にステータスコードを返すサーバー（使い切ったら200）
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func testClient(name string) *Client {
+	return New(Config{Name: name, Backoff: time.Millisecond, FailureThreshold: 2, OpenFor: time.Minute})
+}
+
+func TestDoRetriesGet(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := testClient(t.Name()).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(calls) != 3 {
+		t.Errorf("status = %d after %d calls, want 200 after 3", resp.StatusCode, *calls)
+	}
+}
+
+func TestDoGivesUpAfterRetries(t *testing.T) {
+	srv, calls := statusServer(t, 502, 502, 502, 502)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	c := New(Config{Name: t.Name(), Backoff: time.Millisecond, Retries: 1})
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || atomic.LoadInt32(calls) != 2 {
+		t.Errorf("status = %d after %d calls, want 502 after 2", resp.StatusCode, *calls)
+	}
+}
+
+func TestDoDoesNotRetryPost(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		key   string
+		calls int32
+	}{
+		{"POSTは再試行しない", Config{}, "", 1},
+		{"Idempotency-Keyがあれば再試行する", Config{}, "key", 2},
+		{"冪等なAPIは再試行する", Config{Idempotent: true}, "", 2},
+	} {
+		srv, calls := statusServer(t, http.StatusServiceUnavailable)
+		tc.cfg.Name = t.Name() + tc.name
+		tc.cfg.Backoff = time.Millisecond
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("{}"))
+		if tc.key != "" {
+			req.Header.Set("Idempotency-Key", tc.key)
+		}
+		resp, err := New(tc.cfg).Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		resp.Body.Close()
+		if got := atomic.LoadInt32(calls); got != tc.calls {
+			t.Errorf("%s: %d calls, want %d", tc.name, got, tc.calls)
+		}
+	}
+}
+
+func TestDoRejectsWhileOpen(t *testing.T) {
+	srv, calls := statusServer(t, 500, 500, 500)
+	c := testClient(t.Name())
+	for n := 0; n < 2; n++ {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v, want ErrCircuitOpen", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("%d calls, want 2", got)
+	}
+}
+
+func TestDoReleasesProbeWhenRewindFails(t *testing.T) {
+	// 1回目の失敗で回路が開き、再試行の時点ではすぐ半開になる
+	srv, _ := statusServer(t, http.StatusServiceUnavailable)
+	c := New(Config{Name: t.Name(), Backoff: time.Millisecond, FailureThreshold: 1, OpenFor: time.Nanosecond})
+	b := c.breaker(strings.TrimPrefix(srv.URL, "http://"))
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader("body"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errors.New("rewind") }
+	if _, err := c.Do(req); err == nil || err.Error() != "rewind" {
+		t.Fatalf("err = %v, want the rewind error", err)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("breaker stuck after rewind failure: %v", err)
+	}
+}
+
+func TestDoCancellationIsNotSuccess(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	c := testClient(t.Name())
+	b := c.breaker(strings.TrimPrefix(srv.URL, "http://"))
+	b.state, b.openedAt, b.failures = stateOpen, time.Now().Add(-time.Hour), 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("expected the canceled request to fail")
+	}
+	b.mu.Lock()
+	state, failures, trying := b.state, b.failures, b.trying
+	b.mu.Unlock()
+	if state != stateHalfOpen || failures != 2 || trying {
+		t.Errorf("state = %s, failures = %d, trying = %v; want half-open, 2, false", state, failures, trying)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := func(v string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": {v}}}
+	}
+	if got := retryAfter(header("3"), time.Second); got != 3*time.Second {
+		t.Errorf("Retry-After 3 = %v", got)
+	}
+	if got := retryAfter(header("3600"), time.Second); got != maxRetryWait {
+		t.Errorf("Retry-After 3600 = %v, want %v", got, maxRetryWait)
+	}
+	for n := 0; n < 100; n++ {
+		for _, resp := range []*http.Response{nil, header("soon")} {
+			if got := retryAfter(resp, time.Second); got < 500*time.Millisecond || got >= 1500*time.Millisecond {
+				t.Fatalf("jittered wait = %v, want within [0.5s, 1.5s)", got)
+			}
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 値を伏せるクエリパラメーター（APIキー、トークン、DiscordのCDNの署名など）
+var secretParam = regexp.MustCompile(`(?i)(key|token|secret|password|passwd|auth|signature|sig|^hm$)`)
+
+// ログに書けるようにURLから秘密の値を伏せる
+// ユーザー情報は取り除き、秘密に見えるクエリパラメーターの値を *** にする
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	copied := *u
+	copied.User = nil
+	q := copied.Query()
+	changed := false
+	for name := range q {
+		if secretParam.MatchString(name) {
+			q.Set(name, "***")
+			changed = true
+		}
+	}
+	if changed {
+		copied.RawQuery = strings.ReplaceAll(q.Encode(), url.QueryEscape("***"), "***")
+	}
+	return copied.String()
+}
+
+// 1回の試行を1行で記録する（ヘッダーと本文は書かない）
+func logAttempt(upstream string, req *http.Request, resp *http.Response, err error, elapsed time.Duration, attempt int) {
+	result := ""
+	switch {
+	case err != nil:
+		result = "エラー: " + err.Error()
+	default:
+		result = resp.Status
+	}
+	suffix := ""
+	if attempt > 1 {
+		suffix = "（再試行）"
+	}
+	log.Printf("[http %s] %s %s → %s %s%s\n", upstream, req.Method, RedactURL(req.URL), result, elapsed.Round(time.Millisecond), suffix)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
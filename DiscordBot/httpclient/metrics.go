@@ -0,0 +1,116 @@
+package httpclient
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 外部サービスごとの通信の統計
+type Stats struct {
+	Upstream     string        `json:"upstream"`
+	Requests     int64         `json:"requests"`             // 送った回数（再試行を含む）
+	Retries      int64         `json:"retries"`              // うち再試行の回数
+	Errors       int64         `json:"errors"`               // 通信エラーと5xxの応答
+	Rejected     int64         `json:"rejected"`             // 回路が開いていて送らなかった回数
+	CircuitOpens int64         `json:"circuit_opens"`        // 回路を開いた回数
+	OpenUntil    time.Time     `json:"open_until,omitempty"` // 最後に回路を開いたとき、送らずにいる期限
+	StatusCodes  map[int]int64 `json:"status_codes"`
+	AvgLatency   time.Duration `json:"avg_latency_ns"`
+	MaxLatency   time.Duration `json:"max_latency_ns"`
+	LastError    string        `json:"last_error,omitempty"`
+	LastErrorAt  time.Time     `json:"last_error_at,omitempty"`
+}
+
+// 集計中の統計
+type counter struct {
+	mu           sync.Mutex
+	stats        Stats
+	totalLatency time.Duration // 平均を求めるための合計
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*counter)
+)
+
+func statsFor(upstream string) *counter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	c, ok := registry[upstream]
+	if !ok {
+		c = &counter{stats: Stats{Upstream: upstream, StatusCodes: make(map[int]int64)}}
+		registry[upstream] = c
+	}
+	return c
+}
+
+func (c *counter) record(resp *http.Response, err error, elapsed time.Duration, retry bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &c.stats
+	s.Requests++
+	if retry {
+		s.Retries++
+	}
+	c.totalLatency += elapsed
+	if elapsed > s.MaxLatency {
+		s.MaxLatency = elapsed
+	}
+	switch {
+	case err != nil:
+		s.Errors++
+		s.LastError, s.LastErrorAt = err.Error(), time.Now()
+	default:
+		s.StatusCodes[resp.StatusCode]++
+		if resp.StatusCode >= http.StatusInternalServerError {
+			s.Errors++
+			s.LastError, s.LastErrorAt = resp.Status, time.Now()
+		}
+	}
+}
+
+func (c *counter) reject() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Rejected++
+}
+
+func (c *counter) open(until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.CircuitOpens++
+	c.stats.OpenUntil = until
+}
+
+// すべての外部サービスの統計を名前順に返す
+func Snapshot() []Stats {
+	registryMu.Lock()
+	list := make([]*counter, 0, len(registry))
+	for _, c := range registry {
+		list = append(list, c)
+	}
+	registryMu.Unlock()
+
+	out := make([]Stats, 0, len(list))
+	for _, c := range list {
+		c.mu.Lock()
+		copied := c.stats
+		copied.StatusCodes = make(map[int]int64, len(c.stats.StatusCodes))
+		for code, n := range c.stats.StatusCodes {
+			copied.StatusCodes[code] = n
+		}
+		if c.stats.Requests > 0 {
+			copied.AvgLatency = c.totalLatency / time.Duration(c.stats.Requests)
+		}
+		c.mu.Unlock()
+		out = append(out, copied)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Upstream < out[j].Upstream })
+	return out
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,190 @@
+package discordtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// テストで使う、メッセージ一覧（/channels/{id}/messages）のREST APIを真似るサーバー
+// メッセージのIDは番号から作り、Discordと同じく新しい順で返す。
+// 本文を書き換えたり消したりして、Botが停止していた間の編集・削除を再現できる
+type Server struct {
+	mu          sync.Mutex
+	channels    map[string]*channel
+	requests    []Request
+	rateLimited int // 429を返す残り回数
+}
+
+type channel struct {
+	ids      []int          // 古い順の番号
+	messages map[int]string // 番号 -> 本文
+}
+
+// サーバーが受け取ったリクエスト
+type Request struct {
+	ChannelID, Before, After string
+}
+
+// IDは桁数を揃え、文字列の大小とIDの大小を一致させる
+func MessageID(n int) string {
+	return fmt.Sprintf("%019d", n)
+}
+
+func NewServer() *Server {
+	return &Server{channels: make(map[string]*channel)}
+}
+
+// 番号が1からnで、本文が「message 番号」のメッセージを持つチャンネルを加える
+func (f *Server) AddChannel(channelID string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := &channel{ids: make([]int, n), messages: make(map[int]string, n)}
+	for i := 1; i <= n; i++ {
+		ch.ids[i-1] = i
+		ch.messages[i] = "message " + strconv.Itoa(i)
+	}
+	f.channels[channelID] = ch
+}
+
+// メッセージを投稿または編集する
+func (f *Server) Set(channelID string, n int, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := f.channels[channelID]
+	if _, ok := ch.messages[n]; !ok {
+		at := sort.SearchInts(ch.ids, n)
+		ch.ids = append(ch.ids, 0)
+		copy(ch.ids[at+1:], ch.ids[at:])
+		ch.ids[at] = n
+	}
+	ch.messages[n] = content
+}
+
+// メッセージを削除する
+func (f *Server) Remove(channelID string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := f.channels[channelID]
+	if _, ok := ch.messages[n]; !ok {
+		return
+	}
+	at := sort.SearchInts(ch.ids, n)
+	ch.ids = append(ch.ids[:at], ch.ids[at+1:]...)
+	delete(ch.messages, n)
+}
+
+// 続くn回のリクエストにレート制限（429）を返す
+func (f *Server) RateLimit(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rateLimited = n
+}
+
+// これまでに受け取ったリクエスト
+func (f *Server) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// 受け取ったリクエストの記録を消す
+func (f *Server) ResetRequests() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = nil
+}
+
+func (f *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "channels" || parts[2] != "messages" {
+		http.NotFound(w, r)
+		return
+	}
+	channelID := parts[1]
+	q := r.URL.Query()
+
+	f.mu.Lock()
+	f.requests = append(f.requests, Request{ChannelID: channelID, Before: q.Get("before"), After: q.Get("after")})
+	w.Header().Set("Content-Type", "application/json")
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"message":"You are being rate limited.","retry_after":0.01,"global":false}`)
+		return
+	}
+	ch, ok := f.channels[channelID]
+	if !ok {
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Unknown Channel","code":10003}`)
+		return
+	}
+	limit := 50
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil {
+		limit = l
+	}
+
+	// afterを指定したら直後から、beforeを指定したら直前まで、どちらも無ければ最新から limit 件
+	ids := ch.ids
+	switch {
+	case q.Get("after") != "":
+		after, _ := strconv.Atoi(q.Get("after"))
+		ids = ids[sort.SearchInts(ids, after+1):]
+		if len(ids) > limit {
+			ids = ids[:limit]
+		}
+	case q.Get("before") != "":
+		before, _ := strconv.Atoi(q.Get("before"))
+		ids = ids[:sort.SearchInts(ids, before)]
+		fallthrough
+	default:
+		if len(ids) > limit {
+			ids = ids[len(ids)-limit:]
+		}
+	}
+
+	page := []*discordgo.Message{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		page = append(page, &discordgo.Message{
+			ID:        MessageID(ids[i]),
+			ChannelID: channelID,
+			Content:   ch.messages[ids[i]],
+			Author:    &discordgo.User{ID: "user"},
+		})
+	}
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(page)
+}
+
+// サーバーへ接続するセッションを返す（テストが終わるとサーバーを閉じる）
+func NewSession(tb testing.TB, f *Server) *discordgo.Session {
+	tb.Helper()
+	srv := httptest.NewServer(f)
+	saved := discordgo.EndpointChannels
+	discordgo.EndpointChannels = srv.URL + "/channels/"
+	tb.Cleanup(func() {
+		discordgo.EndpointChannels = saved
+		srv.Close()
+	})
+
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s.Client = srv.Client()
+	return s
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
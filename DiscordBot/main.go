@@ -1,17 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 
+	"main/attendance"
 	"main/botHandler/botRouter"
 	"main/commands"
+	"main/digest"
+	"main/guildarchive"
+	"main/messagestore"
 
 	"main/model/envconfig"
+	"main/recording"
+	"main/redact"
+	"main/search"
 	"main/serverHandler/router"
+	"main/storage"
+	"main/summary"
+	"main/task"
+	"main/transcript"
+	"main/tts"
+	"main/usage"
+	"main/voice"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -24,10 +40,7 @@ func main() {
 	env, err := envconfig.NewEnv()
 	if err != nil {
 		fmt.Println("error loading env")
-		env = &envconfig.Env{
-			TOKEN:      os.Getenv("TOKEN"),
-			ServerPort: os.Getenv("PORT"),
-		}
+		env = envconfig.FromOS()
 	}
 	Token := "Bot " + env.TOKEN //"Bot"という接頭辞がないと401 unauthorizedエラーが起きます
 	discord, err := discordgo.New(Token)
@@ -49,18 +62,162 @@ func main() {
 	// ハンドラーの登録
 	botRouter.RegisterHandlers(discord)
 
+	// ボイスチャンネルへの音声再生（ギルドごとのキュー）
+	player := voice.NewPlayer(discord)
+
+	// テキストチャンネルの読み上げ
+	synthesizer, err := tts.NewSynthesizer(env.TTSEngine, env.VoicevoxURL, env.TTSCommand)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ttsSettings, err := tts.LoadSettings(storage.NewJSONFile(env.DataDir, "tts.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ttsReader := tts.NewReader(synthesizer, player, ttsSettings)
+	discord.AddHandler(ttsReader.OnMessageCreate)
+
+	// ボイスチャンネルの出席記録
+	tracker, err := attendance.NewTracker(filepath.Join(env.DataDir, "attendance"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	discord.AddHandler(tracker.OnVoiceStateUpdate)
+
+	// 出力や要約から個人情報を伏せ字にする
+	redactor, err := redact.NewEngine(filepath.Join(env.DataDir, "redact"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ギルドごとの要約・書き起こしの利用量と月間の上限
+	prices, err := usage.ParsePrices(env.UsagePrices)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ledger, err := usage.NewLedger(filepath.Join(env.DataDir, "usage"), prices)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ギルドごとの録音セッションと書き起こし結果
+	recordings := recording.NewManager()
+	discord.AddHandler(recordings.OnVoiceStateUpdate)
+	transcripts := transcript.NewStore(filepath.Join(env.DataDir, "transcripts"))
+	recordConfig := &commands.RecordConfig{
+		Recordings:      recordings,
+		Attendance:      tracker,
+		Transcripts:     transcripts,
+		Redactor:        redactor,
+		ResultChannelID: env.TranscriptChannelID,
+		Usage:           ledger,
+	}
+
+	// メッセージの保存（crawling・summaryはここから読み込む）
+	messages, err := messagestore.NewStore(filepath.Join(env.DataDir, "messages"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	discord.AddHandler(messages.OnMessageCreate)
+	discord.AddHandler(messages.OnMessageUpdate)
+	discord.AddHandler(messages.OnMessageDelete)
+	discord.AddHandler(messages.OnMessageDeleteBulk)
+
+	// メッセージの全文検索
+	searcher := search.NewSearcher(messages)
+	discord.AddHandler(searcher.OnGuildCreate)
+	discord.AddHandler(searcher.OnMessageCreate)
+	discord.AddHandler(searcher.OnMessageUpdate)
+	discord.AddHandler(searcher.OnMessageDelete)
+	discord.AddHandler(searcher.OnMessageDeleteBulk)
+	// ハンドラーを登録する前に届いたギルドの分も取り込みを始める
+	for _, g := range discord.State.Guilds {
+		searcher.Prepare(discord, g.ID)
+	}
+
+	// 要約した範囲の記録
+	summaryHistory, err := summary.NewHistory(filepath.Join(env.DataDir, "summary"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 要約のバックエンド（OpenAI互換APIは設定がある場合だけ使える）
+	backends := []summary.Summarizer{summary.NewFastAPI(env.FastAPIURL), summary.Fake{}}
+	if env.OpenAIBaseURL != "" || env.OpenAIAPIKey != "" {
+		backends = append(backends, summary.NewOpenAI(summary.OpenAIConfig{
+			BaseURL: env.OpenAIBaseURL,
+			APIKey:  env.OpenAIAPIKey,
+			Model:   env.OpenAIModel,
+		}))
+	}
+	summarizers, err := summary.NewSelector(filepath.Join(env.DataDir, "summary"), env.SummaryBackend, backends...)
+	if err != nil {
+		log.Fatalf("要約のバックエンド %q を使用できません: %v", env.SummaryBackend, err)
+	}
+	// ギルドごとに上書きした要約のテンプレート
+	summaryTemplates, err := summary.NewTemplates(filepath.Join(env.DataDir, "summary"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// 要約のアクションアイテムから作るタスク（期限前の通知と週ごとの報告）
+	tasks, err := task.NewStore(filepath.Join(env.DataDir, "tasks"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	go tasks.Start(context.Background(), commands.TaskReminder(discord), commands.TaskReport(discord))
+	// 同じ範囲の要約の再利用（メッセージが編集・削除されたら使わない）
+	summaryCache := summary.NewCache()
+	discord.AddHandler(summaryCache.OnMessageUpdate)
+	discord.AddHandler(summaryCache.OnMessageDelete)
+	discord.AddHandler(summaryCache.OnMessageDeleteBulk)
+	summaryConfig := &commands.SummaryConfig{
+		Messages:    messages,
+		Redactor:    redactor,
+		History:     summaryHistory,
+		Summarizers: summarizers,
+		Pipeline:    summary.NewPipeline(),
+		Tasks:       tasks,
+		Templates:   summaryTemplates,
+		Cache:       summaryCache,
+		Usage:       ledger,
+	}
+
+	// 定期的なダイジェスト
+	digests, err := digest.NewScheduler(filepath.Join(env.DataDir, "digest"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	go digests.Start(context.Background(), commands.DigestRunner(discord, summaryConfig))
+
+	// サーバー全体のアーカイブ
+	archiver := guildarchive.NewArchiver(discord, filepath.Join(env.DataDir, "archives"), redactor)
+
 	var commandHandlers []*botRouter.Handler
 	// 所属しているサーバすべてにスラッシュコマンドを追加する
 	// NewCommandHandlerの第二引数を空にすることで、グローバルでの使用を許可する
 	commandHandler := botRouter.NewCommandHandler(discord, "")
+	// コマンドの内部エラーを報告するチャンネル
+	commandHandler.SetErrorChannel(env.LogChannelID)
 	// 追加したいコマンドをここに追加
-	commandHandler.CommandRegister(commands.PingCommand())       // テスト用の Ping/Pong コマンド
-	commandHandler.CommandRegister(commands.RecordCommand())     // 音声を録音するコマンド
-	commandHandler.CommandRegister(commands.DisconnectCommand()) // ボイスチャンネルから切断するコマンド
+	commandHandler.CommandRegister(commands.PingCommand())                         // テスト用の Ping/Pong コマンド
+	commandHandler.CommandRegister(commands.RecordCommand(recordConfig))           // 音声を録音するコマンド
+	commandHandler.CommandRegister(commands.StopRecordCommand(recordings))         // 録音を停止するコマンド
+	commandHandler.CommandRegister(commands.DisconnectCommand(recordings, player)) // ボイスチャンネルから切断するコマンド
+	commandHandler.CommandRegister(commands.PlayCommand(player))                   // 音声ファイルを再生するコマンド
+	commandHandler.CommandRegister(commands.TTSCommand(ttsReader))                 // メッセージを読み上げるコマンド
+	commandHandler.CommandRegister(commands.AttendanceCommand(tracker))            // 会議の出席を記録するコマンド
 
-	commandHandler.CommandRegister(commands.CrawlingTextCommand())     // テキストをクローリングするコマンド
-	commandHandler.CommandRegister(commands.SummariesCommand())        // クローリングしたテキストを要約するコマンド
-	commandHandler.CommandRegister(commands.CreateCommissionCommand()) // 委任状を作成するコマンド
+	commandHandler.CommandRegister(commands.CrawlingTextCommand(messages, redactor))    // テキストをクローリングするコマンド
+	commandHandler.CommandRegister(commands.SummariesCommand(summaryConfig))            // クローリングしたテキストを要約するコマンド
+	commandHandler.CommandRegister(commands.SummaryBackendCommand(summarizers))         // 要約のバックエンドを設定するコマンド
+	commandHandler.CommandRegister(commands.SummaryTemplateCommand(summaryTemplates))   // 要約のテンプレートを設定するコマンド
+	commandHandler.CommandRegister(commands.DigestCommand(digests))                     // 定期的に要約を投稿するコマンド
+	commandHandler.CommandRegister(commands.TaskCommand(tasks))                         // 要約から作ったタスクを管理するコマンド
+	commandHandler.CommandRegister(commands.UsageCommand(ledger))                       // 要約と書き起こしの利用量を表示するコマンド
+	commandHandler.CommandRegister(commands.CreateCommissionCommand(env.CommissionURL)) // 委任状を作成するコマンド
+	commandHandler.CommandRegister(commands.ArchiveCommand(archiver))                   // サーバー全体をアーカイブするコマンド
+	commandHandler.CommandRegister(commands.SearchCommand(searcher))                    // メッセージを検索するコマンド
+	commandHandler.CommandRegister(commands.RedactCommand(redactor))                    // 伏せ字のルールを設定するコマンド
+	commandHandler.CommandRegister(commands.UnredactCommand(redactor))                  // 伏せ字を復元するコマンド
 	commandHandlers = append(commandHandlers, commandHandler)
 
 	fmt.Println("Discordに接続しました。")
@@ -79,7 +236,15 @@ func main() {
 		}
 		port = ":" + port
 
-		mux := router.NewRouter(discord)
+		mux := router.NewRouter(discord, &router.Dependencies{
+			Player:      player,
+			Recordings:  recordings,
+			SoundsDir:   env.SoundsDir,
+			Transcripts: transcripts,
+			Attendance:  tracker,
+			Messages:    messages,
+			APIToken:    env.APIToken,
+		})
 		log.Printf("Serving HTTP port: %s\n", port)
 		log.Fatal(http.ListenAndServe(port, mux))
 	}()
@@ -0,0 +1,321 @@
+package messagestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ログを書き直す最小の行数
+// 行数がこれとメッセージ数の2倍の両方を超えたら、現在の状態だけを残すよう書き直す
+const compactMinEntries = 1000
+
+// 1チャンネル分のメッセージ
+// ログファイルは読み込むときに全体を読み、以降はメモリ上の状態と合わせて追記する
+type channelLog struct {
+	path string
+
+	mu       sync.Mutex
+	messages map[string]*Message
+	order    []string // 古い順のメッセージID
+	sorted   bool
+	entries  int // ログファイルの行数
+
+	syncMu sync.Mutex // バックフィルの多重実行を防ぐ
+}
+
+// ログファイルを読み込み、操作を順に適用する
+func loadChannel(path string) (*channelLog, error) {
+	c := &channelLog{
+		path:     path,
+		messages: make(map[string]*Message),
+		sorted:   true,
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// 書き込み途中で終了した行は読み飛ばす
+			continue
+		}
+		c.apply(&e)
+		c.entries++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if c.needsCompact() {
+		if err := c.compact(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// 操作をログファイルに追記し、メモリ上にも反映する
+// 編集や削除で行数が増えすぎたら、ログを書き直して小さくする
+func (c *channelLog) write(entries ...*entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.append(entries); err != nil {
+		return err
+	}
+	if c.needsCompact() {
+		return c.compact()
+	}
+	return nil
+}
+
+func (c *channelLog) append(entries []*entry) error {
+	if err := appendEntries(c.path, entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		c.apply(e)
+		c.entries++
+	}
+	return nil
+}
+
+// 操作をログファイルに追記する（メモリ上の状態は変えない）
+func appendEntries(path string, entries []*entry) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// 書き直すほどログの行数が増えたか（呼び出し側でロックを取る）
+func (c *channelLog) needsCompact() bool {
+	return c.entries > compactMinEntries && c.entries > 2*len(c.messages)
+}
+
+// メッセージごとの現在の状態（履歴と削除日時を含む）だけを残すようにログを書き直す
+// 一時ファイルに書いてから置き換えるため、途中で終了しても元のログは残る（呼び出し側でロックを取る）
+func (c *channelLog) compact() error {
+	c.sortOrder()
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, id := range c.order {
+		m := c.messages[id]
+		e := &entry{Op: opSnapshot, At: time.Now(), Message: m.Message, Revisions: m.Revisions, DeletedAt: m.DeletedAt}
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("ログの書き直しに失敗しました: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.entries = len(c.order)
+	return nil
+}
+
+// REST APIで取得したメッセージのうち、未保存のものを新規として書き込む
+func (c *channelLog) writeNew(messages []*discordgo.Message) (int, error) {
+	var entries []*entry
+	for _, m := range messages {
+		if c.has(m.ID) {
+			continue
+		}
+		e := newEntry(opCreate)
+		e.Message = m
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return len(entries), c.write(entries...)
+}
+
+// 操作を1件適用する（呼び出し側でロックを取る）
+// list() が返したメッセージはロックの外で読まれるため、保存済みの値は書き換えずに差し替える
+func (c *channelLog) apply(e *entry) {
+	switch e.Op {
+	case opCreate:
+		if e.Message == nil {
+			return
+		}
+		if _, ok := c.messages[e.Message.ID]; ok {
+			return
+		}
+		c.messages[e.Message.ID] = &Message{Message: e.Message}
+		if n := len(c.order); n > 0 && IDLess(e.Message.ID, c.order[n-1]) {
+			c.sorted = false
+		}
+		c.order = append(c.order, e.Message.ID)
+
+	case opUpdate:
+		if e.Message == nil {
+			return
+		}
+		old, ok := c.messages[e.Message.ID]
+		if !ok {
+			// 保存前のメッセージの編集は、内容が揃っていれば新規として扱う
+			if e.Message.Author != nil {
+				c.apply(&entry{Op: opCreate, At: e.At, Message: e.Message})
+			}
+			return
+		}
+		updated := *old
+		if e.Message.Author == nil {
+			// 埋め込みの展開など、本文を含まない部分的な更新
+			if e.Message.Embeds != nil {
+				message := *old.Message
+				message.Embeds = e.Message.Embeds
+				updated.Message = &message
+				c.messages[e.Message.ID] = &updated
+			}
+			return
+		}
+		if e.Message.Content != old.Content {
+			editedAt := e.At
+			if e.Message.EditedTimestamp != nil {
+				editedAt = *e.Message.EditedTimestamp
+			}
+			updated.Revisions = append(append([]Revision(nil), old.Revisions...), Revision{Content: old.Content, EditedAt: editedAt})
+		}
+		if e.Message.Timestamp.IsZero() {
+			e.Message.Timestamp = old.Timestamp
+		}
+		updated.Message = e.Message
+		c.messages[e.Message.ID] = &updated
+
+	case opDelete:
+		if m, ok := c.messages[e.ID]; ok && m.DeletedAt == nil {
+			deleted := *m
+			at := e.At
+			deleted.DeletedAt = &at
+			c.messages[e.ID] = &deleted
+		}
+
+	case opSnapshot:
+		if e.Message == nil {
+			return
+		}
+		if _, ok := c.messages[e.Message.ID]; !ok {
+			if n := len(c.order); n > 0 && IDLess(e.Message.ID, c.order[n-1]) {
+				c.sorted = false
+			}
+			c.order = append(c.order, e.Message.ID)
+		}
+		c.messages[e.Message.ID] = &Message{Message: e.Message, Revisions: e.Revisions, DeletedAt: e.DeletedAt}
+	}
+}
+
+// 削除されていないメッセージを古い順に返す
+func (c *channelLog) list() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sortOrder()
+	list := make([]*Message, 0, len(c.order))
+	for _, id := range c.order {
+		if m := c.messages[id]; m.DeletedAt == nil {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// メッセージIDを古い順に並べる（呼び出し側でロックを取る）
+func (c *channelLog) sortOrder() {
+	if !c.sorted {
+		sort.Slice(c.order, func(i, j int) bool { return IDLess(c.order[i], c.order[j]) })
+		c.sorted = true
+	}
+}
+
+// 編集・削除の履歴を含めてメッセージを返す
+func (c *channelLog) get(id string) (*Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.messages[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *m
+	copied.Revisions = append([]Revision(nil), m.Revisions...)
+	return &copied, true
+}
+
+// 最も新しいメッセージのID（削除済みを含む）
+func (c *channelLog) newestID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	newest := ""
+	for _, id := range c.order {
+		if newest == "" || IDLess(newest, id) {
+			newest = id
+		}
+	}
+	return newest
+}
+
+func (c *channelLog) has(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.messages[id]
+	return ok
+}
+
+func newEntry(op string) *entry {
+	return &entry{Op: op, At: time.Now()}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
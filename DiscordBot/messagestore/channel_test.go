@@ -0,0 +1,294 @@
+package messagestore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func newTestLog(t *testing.T) *channelLog {
+	t.Helper()
+	c, err := loadChannel(filepath.Join(t.TempDir(), "channel.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testMessage(id, content string) *discordgo.Message {
+	return &discordgo.Message{
+		ID:        id,
+		ChannelID: "channel",
+		Content:   content,
+		Author:    &discordgo.User{ID: "user"},
+		Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func opEntry(op string, m *discordgo.Message) *entry {
+	e := newEntry(op)
+	e.Message = m
+	return e
+}
+
+func deleteEntry(id string) *entry {
+	e := newEntry(opDelete)
+	e.ID = id
+	return e
+}
+
+func listIDs(c *channelLog) []string {
+	var ids []string
+	for _, m := range c.list() {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lineCount(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
+
+func TestApplyCreate(t *testing.T) {
+	c := newTestLog(t)
+	if err := c.write(opEntry(opCreate, testMessage("30", "c")), opEntry(opCreate, testMessage("100", "a")), opEntry(opCreate, testMessage("20", "b"))); err != nil {
+		t.Fatal(err)
+	}
+	// 同じIDの作成は無視される
+	if err := c.write(opEntry(opCreate, testMessage("30", "changed"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := listIDs(c), []string{"20", "30", "100"}; !equalIDs(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if m, _ := c.get("30"); m.Content != "c" {
+		t.Errorf("content = %q, want %q", m.Content, "c")
+	}
+	if got := c.newestID(); got != "100" {
+		t.Errorf("newestID = %q, want 100", got)
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	c := newTestLog(t)
+	if err := c.write(opEntry(opCreate, testMessage("1", "first"))); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := c.get("1")
+
+	edited := testMessage("1", "second")
+	editedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	edited.EditedTimestamp = &editedAt
+	edited.Timestamp = time.Time{} // 編集イベントに投稿日時が無くても保存済みのものを残す
+	if err := c.write(opEntry(opUpdate, edited)); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := c.get("1")
+	if m.Content != "second" {
+		t.Errorf("content = %q, want second", m.Content)
+	}
+	if len(m.Revisions) != 1 || m.Revisions[0].Content != "first" || !m.Revisions[0].EditedAt.Equal(editedAt) {
+		t.Errorf("revisions = %+v, want [first at %v]", m.Revisions, editedAt)
+	}
+	if m.Timestamp.IsZero() {
+		t.Error("timestamp was cleared by the update")
+	}
+	// 以前に返したメッセージは書き換えない
+	if before.Content != "first" || len(before.Revisions) != 0 {
+		t.Errorf("previously returned message changed: %q %+v", before.Content, before.Revisions)
+	}
+
+	// 本文が同じ更新は履歴を増やさない
+	if err := c.write(opEntry(opUpdate, testMessage("1", "second"))); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := c.get("1"); len(m.Revisions) != 1 {
+		t.Errorf("revisions = %d, want 1", len(m.Revisions))
+	}
+}
+
+func TestApplyPartialUpdate(t *testing.T) {
+	c := newTestLog(t)
+	if err := c.write(opEntry(opCreate, testMessage("1", "https://example.com"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 埋め込みの展開は本文も投稿者も含まない
+	partial := &discordgo.Message{ID: "1", ChannelID: "channel", Embeds: []*discordgo.MessageEmbed{{Title: "Example"}}}
+	if err := c.write(opEntry(opUpdate, partial)); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := c.get("1")
+	if m.Content != "https://example.com" || m.Author == nil {
+		t.Errorf("partial update dropped fields: content=%q author=%v", m.Content, m.Author)
+	}
+	if len(m.Embeds) != 1 || m.Embeds[0].Title != "Example" {
+		t.Errorf("embeds = %+v", m.Embeds)
+	}
+	if len(m.Revisions) != 0 {
+		t.Errorf("revisions = %+v, want none", m.Revisions)
+	}
+
+	// 未保存のメッセージの部分的な更新は無視し、内容が揃っていれば新規として扱う
+	if err := c.write(opEntry(opUpdate, &discordgo.Message{ID: "2", ChannelID: "channel"}), opEntry(opUpdate, testMessage("3", "late"))); err != nil {
+		t.Fatal(err)
+	}
+	if c.has("2") {
+		t.Error("partial update created message 2")
+	}
+	if m, ok := c.get("3"); !ok || m.Content != "late" {
+		t.Errorf("update of unknown message was not stored: %+v", m)
+	}
+}
+
+func TestApplyDelete(t *testing.T) {
+	c := newTestLog(t)
+	if err := c.write(opEntry(opCreate, testMessage("1", "a")), opEntry(opCreate, testMessage("2", "b"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.write(deleteEntry("1"), deleteEntry("404")); err != nil {
+		t.Fatal(err)
+	}
+	m, ok := c.get("1")
+	if !ok || m.DeletedAt == nil {
+		t.Fatalf("deleted message = %+v, want DeletedAt set", m)
+	}
+	first := *m.DeletedAt
+
+	// 2回目の削除で削除日時は変わらない
+	if err := c.write(deleteEntry("1")); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := c.get("1"); !m.DeletedAt.Equal(first) {
+		t.Errorf("DeletedAt changed from %v to %v", first, m.DeletedAt)
+	}
+	if got, want := listIDs(c), []string{"2"}; !equalIDs(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if c.has("404") {
+		t.Error("delete created an unknown message")
+	}
+}
+
+func TestLoadChannelReplaysLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "channel.jsonl")
+	c, err := loadChannel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.write(opEntry(opCreate, testMessage("2", "b")), opEntry(opCreate, testMessage("1", "a"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.write(opEntry(opUpdate, testMessage("1", "a2")), deleteEntry("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadChannel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listIDs(loaded), []string{"1"}; !equalIDs(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if m, _ := loaded.get("1"); m.Content != "a2" || len(m.Revisions) != 1 {
+		t.Errorf("message 1 = %q %+v", m.Content, m.Revisions)
+	}
+	if m, _ := loaded.get("2"); m.DeletedAt == nil {
+		t.Error("message 2 is not deleted after reload")
+	}
+	if loaded.entries != 4 {
+		t.Errorf("entries = %d, want 4", loaded.entries)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "channel.jsonl")
+	c, err := loadChannel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.write(opEntry(opCreate, testMessage("1", "edit 0")), opEntry(opCreate, testMessage("2", "gone"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.write(deleteEntry("2")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= compactMinEntries; i++ {
+		if err := c.write(opEntry(opUpdate, testMessage("1", "edit "+strconv.Itoa(i)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 書き直した後はメッセージごとに1行だけ残る
+	if n := lineCount(t, path); n > compactMinEntries {
+		t.Errorf("log has %d lines after compaction", n)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file was left: %v", err)
+	}
+
+	loaded, err := loadChannel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := loaded.get("1")
+	if !ok {
+		t.Fatal("message 1 was lost")
+	}
+	if m.Content != "edit "+strconv.Itoa(compactMinEntries) || len(m.Revisions) != compactMinEntries || m.Revisions[0].Content != "edit 0" {
+		t.Errorf("message 1 = %q with %d revisions", m.Content, len(m.Revisions))
+	}
+	if m, ok := loaded.get("2"); !ok || m.DeletedAt == nil || m.Content != "gone" {
+		t.Errorf("deleted message was not kept: %+v", m)
+	}
+	if got, want := listIDs(loaded), []string{"1"}; !equalIDs(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+
+	// 書き直した後のログにも追記できる
+	if err := loaded.write(opEntry(opCreate, testMessage("3", "c"))); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loadChannel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listIDs(reloaded), []string{"1", "3"}; !equalIDs(got, want) {
+		t.Errorf("list after append = %v, want %v", got, want)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
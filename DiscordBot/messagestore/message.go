@@ -0,0 +1,53 @@
+package messagestore
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 保存しているメッセージ
+type Message struct {
+	*discordgo.Message
+	Revisions []Revision `json:"revisions,omitempty"` // 編集前の本文（古い順）
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// 編集される前の本文
+type Revision struct {
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"` // この本文が書き換えられた日時
+}
+
+// ログに追記する操作
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	// 書き直したログの1件（履歴と削除日時を含むメッセージの状態）
+	opSnapshot = "snapshot"
+)
+
+// チャンネルごとのログファイルの1行
+type entry struct {
+	Op      string             `json:"op"`
+	At      time.Time          `json:"at"`
+	ID      string             `json:"id,omitempty"`
+	Message *discordgo.Message `json:"message,omitempty"`
+
+	// opSnapshotだけで使う
+	Revisions []Revision `json:"revisions,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// スノーフレークIDを比較する（桁数が少ないほど古い）
+func IDLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
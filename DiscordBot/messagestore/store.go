@@ -0,0 +1,338 @@
+package messagestore
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"main/crawler"
+	"main/storage"
+)
+
+var ErrNotFound = errors.New("メッセージが見つかりません")
+
+// 起動後に取り直して編集・削除を確かめる直近のメッセージの件数（REST APIの1ページ分）
+const reconcileWindow = 100
+
+// この時間使われなかったチャンネルはメモリから取り除く（ログファイルは残る）
+const channelIdleTimeout = 30 * time.Minute
+
+// チャンネルごとの取り込み状況
+type channelState struct {
+	BackfilledAt time.Time `json:"backfilled_at"`
+
+	reconciled bool // 起動後に直近のメッセージを取り直したか（保存しない）
+}
+
+// ゲートウェイのイベントからメッセージを保存し、編集・削除の履歴を残す
+// 初回だけREST APIで過去のメッセージを取り込み（バックフィル）、以降はイベントで最新に保つ
+// チャンネルごとのログは読み出すときにすべてメモリに読み込み、しばらく使われなければ手放す。
+// イベントは読み込まれていないチャンネルならファイルに追記するだけにし、全体を読み込まない。
+// ログは操作を追記していき、行数がメッセージ数に比べて増えすぎたら現在の状態だけに書き直す
+type Store struct {
+	dir         string
+	stateFile   *storage.JSONFile
+	checkpoints *crawler.Checkpoints
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	channels map[string]*openChannel
+	state    map[string]*channelState
+}
+
+// メモリに読み込んだチャンネル（mu で保護する）
+type openChannel struct {
+	log      *channelLog
+	users    int // 使用中の呼び出しの数（0でなければ取り除かない）
+	lastUsed time.Time
+}
+
+// 取り込み状況を読み込んでStoreを返す
+func NewStore(dir string) (*Store, error) {
+	st := &Store{
+		dir:         dir,
+		stateFile:   storage.NewJSONFile(dir, "channels.json"),
+		idleTimeout: channelIdleTimeout,
+		channels:    make(map[string]*openChannel),
+		state:       make(map[string]*channelState),
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := st.stateFile.Load(&st.state); err != nil {
+		return nil, err
+	}
+	checkpoints, err := crawler.NewCheckpoints(dir)
+	if err != nil {
+		return nil, err
+	}
+	st.checkpoints = checkpoints
+	return st, nil
+}
+
+func (st *Store) path(channelID string) string {
+	return filepath.Join(st.dir, filepath.Base(channelID)+".jsonl")
+}
+
+// チャンネルのログを返す（読み込まれていなければファイルから読み込む）
+// 使い終わったら release を呼ぶ
+func (st *Store) acquire(channelID string) (*channelLog, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.evictIdle()
+	oc, ok := st.channels[channelID]
+	if !ok {
+		c, err := loadChannel(st.path(channelID))
+		if err != nil {
+			return nil, err
+		}
+		oc = &openChannel{log: c}
+		st.channels[channelID] = oc
+	}
+	oc.users++
+	oc.lastUsed = time.Now()
+	return oc.log, nil
+}
+
+func (st *Store) release(channelID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if oc, ok := st.channels[channelID]; ok {
+		oc.users--
+		oc.lastUsed = time.Now()
+	}
+}
+
+// 使われていない時間が長いチャンネルをメモリから取り除く（呼び出し側でロックを取る）
+func (st *Store) evictIdle() {
+	for id, oc := range st.channels {
+		if oc.users == 0 && time.Since(oc.lastUsed) > st.idleTimeout {
+			delete(st.channels, id)
+		}
+	}
+}
+
+// 操作を書き込む
+// 読み込まれているチャンネルはメモリ上の状態にも反映し、そうでなければファイルに追記するだけにする
+func (st *Store) record(channelID string, entries ...*entry) {
+	if len(entries) == 0 {
+		return
+	}
+	err := st.appendUnloaded(channelID, entries)
+	if errors.Is(err, errChannelLoaded) {
+		var c *channelLog
+		if c, err = st.acquire(channelID); err == nil {
+			err = c.write(entries...)
+			st.release(channelID)
+		}
+	}
+	if err != nil {
+		log.Printf("メッセージの保存に失敗しました (%s): %v\n", channelID, err)
+	}
+}
+
+var errChannelLoaded = errors.New("チャンネルは読み込まれています")
+
+// 読み込まれていないチャンネルのログに追記する
+// 追記の間はロックを持ち、読み込みと重ならないようにする
+func (st *Store) appendUnloaded(channelID string, entries []*entry) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.channels[channelID]; ok {
+		return errChannelLoaded
+	}
+	return appendEntries(st.path(channelID), entries)
+}
+
+// 投稿されたメッセージを保存する
+func (st *Store) OnMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		return
+	}
+	e := newEntry(opCreate)
+	e.Message = m.Message
+	st.record(m.ChannelID, e)
+}
+
+// 編集されたメッセージを保存する（編集前の本文は履歴に残る）
+func (st *Store) OnMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m.GuildID == "" {
+		return
+	}
+	e := newEntry(opUpdate)
+	e.Message = m.Message
+	st.record(m.ChannelID, e)
+}
+
+// 削除されたメッセージに削除日時を記録する
+func (st *Store) OnMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	if m.GuildID == "" {
+		return
+	}
+	e := newEntry(opDelete)
+	e.ID = m.ID
+	st.record(m.ChannelID, e)
+}
+
+// まとめて削除されたメッセージに削除日時を記録する
+func (st *Store) OnMessageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	if m.GuildID == "" {
+		return
+	}
+	entries := make([]*entry, 0, len(m.Messages))
+	for _, id := range m.Messages {
+		e := newEntry(opDelete)
+		e.ID = id
+		entries = append(entries, e)
+	}
+	st.record(m.ChannelID, entries...)
+}
+
+// チャンネルを最新の状態にする
+// 初回は全履歴を取り込み、2回目以降はBotが停止していた間の新しいメッセージを取得する
+// 停止中の編集・削除は直近 reconcileWindow 件の範囲でだけ反映される
+func (st *Store) Sync(s *discordgo.Session, channelID string) error {
+	c, err := st.acquire(channelID)
+	if err != nil {
+		return err
+	}
+	defer st.release(channelID)
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	st.mu.Lock()
+	cs, backfilled := st.state[channelID]
+	st.mu.Unlock()
+
+	if backfilled {
+		return st.catchUp(s, c, channelID, cs)
+	}
+	if err := st.backfill(s, c, channelID); err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	// 取り込んだばかりなので、停止中の編集・削除を取り直す必要はない
+	st.state[channelID] = &channelState{BackfilledAt: time.Now(), reconciled: true}
+	state := make(map[string]*channelState, len(st.state))
+	for id, cs := range st.state {
+		state[id] = cs
+	}
+	st.mu.Unlock()
+	return st.stateFile.Save(state)
+}
+
+// 全履歴を古い順に取得し、未保存のものを書き込む
+// ページごとにチェックポイントを残すため、中断しても次回は続きから取り込む
+func (st *Store) backfill(s *discordgo.Session, c *channelLog, channelID string) error {
+	count := 0
+	err := st.checkpoints.Crawl(context.Background(), s, channelID, channelID, func(page []*discordgo.Message) error {
+		n, err := c.writeNew(page)
+		count += n
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("%d 件のメッセージを取り込みました (%s)\n", count, channelID)
+	return nil
+}
+
+// 保存済みの最新メッセージより後のメッセージを取得する
+// 起動して最初の同期では、停止していた間の編集・削除も直近のメッセージの範囲で反映する
+// reconciled はチャンネルの syncMu を持っている間だけ読み書きする
+func (st *Store) catchUp(s *discordgo.Session, c *channelLog, channelID string, cs *channelState) error {
+	err := crawler.Forward(context.Background(), s, channelID, c.newestID(), func(page []*discordgo.Message) error {
+		_, err := c.writeNew(page)
+		return err
+	})
+	if err != nil || cs.reconciled {
+		return err
+	}
+	if err := st.reconcile(s, c, channelID); err != nil {
+		return err
+	}
+	cs.reconciled = true
+	return nil
+}
+
+// 直近のメッセージを取り直して保存済みの内容と比べ、編集・削除を記録する
+// 停止中の編集・削除はイベントで受け取れないため、起動後に一度だけ行う。
+// 取り直すのは直近 reconcileWindow 件だけなので、それより古いメッセージの編集・削除は反映されない
+func (st *Store) reconcile(s *discordgo.Session, c *channelLog, channelID string) error {
+	recent, err := s.ChannelMessages(channelID, reconcileWindow, "", "", "")
+	if err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+
+	// 取り直した範囲（新しい順に返る）の中で、保存済みの内容と違うものを探す
+	newest, oldest := recent[0].ID, recent[len(recent)-1].ID
+	fetched := make(map[string]bool, len(recent))
+	var entries []*entry
+	for _, m := range recent {
+		fetched[m.ID] = true
+		if stored, ok := c.get(m.ID); ok && stored.DeletedAt == nil && stored.Content != m.Content {
+			e := newEntry(opUpdate)
+			e.Message = m
+			entries = append(entries, e)
+		}
+	}
+	for _, m := range c.list() {
+		if IDLess(m.ID, oldest) || IDLess(newest, m.ID) || fetched[m.ID] {
+			continue
+		}
+		e := newEntry(opDelete)
+		e.ID = m.ID
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	log.Printf("停止中の編集・削除 %d 件を反映しました (%s)\n", len(entries), channelID)
+	return c.write(entries...)
+}
+
+// 削除されていないメッセージを古い順に返す
+func (st *Store) Messages(channelID string) ([]*discordgo.Message, error) {
+	c, err := st.acquire(channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer st.release(channelID)
+	list := c.list()
+	messages := make([]*discordgo.Message, 0, len(list))
+	for _, m := range list {
+		messages = append(messages, m.Message)
+	}
+	return messages, nil
+}
+
+// 編集・削除の履歴を含めてメッセージを返す
+func (st *Store) Get(channelID, messageID string) (*Message, error) {
+	c, err := st.acquire(channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer st.release(channelID)
+	m, ok := c.get(messageID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
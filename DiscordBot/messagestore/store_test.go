@@ -0,0 +1,193 @@
+package messagestore
+
+import (
+	"testing"
+
+	"main/internal/discordtest"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func storedContents(t *testing.T, st *Store, channelID string) map[string]string {
+	t.Helper()
+	messages, err := st.Messages(channelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string]string, len(messages))
+	for _, m := range messages {
+		contents[m.ID] = m.Content
+	}
+	return contents
+}
+
+func TestSyncBackfillsThenCatchesUp(t *testing.T) {
+	const channelID = "channel"
+	dir := t.TempDir()
+	f := discordtest.NewServer()
+	f.AddChannel(channelID, 150)
+	s := discordtest.NewSession(t, f)
+
+	st, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Sync(s, channelID); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(storedContents(t, st, channelID)); got != 150 {
+		t.Fatalf("backfilled %d messages, want 150", got)
+	}
+
+	// 停止中に新しい投稿、直近の編集・削除、古いメッセージの削除があった
+	f.Set(channelID, 151, "message 151")
+	f.Set(channelID, 152, "message 152")
+	f.Set(channelID, 120, "edited")
+	f.Remove(channelID, 140)
+	f.Remove(channelID, 10)
+
+	restarted, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Sync(s, channelID); err != nil {
+		t.Fatal(err)
+	}
+	contents := storedContents(t, restarted, channelID)
+	for _, n := range []int{151, 152} {
+		if _, ok := contents[discordtest.MessageID(n)]; !ok {
+			t.Errorf("message %d was not caught up", n)
+		}
+	}
+	if contents[discordtest.MessageID(120)] != "edited" {
+		t.Errorf("message 120 = %q, want edited", contents[discordtest.MessageID(120)])
+	}
+	m, err := restarted.Get(channelID, discordtest.MessageID(120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Revisions) != 1 || m.Revisions[0].Content != "message 120" {
+		t.Errorf("revisions = %+v", m.Revisions)
+	}
+	if _, ok := contents[discordtest.MessageID(140)]; ok {
+		t.Error("message 140 deleted while stopped is still listed")
+	}
+	if m, err := restarted.Get(channelID, discordtest.MessageID(140)); err != nil || m.DeletedAt == nil {
+		t.Errorf("message 140 = %+v, %v; want DeletedAt set", m, err)
+	}
+	// 直近 reconcileWindow 件より古い削除は反映されない
+	if _, ok := contents[discordtest.MessageID(10)]; !ok {
+		t.Error("message 10 outside the reconcile window was marked deleted")
+	}
+	if _, err := restarted.Get(channelID, "404"); err != ErrNotFound {
+		t.Errorf("Get(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReconcileRunsOncePerStart(t *testing.T) {
+	const channelID = "channel"
+	f := discordtest.NewServer()
+	f.AddChannel(channelID, 5)
+	s := discordtest.NewSession(t, f)
+
+	st, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := st.Sync(s, channelID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 起動後の最初の同期だけが編集を取り直す。以降の編集はイベントで受け取る
+	f.Set(channelID, 3, "edited later")
+	f.ResetRequests()
+	if err := st.Sync(s, channelID); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedContents(t, st, channelID)[discordtest.MessageID(3)]; got != "message 3" {
+		t.Errorf("message 3 = %q, want the stored content", got)
+	}
+	if requests := len(f.Requests()); requests != 1 {
+		t.Errorf("requests = %d, want 1 (forward only)", requests)
+	}
+}
+
+func TestEventsIgnoreDirectMessages(t *testing.T) {
+	st, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.OnMessageCreate(nil, &discordgo.MessageCreate{Message: &discordgo.Message{ID: "1", ChannelID: "dm", Content: "secret", Author: &discordgo.User{ID: "user"}}})
+	st.OnMessageCreate(nil, &discordgo.MessageCreate{Message: &discordgo.Message{ID: "2", ChannelID: "guild", GuildID: "g", Content: "hello", Author: &discordgo.User{ID: "user"}}})
+	st.OnMessageDeleteBulk(nil, &discordgo.MessageDeleteBulk{ChannelID: "guild", GuildID: "g", Messages: []string{"2"}})
+
+	if got := storedContents(t, st, "dm"); len(got) != 0 {
+		t.Errorf("direct message was stored: %v", got)
+	}
+	m, err := st.Get("guild", "2")
+	if err != nil || m.DeletedAt == nil {
+		t.Errorf("bulk delete was not recorded: %+v, %v", m, err)
+	}
+}
+
+func TestEventsDoNotLoadChannels(t *testing.T) {
+	st, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.OnMessageCreate(nil, &discordgo.MessageCreate{Message: &discordgo.Message{ID: "1", ChannelID: "c", GuildID: "g", Content: "hello", Author: &discordgo.User{ID: "user"}}})
+	st.OnMessageUpdate(nil, &discordgo.MessageUpdate{Message: &discordgo.Message{ID: "1", ChannelID: "c", GuildID: "g", Content: "edited", Author: &discordgo.User{ID: "user"}}})
+
+	st.mu.Lock()
+	loaded := len(st.channels)
+	st.mu.Unlock()
+	if loaded != 0 {
+		t.Fatalf("%d channels loaded by events, want 0", loaded)
+	}
+
+	// 読み出すときにはイベントで追記した内容が反映されている
+	m, err := st.Get("c", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "edited" || len(m.Revisions) != 1 {
+		t.Errorf("got %q with %d revisions, want edited with 1", m.Content, len(m.Revisions))
+	}
+
+	// 読み込まれたチャンネルへのイベントはメモリ上にも反映する
+	st.OnMessageDelete(nil, &discordgo.MessageDelete{Message: &discordgo.Message{ID: "1", ChannelID: "c", GuildID: "g"}})
+	if got := storedContents(t, st, "c"); len(got) != 0 {
+		t.Errorf("deleted message is still listed: %v", got)
+	}
+}
+
+func TestIdleChannelsAreEvicted(t *testing.T) {
+	st, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.idleTimeout = 0
+	st.OnMessageCreate(nil, &discordgo.MessageCreate{Message: &discordgo.Message{ID: "1", ChannelID: "a", GuildID: "g", Content: "a", Author: &discordgo.User{ID: "user"}}})
+	if _, err := st.Messages("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Messages("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	st.mu.Lock()
+	_, loaded := st.channels["a"]
+	st.mu.Unlock()
+	if loaded {
+		t.Error("idle channel a is still loaded")
+	}
+	if got := storedContents(t, st, "a"); got["1"] != "a" {
+		t.Errorf("reloaded channel a = %v", got)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,17 @@
+package model
+
+type AnnounceRequest struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Sound     string `json:"sound"` // SOUNDS_DIR内のファイル名
+}
+
+type AnnounceResponse struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,26 @@
+package model
+
+type MeetingResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ChannelID string `json:"channel_id"`
+	StartedAt int64  `json:"started_at"`
+	EndedAt   int64  `json:"ended_at,omitempty"`
+}
+
+type AttendeeResponse struct {
+	UserID          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	FirstJoin       int64   `json:"first_join"`
+	LastLeave       int64   `json:"last_leave"`
+	DurationMinutes float64 `json:"duration_minutes"`
+}
+
+type AttendanceResponse struct {
+	Meeting   MeetingResponse    `json:"meeting"`
+	Attendees []AttendeeResponse `json:"attendees"`
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
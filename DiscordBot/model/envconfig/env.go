@@ -9,6 +9,26 @@ import (
 type Env struct {
 	TOKEN      string
 	ServerPort string
+	SoundsDir  string
+	DataDir    string
+	APIToken   string // 管理用のHTTPエンドポイントの認証に使うトークン
+
+	TranscriptChannelID string
+	LogChannelID        string
+
+	TTSEngine   string
+	VoicevoxURL string
+	TTSCommand  string
+
+	SummaryBackend string
+	FastAPIURL     string
+	OpenAIBaseURL  string
+	OpenAIAPIKey   string
+	OpenAIModel    string
+
+	UsagePrices string // モデルごとの料金（usage.ParsePrices の書式）
+
+	CommissionURL string
 }
 
 func NewEnv() (*Env, error) {
@@ -17,10 +37,42 @@ func NewEnv() (*Env, error) {
 		return nil, err
 	}
 
+	return FromOS(), nil
+}
+
+// 環境変数から設定を読み込む（.envが無い環境向け）
+func FromOS() *Env {
 	return &Env{
 		TOKEN:      os.Getenv("TOKEN"),
 		ServerPort: os.Getenv("PORT"),
-	}, nil
+		SoundsDir:  getenvDefault("SOUNDS_DIR", "sounds"),
+		DataDir:    getenvDefault("DATA_DIR", "data"),
+		APIToken:   os.Getenv("API_TOKEN"),
+
+		TranscriptChannelID: os.Getenv("TRANSCRIPT_CHANNEL_ID"),
+		LogChannelID:        os.Getenv("LOG_CHANNEL_ID"),
+
+		TTSEngine:   getenvDefault("TTS_ENGINE", "voicevox"),
+		VoicevoxURL: getenvDefault("VOICEVOX_URL", "http://localhost:50021"),
+		TTSCommand:  os.Getenv("TTS_COMMAND"),
+
+		SummaryBackend: getenvDefault("SUMMARY_BACKEND", "fastapi"),
+		FastAPIURL:     os.Getenv("FASTAPI_URL"),
+		OpenAIBaseURL:  os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:   os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:    os.Getenv("OPENAI_MODEL"),
+
+		UsagePrices: os.Getenv("USAGE_PRICES"),
+
+		CommissionURL: getenvDefault("COMMISSION_API_URL", "http://localhost:3000/api/submit/"),
+	}
+}
+
+func getenvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
 }
 
 /* Copyright (c) 2025 古川幸樹　*/
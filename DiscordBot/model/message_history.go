@@ -0,0 +1,22 @@
+package model
+
+type MessageRevision struct {
+	Content  string `json:"content"`
+	EditedAt int64  `json:"edited_at"` // ミリ秒
+}
+
+type MessageHistoryResponse struct {
+	ID        string            `json:"id"`
+	ChannelID string            `json:"channel_id"`
+	AuthorID  string            `json:"author_id"`
+	Author    string            `json:"author"`
+	Content   string            `json:"content"`
+	CreatedAt int64             `json:"created_at"`
+	Deleted   bool              `json:"deleted"`
+	DeletedAt int64             `json:"deleted_at,omitempty"`
+	Revisions []MessageRevision `json:"revisions"`
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,117 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	ErrAlreadyRecording = errors.New("このサーバーではすでに録音中です")
+	ErrNotRecording     = errors.New("録音していません")
+	ErrBusyElsewhere    = errors.New("このサーバーでは別のボイスチャンネルで録音中です")
+)
+
+// ギルドごとの録音セッションを管理する
+// 複数のギルドで同時に録音でき、セッションは互いに独立している
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// Managerを返す
+func NewManager() *Manager {
+	return &Manager{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// 録音セッションを開始する
+// 返したコンテキストは停止を要求されるか、最大録音時間を過ぎるとキャンセルされる
+func (m *Manager) Start(guildID, channelID string, maxDuration time.Duration) (*Session, context.Context, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[guildID]; ok {
+		return nil, nil, ErrAlreadyRecording
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxDuration)
+	sess := newSession(guildID, channelID, cancel)
+	m.sessions[guildID] = sess
+	return sess, ctx, nil
+}
+
+// ギルドの録音セッションを返す（録音していなければnil）
+func (m *Manager) Get(guildID string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[guildID]
+}
+
+// 別のチャンネルで録音中でないか確かめる（接続を移すと録音が止まるため）
+// channelIDが空なら接続中のチャンネルを使うので、録音の妨げにならない
+func (m *Manager) CheckChannel(guildID, channelID string) error {
+	sess := m.Get(guildID)
+	if sess == nil || channelID == "" || channelID == sess.ChannelID {
+		return nil
+	}
+	return ErrBusyElsewhere
+}
+
+// 録音の停止を要求する
+func (m *Manager) Stop(guildID string) (*Session, error) {
+	sess := m.Get(guildID)
+	if sess == nil {
+		return nil, ErrNotRecording
+	}
+	sess.stop()
+	return sess, nil
+}
+
+// 録音の停止を要求し、音声の受信が終わるまで待つ
+// 録音していない場合はすぐに戻る
+func (m *Manager) StopAndWait(guildID string, timeout time.Duration) error {
+	sess, err := m.Stop(guildID)
+	if err != nil {
+		return nil
+	}
+	select {
+	case <-sess.Stopped():
+		return nil
+	case <-time.After(timeout):
+		return errors.New("録音の停止がタイムアウトしました")
+	}
+}
+
+// セッションを完了し、ギルドで次の録音を開始できるようにする
+func (m *Manager) Finish(sess *Session) {
+	sess.SetState(StateDone)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[sess.GuildID] == sess {
+		delete(m.sessions, sess.GuildID)
+	}
+}
+
+// Botがボイスチャンネルから切断・移動させられたら録音を停止する
+func (m *Manager) OnVoiceStateUpdate(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
+	if s.State.User == nil || vs.UserID != s.State.User.ID {
+		return
+	}
+	sess := m.Get(vs.GuildID)
+	if sess == nil || sess.State() == StateStarting {
+		return
+	}
+	if vs.ChannelID != sess.ChannelID {
+		sess.stop()
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,305 @@
+package recording
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Botとしてログインした状態のセッション（OnVoiceStateUpdate用）
+func botSession() *discordgo.Session {
+	s := &discordgo.Session{State: discordgo.NewState()}
+	s.State.User = &discordgo.User{ID: "bot"}
+	return s
+}
+
+// recordVoiceと同じ順に状態を進め、停止を要求されるまで録音する
+func runRecording(m *Manager, guildID, channelID string) error {
+	sess, ctx, err := m.Start(guildID, channelID, time.Minute)
+	if err != nil {
+		return err
+	}
+	defer m.Finish(sess)
+	sess.SetState(StateRecording)
+	<-ctx.Done()
+	sess.SetState(StateTranscribing)
+	return nil
+}
+
+func TestStartRejectsSecondSessionInGuild(t *testing.T) {
+	m := NewManager()
+	sess, _, err := m.Start("g1", "c1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Start("g1", "c2", time.Minute); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("同じギルドでの2つ目の録音: err = %v, want ErrAlreadyRecording", err)
+	}
+	if _, _, err := m.Start("g2", "c3", time.Minute); err != nil {
+		t.Errorf("別のギルドの録音: err = %v", err)
+	}
+
+	m.Finish(sess)
+	if _, _, err := m.Start("g1", "c1", time.Minute); err != nil {
+		t.Errorf("完了後の録音: err = %v", err)
+	}
+}
+
+func TestStateTransitions(t *testing.T) {
+	m := NewManager()
+	sess, ctx, err := m.Start("g", "c", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sess.State(); got != StateStarting {
+		t.Fatalf("開始直後の状態 = %v", got)
+	}
+	sess.SetState(StateRecording)
+
+	if _, err := m.Stop("g"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sess.State(); got != StateStopping {
+		t.Errorf("停止要求後の状態 = %v, want stopping", got)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("停止要求でコンテキストがキャンセルされていません")
+	}
+
+	// 状態は戻らない
+	sess.SetState(StateRecording)
+	if got := sess.State(); got != StateStopping {
+		t.Errorf("状態が戻りました: %v", got)
+	}
+
+	sess.SetState(StateTranscribing)
+	select {
+	case <-sess.Stopped():
+	default:
+		t.Error("書き起こしに進んでもStoppedが閉じていません")
+	}
+	m.Finish(sess)
+	select {
+	case <-sess.Done():
+	default:
+		t.Error("完了してもDoneが閉じていません")
+	}
+	if m.Get("g") != nil {
+		t.Error("完了したセッションが残っています")
+	}
+	if _, err := m.Stop("g"); !errors.Is(err, ErrNotRecording) {
+		t.Errorf("完了後の停止: err = %v, want ErrNotRecording", err)
+	}
+}
+
+func TestStopAndWait(t *testing.T) {
+	m := NewManager()
+	if err := m.StopAndWait("g", time.Second); err != nil {
+		t.Errorf("録音していないときの停止: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- runRecording(m, "g", "c") }()
+	waitForState(t, m, "g", StateRecording)
+
+	if err := m.StopAndWait("g", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// 受信が終わらなければタイムアウトする
+	sess, _, err := m.Start("g", "c", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.StopAndWait("g", 20*time.Millisecond); err == nil {
+		t.Error("受信が終わっていないのにタイムアウトしません")
+	}
+	m.Finish(sess)
+}
+
+func TestDisconnectStopsRecording(t *testing.T) {
+	s := botSession()
+	m := NewManager()
+	sess, ctx, err := m.Start("g", "c", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Finish(sess)
+
+	// 接続中（Starting）の移動は録音を止めない
+	m.OnVoiceStateUpdate(s, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{UserID: "bot", GuildID: "g", ChannelID: "other"}})
+	if ctx.Err() != nil {
+		t.Fatal("接続中の移動で録音が止まりました")
+	}
+
+	sess.SetState(StateRecording)
+	// 他のユーザーや同じチャンネルの更新は無視する
+	m.OnVoiceStateUpdate(s, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{UserID: "someone", GuildID: "g", ChannelID: ""}})
+	m.OnVoiceStateUpdate(s, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{UserID: "bot", GuildID: "g", ChannelID: "c"}})
+	if ctx.Err() != nil {
+		t.Fatal("関係の無い更新で録音が止まりました")
+	}
+
+	// Botの切断で録音を止める
+	m.OnVoiceStateUpdate(s, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{UserID: "bot", GuildID: "g", ChannelID: ""}})
+	if ctx.Err() == nil {
+		t.Error("切断しても録音が止まりません")
+	}
+	if got := sess.State(); got != StateStopping {
+		t.Errorf("切断後の状態 = %v, want stopping", got)
+	}
+}
+
+func TestCheckChannel(t *testing.T) {
+	m := NewManager()
+	if err := m.CheckChannel("g", "c2"); err != nil {
+		t.Errorf("録音していないとき: %v", err)
+	}
+	sess, _, err := m.Start("g", "c1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Finish(sess)
+	for _, channelID := range []string{"", "c1"} {
+		if err := m.CheckChannel("g", channelID); err != nil {
+			t.Errorf("CheckChannel(%q) = %v", channelID, err)
+		}
+	}
+	if err := m.CheckChannel("g", "c2"); !errors.Is(err, ErrBusyElsewhere) {
+		t.Errorf("別のチャンネル: err = %v, want ErrBusyElsewhere", err)
+	}
+}
+
+// 録音の開始・停止・切断を同時に繰り返しても、ギルドごとに1つのセッションしか無いこと
+// go test -race で実行する
+func TestConcurrentStartStopDisconnect(t *testing.T) {
+	s := botSession()
+	m := NewManager()
+	guilds := []string{"g1", "g2", "g3"}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		started = make(map[string]int)
+		active  = make(map[string]int)
+		maxSeen = make(map[string]int)
+	)
+	const rounds = 50
+	for _, guildID := range guilds {
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(guildID string) {
+				defer wg.Done()
+				for n := 0; n < rounds; n++ {
+					sess, ctx, err := m.Start(guildID, "c", time.Second)
+					if errors.Is(err, ErrAlreadyRecording) {
+						time.Sleep(time.Microsecond)
+						continue
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					started[guildID]++
+					active[guildID]++
+					if active[guildID] > maxSeen[guildID] {
+						maxSeen[guildID] = active[guildID]
+					}
+					mu.Unlock()
+
+					sess.SetState(StateRecording)
+					<-ctx.Done()
+					sess.SetState(StateTranscribing)
+
+					mu.Lock()
+					active[guildID]--
+					mu.Unlock()
+					m.Finish(sess)
+				}
+			}(guildID)
+		}
+
+		// 停止・停止待ち・切断を並行して送り続ける
+		wg.Add(3)
+		go func(guildID string) {
+			defer wg.Done()
+			for n := 0; n < rounds*4; n++ {
+				m.Stop(guildID)
+				time.Sleep(time.Microsecond)
+			}
+		}(guildID)
+		go func(guildID string) {
+			defer wg.Done()
+			for n := 0; n < rounds; n++ {
+				m.StopAndWait(guildID, 100*time.Millisecond)
+			}
+		}(guildID)
+		go func(guildID string) {
+			defer wg.Done()
+			for n := 0; n < rounds*4; n++ {
+				m.OnVoiceStateUpdate(s, &discordgo.VoiceStateUpdate{VoiceState: &discordgo.VoiceState{UserID: "bot", GuildID: guildID, ChannelID: ""}})
+				if sess := m.Get(guildID); sess != nil {
+					sess.State()
+				}
+				time.Sleep(time.Microsecond)
+			}
+		}(guildID)
+	}
+
+	// 取り残されたセッションは停止を要求し続けて終わらせる
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case <-finished:
+			for _, guildID := range guilds {
+				if started[guildID] == 0 {
+					t.Errorf("%s で一度も録音が始まりませんでした", guildID)
+				}
+				if maxSeen[guildID] > 1 {
+					t.Errorf("%s で同時に %d 個のセッションがありました", guildID, maxSeen[guildID])
+				}
+				if m.Get(guildID) != nil {
+					t.Errorf("%s のセッションが残っています", guildID)
+				}
+			}
+			return
+		case <-timeout:
+			t.Fatal("録音が終わりません")
+		case <-time.After(5 * time.Millisecond):
+			for _, guildID := range guilds {
+				m.Stop(guildID)
+			}
+		}
+	}
+}
+
+// セッションが指定した状態になるまで待つ
+func waitForState(t *testing.T, m *Manager, guildID string, state State) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if sess := m.Get(guildID); sess != nil && sess.State() == state {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s の状態が %v になりません", guildID, state)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,111 @@
+package recording
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 録音セッションの状態
+type State int
+
+const (
+	StateStarting     State = iota // ボイスチャンネルに接続中
+	StateRecording                 // 録音中
+	StateStopping                  // 停止を要求された
+	StateTranscribing              // 録音を終えて書き起こし中
+	StateDone                      // 完了
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateRecording:
+		return "recording"
+	case StateStopping:
+		return "stopping"
+	case StateTranscribing:
+		return "transcribing"
+	case StateDone:
+		return "done"
+	}
+	return "unknown"
+}
+
+// ギルドごとの録音セッション
+type Session struct {
+	GuildID   string
+	ChannelID string
+	StartedAt time.Time
+
+	mu      sync.Mutex
+	state   State
+	cancel  context.CancelFunc
+	stopped chan struct{} // 録音（音声の受信）が終わると閉じる
+	done    chan struct{} // セッションが完了すると閉じる
+}
+
+func newSession(guildID, channelID string, cancel context.CancelFunc) *Session {
+	return &Session{
+		GuildID:   guildID,
+		ChannelID: channelID,
+		StartedAt: time.Now(),
+		state:     StateStarting,
+		cancel:    cancel,
+		stopped:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// 現在の状態を返す
+func (s *Session) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// 状態を進める
+// 状態は前にしか進まず、戻そうとした場合は何もしない
+func (s *Session) SetState(next State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next <= s.state {
+		return
+	}
+	prev := s.state
+	s.state = next
+
+	if prev < StateTranscribing && next >= StateTranscribing {
+		close(s.stopped)
+	}
+	if next == StateDone {
+		s.cancel()
+		close(s.done)
+	}
+}
+
+// 録音の停止を要求する
+func (s *Session) stop() {
+	s.mu.Lock()
+	if s.state < StateStopping {
+		s.state = StateStopping
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// 音声の受信が終わると閉じるチャンネルを返す
+func (s *Session) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// セッションが完了すると閉じるチャンネルを返す
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
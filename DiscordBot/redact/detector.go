@@ -0,0 +1,116 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 個人情報を検出する規則
+type Detector struct {
+	Name    string // 置き換え後に表示する種類名
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool // 誤検出を除く追加の判定（nilなら常に有効）
+}
+
+const (
+	digit  = `[0-9０-９]`
+	hyphen = `[-－‐−ー]`
+	// 電話番号は空白で区切って書かれることもある
+	separator = `[ 　\-－‐−ー]`
+)
+
+// 組み込みの検出規則（上から順に適用する）
+var builtinDetectors = []*Detector{
+	{
+		Name:    "メールアドレス",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		Name:    "マイナンバー",
+		Pattern: regexp.MustCompile(digit + `{4}[ 　\-－]?` + digit + `{4}[ 　\-－]?` + digit + `{4}`),
+		Valid:   validMyNumber,
+	},
+	{
+		Name:    "住所",
+		Pattern: regexp.MustCompile(`(?:北海道|東京都|京都府|大阪府|\p{Han}{2,3}県)[\p{Han}\p{Hiragana}\p{Katakana}ー]{1,20}?` + digit + `+(?:(?:丁目|番地|番|号|の|` + hyphen + `)` + digit + `+)*(?:号|番地)?`),
+	},
+	{
+		Name:    "電話番号",
+		Pattern: regexp.MustCompile(`(?:\+81[ \-]?|[0０])` + digit + `{1,4}` + separator + `?` + digit + `{1,4}` + separator + `?` + digit + `{3,4}`),
+		Valid:   validPhone,
+	},
+	{
+		Name:    "郵便番号",
+		Pattern: regexp.MustCompile(`〒\s*` + digit + `{3}` + hyphen + `?` + digit + `{4}|` + digit + `{3}` + hyphen + digit + `{4}`),
+		Valid:   func(m string) bool { return countDigits(m) == 7 },
+	},
+}
+
+// 組み込みの検出規則の名前を返す
+func BuiltinNames() []string {
+	var names []string
+	for _, d := range builtinDetectors {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// 全角数字を含む文字列から数字だけを取り出す
+func digitsOf(s string) []int {
+	var digits []int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, int(r-'0'))
+		case r >= '０' && r <= '９':
+			digits = append(digits, int(r-'０'))
+		}
+	}
+	return digits
+}
+
+// 日本の電話番号は国内表記で10桁または11桁
+func validPhone(m string) bool {
+	n := countDigits(m)
+	if strings.HasPrefix(m, "+81") {
+		n = n - 2 + 1 // 国番号を除き、先頭の0を補う
+	}
+	return n == 10 || n == 11
+}
+
+// マイナンバーの検査用数字を確かめる
+func validMyNumber(m string) bool {
+	d := digitsOf(m)
+	if len(d) != 12 {
+		return false
+	}
+	sum := 0
+	for n := 1; n <= 11; n++ {
+		p := d[11-n]
+		q := n + 1
+		if n >= 7 {
+			q = n - 5
+		}
+		sum += p * q
+	}
+	check := 0
+	if r := sum % 11; r > 1 {
+		check = 11 - r
+	}
+	return d[11] == check
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,82 @@
+package redact
+
+import "testing"
+
+func TestBuiltinDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want string
+	}{
+		{"メールアドレス", "連絡先は taro.yamada+work@example.co.jp です", "連絡先は [メールアドレス#1] です"},
+		{"メールアドレスでない@", "@everyone 明日です", "@everyone 明日です"},
+		{"マイナンバー", "番号は123456789018です", "番号は[マイナンバー#1]です"},
+		{"マイナンバー（区切りあり）", "1234-5678-9018", "[マイナンバー#1]"},
+		{"マイナンバー（全角）", "１２３４５６７８９０１８", "[マイナンバー#1]"},
+		{"検査用数字が合わない12桁", "注文番号 123456789011", "注文番号 123456789011"},
+		{"長い数字の一部の12桁", "1234567890180", "1234567890180"},
+		{"住所", "東京都千代田区千代田1-1に集合", "[住所#1]に集合"},
+		{"住所（丁目・番地）", "大阪府大阪市北区梅田3丁目1番地", "[住所#1]"},
+		{"住所（県）", "神奈川県横浜市中区1-2-3", "[住所#1]"},
+		{"番地の無い地名", "東京都に行きます", "東京都に行きます"},
+		{"携帯電話", "090-1234-5678 まで", "[電話番号#1] まで"},
+		{"固定電話", "03-1234-5678", "[電話番号#1]"},
+		{"電話番号（区切りなし）", "09012345678", "[電話番号#1]"},
+		{"電話番号（空白区切り）", "090 1234 5678", "[電話番号#1]"},
+		{"電話番号（国番号）", "+81-90-1234-5678", "[電話番号#1]"},
+		{"電話番号（全角）", "０９０－１２３４－５６７８", "[電話番号#1]"},
+		{"桁数の足りない電話番号", "0120-123", "0120-123"},
+		{"長い数字の一部", "0901234567890", "0901234567890"},
+		{"郵便番号", "〒100-0001", "[郵便番号#1]"},
+		{"郵便番号（〒とハイフンなし）", "〒1000001", "[郵便番号#1]"},
+		{"郵便番号（ハイフンのみ）", "100-0001 です", "[郵便番号#1] です"},
+		{"日付は郵便番号ではない", "2025-04-10", "2025-04-10"},
+		{"個人情報なし", "明日の会議は10時からです", "明日の会議は10時からです"},
+	} {
+		e := testEngine(t)
+		if got := e.NewSession("guild", "test").Redact(tc.text); got != tc.want {
+			t.Errorf("%s: Redact(%q) = %q, want %q", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestValidMyNumber(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"正しい検査用数字", "123456789018", true},
+		{"余りが0か1なら検査用数字は0", "000000000000", true},
+		{"誤った検査用数字", "123456789012", false},
+		{"桁数が違う", "12345678901", false},
+		{"全角と区切り", "１２３４ ５６７８ ９０１８", true},
+	} {
+		if got := validMyNumber(tc.text); got != tc.want {
+			t.Errorf("%s: validMyNumber(%q) = %v, want %v", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestValidPhone(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"携帯電話", "090-1234-5678", true},
+		{"固定電話", "03-1234-5678", true},
+		{"9桁", "03-123-4567", false},
+		{"12桁", "090-12345-6789", false},
+		{"国番号付きの携帯電話", "+81 90 1234 5678", true},
+		{"国番号付きの固定電話", "+81-3-1234-5678", true},
+	} {
+		if got := validPhone(tc.text); got != tc.want {
+			t.Errorf("%s: validPhone(%q) = %v, want %v", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
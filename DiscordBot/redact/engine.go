@@ -0,0 +1,363 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"main/storage"
+)
+
+var (
+	ErrRuleExists   = errors.New("同じ名前のルールがすでにあります")
+	ErrRuleNotFound = errors.New("ルールが見つかりません")
+	ErrKeyNotFound  = errors.New("復元キーが見つかりません")
+	ErrNotOfficer   = errors.New("伏せ字を復元する権限がありません")
+)
+
+// 正規表現として受け付ける長さの上限
+const maxPatternLength = 200
+
+// ギルドごとに追加する検出ルール
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// ギルドごとの設定
+type guildSettings struct {
+	Rules         []Rule `json:"rules"`
+	OfficerRoleID string `json:"officer_role_id,omitempty"` // 伏せ字を復元できるロール
+}
+
+// 伏せ字にした元の値（復元キーごとに保存する）
+type Record struct {
+	Key       string            `json:"key"`
+	GuildID   string            `json:"guild_id"`
+	Source    string            `json:"source"` // crawling, summary, transcript
+	CreatedAt time.Time         `json:"created_at"`
+	Values    map[string]string `json:"values"` // 伏せ字 → 元の値
+}
+
+// 個人情報を伏せ字に置き換える
+// 元の値はデータディレクトリに保存し、担当者だけが復元キーで確認できる
+type Engine struct {
+	dir          string
+	settingsFile *storage.JSONFile
+
+	mu       sync.Mutex
+	settings map[string]*guildSettings
+	compiled map[string][]*Detector
+}
+
+// 保存済みの設定を読み込んでEngineを返す
+func NewEngine(dir string) (*Engine, error) {
+	e := &Engine{
+		dir:          dir,
+		settingsFile: storage.NewJSONFile(dir, "rules.json"),
+		settings:     make(map[string]*guildSettings),
+		compiled:     make(map[string][]*Detector),
+	}
+	if err := e.settingsFile.Load(&e.settings); err != nil {
+		return nil, err
+	}
+	for guildID, gs := range e.settings {
+		detectors, err := compileRules(gs.Rules)
+		if err != nil {
+			return nil, err
+		}
+		e.compiled[guildID] = detectors
+	}
+	return e, nil
+}
+
+func compileRules(rules []Rule) ([]*Detector, error) {
+	var detectors []*Detector
+	for _, r := range rules {
+		re, err := compilePattern(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ルール %s: %v", r.Name, err)
+		}
+		detectors = append(detectors, &Detector{Name: r.Name, Pattern: re})
+	}
+	return detectors, nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("正規表現は%d文字以内で指定してください", maxPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("正規表現が正しくありません: %v", err)
+	}
+	if re.MatchString("") {
+		return nil, errors.New("空文字列に一致する正規表現は使えません")
+	}
+	return re, nil
+}
+
+// 設定を保存する（呼び出し側でロックを取る）
+func (e *Engine) save() error {
+	return e.settingsFile.Save(e.settings)
+}
+
+func (e *Engine) guild(guildID string) *guildSettings {
+	gs, ok := e.settings[guildID]
+	if !ok {
+		gs = &guildSettings{}
+		e.settings[guildID] = gs
+	}
+	return gs
+}
+
+// ギルドに検出ルールを追加する
+func (e *Engine) AddRule(guildID string, rule Rule) error {
+	re, err := compilePattern(rule.Pattern)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	gs := e.guild(guildID)
+	for _, r := range gs.Rules {
+		if r.Name == rule.Name {
+			return ErrRuleExists
+		}
+	}
+	gs.Rules = append(gs.Rules, rule)
+	e.compiled[guildID] = append(e.compiled[guildID], &Detector{Name: rule.Name, Pattern: re})
+	return e.save()
+}
+
+// ギルドの検出ルールを削除する
+func (e *Engine) RemoveRule(guildID, name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	gs := e.guild(guildID)
+	for n, r := range gs.Rules {
+		if r.Name == name {
+			// 作り直せなければ、ルールも検出規則も元のままにする
+			rules := append(append([]Rule(nil), gs.Rules[:n]...), gs.Rules[n+1:]...)
+			detectors, err := compileRules(rules)
+			if err != nil {
+				return err
+			}
+			gs.Rules = rules
+			e.compiled[guildID] = detectors
+			return e.save()
+		}
+	}
+	return ErrRuleNotFound
+}
+
+// ギルドの検出ルールを返す
+func (e *Engine) Rules(guildID string) []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if gs, ok := e.settings[guildID]; ok {
+		return append([]Rule(nil), gs.Rules...)
+	}
+	return nil
+}
+
+// 伏せ字を復元できるロールを設定する
+func (e *Engine) SetOfficerRole(guildID, roleID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.guild(guildID).OfficerRoleID = roleID
+	return e.save()
+}
+
+// メンバーが伏せ字を復元できるか（担当ロールまたは管理者権限）
+func (e *Engine) IsOfficer(guildID string, member *discordgo.Member) bool {
+	if member == nil || member.User == nil {
+		return false
+	}
+	// インタラクションのメンバーには実行したチャンネルでの権限が入っている
+	if member.Permissions&discordgo.PermissionAdministrator != 0 {
+		return true
+	}
+
+	e.mu.Lock()
+	roleID := ""
+	if gs, ok := e.settings[guildID]; ok {
+		roleID = gs.OfficerRoleID
+	}
+	e.mu.Unlock()
+
+	if roleID == "" {
+		return false
+	}
+	for _, r := range member.Roles {
+		if r == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// 1回の出力で使う伏せ字の対応表を作る
+func (e *Engine) NewSession(guildID, source string) *Session {
+	e.mu.Lock()
+	custom := e.compiled[guildID]
+	e.mu.Unlock()
+
+	detectors := append([]*Detector(nil), builtinDetectors...)
+	detectors = append(detectors, custom...)
+	return &Session{
+		engine:    e,
+		detectors: detectors,
+		record: &Record{
+			Key:     strings.ReplaceAll(uuid.NewString(), "-", "")[:12],
+			GuildID: guildID,
+			Source:  source,
+			Values:  make(map[string]string),
+		},
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// 復元キーに対応する元の値を返す
+// 担当者でなければ ErrNotOfficer を返す
+func (e *Engine) Reveal(guildID string, member *discordgo.Member, key string) (*Record, error) {
+	if !e.IsOfficer(guildID, member) {
+		return nil, ErrNotOfficer
+	}
+	var r Record
+	f := storage.NewJSONFile(filepath.Join(e.dir, "keys"), filepath.Base(key)+".json")
+	if err := f.Load(&r); err != nil {
+		return nil, err
+	}
+	// 別のギルドの復元キーは扱わない
+	if r.Key == "" || r.GuildID != guildID {
+		return nil, ErrKeyNotFound
+	}
+	return &r, nil
+}
+
+// 伏せ字の対応表
+type Session struct {
+	engine    *Engine
+	detectors []*Detector
+
+	mu           sync.Mutex
+	record       *Record
+	placeholders map[string]string // 元の値 → 伏せ字
+	counts       map[string]int
+}
+
+// 文章中の個人情報を伏せ字に置き換える
+// 同じ値には同じ伏せ字を使う
+func (ss *Session) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	for _, d := range ss.detectors {
+		var b strings.Builder
+		last := 0
+		for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+			m := text[loc[0]:loc[1]]
+			// 長い数字の一部だけに一致したものは除く
+			if touchesDigit(text, loc[0], loc[1]) || (d.Valid != nil && !d.Valid(m)) {
+				continue
+			}
+			b.WriteString(text[last:loc[0]])
+			b.WriteString(ss.placeholder(d.Name, m))
+			last = loc[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+// 値に対応する伏せ字を返す（呼び出し側でロックを取る）
+func (ss *Session) placeholder(name, value string) string {
+	if p, ok := ss.placeholders[value]; ok {
+		return p
+	}
+	ss.counts[name]++
+	p := fmt.Sprintf("[%s#%d]", name, ss.counts[name])
+	ss.placeholders[value] = p
+	ss.record.Values[p] = value
+	return p
+}
+
+// 一致した範囲の前後が数字か
+func touchesDigit(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && unicode.IsDigit(r) {
+		return true
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && unicode.IsDigit(r) {
+		return true
+	}
+	return false
+}
+
+// 伏せ字にした件数
+func (ss *Session) Count() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return len(ss.record.Values)
+}
+
+// 伏せ字にした種類ごとの件数を説明文にする
+func (ss *Session) Describe() string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var names []string
+	for name := range ss.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d件", name, ss.counts[name]))
+	}
+	return strings.Join(parts, "、")
+}
+
+// 対応表を保存して復元キーを返す（伏せ字が無ければ何もせず空を返す）
+func (ss *Session) Save() (string, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if len(ss.record.Values) == 0 {
+		return "", nil
+	}
+	ss.record.CreatedAt = time.Now()
+	dir := filepath.Join(ss.engine.dir, "keys")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := storage.NewJSONFile(dir, ss.record.Key+".json").Save(ss.record); err != nil {
+		return "", err
+	}
+	return ss.record.Key, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,137 @@
+package redact
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func testEngine(t *testing.T) *Engine {
+	t.Helper()
+	e, err := NewEngine(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestSessionReusesPlaceholders(t *testing.T) {
+	e := testEngine(t)
+	ss := e.NewSession("guild", "summary")
+
+	got := ss.Redact("a@example.com と b@example.com")
+	if want := "[メールアドレス#1] と [メールアドレス#2]"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+	// 別の文章でも同じ値には同じ伏せ字を使う
+	if got := ss.Redact("もう一度 b@example.com"); got != "もう一度 [メールアドレス#2]" {
+		t.Errorf("Redact = %q, want the same placeholder", got)
+	}
+	if got := ss.Count(); got != 2 {
+		t.Errorf("Count = %d, want 2", got)
+	}
+	if got := ss.Describe(); got != "メールアドレス 2件" {
+		t.Errorf("Describe = %q", got)
+	}
+}
+
+func TestSessionCustomRules(t *testing.T) {
+	e := testEngine(t)
+	if err := e.AddRule("guild", Rule{Name: "社員番号", Pattern: `EMP-\d{5}`}); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.NewSession("guild", "test").Redact("EMP-12345 が担当"); got != "[社員番号#1] が担当" {
+		t.Errorf("custom rule: got %q", got)
+	}
+	// 別のギルドのルールは使わない
+	if got := e.NewSession("other", "test").Redact("EMP-12345 が担当"); got != "EMP-12345 が担当" {
+		t.Errorf("other guild: got %q", got)
+	}
+}
+
+func TestSaveAndReveal(t *testing.T) {
+	e := testEngine(t)
+	if err := e.SetOfficerRole("guild", "officer"); err != nil {
+		t.Fatal(err)
+	}
+
+	empty := e.NewSession("guild", "test")
+	empty.Redact("個人情報なし")
+	if key, err := empty.Save(); err != nil || key != "" {
+		t.Errorf("Save without placeholders = %q, %v, want no key", key, err)
+	}
+
+	ss := e.NewSession("guild", "summary")
+	ss.Redact("090-1234-5678")
+	key, err := ss.Save()
+	if err != nil || key == "" {
+		t.Fatalf("Save = %q, %v", key, err)
+	}
+
+	member := func(perms int64, roles ...string) *discordgo.Member {
+		return &discordgo.Member{User: &discordgo.User{ID: "user"}, Permissions: perms, Roles: roles}
+	}
+	for _, tc := range []struct {
+		name    string
+		guildID string
+		member  *discordgo.Member
+		key     string
+		wantErr error
+	}{
+		{"担当ロール", "guild", member(0, "officer"), key, nil},
+		{"管理者", "guild", member(discordgo.PermissionAdministrator), key, nil},
+		{"権限なし", "guild", member(0, "member"), key, ErrNotOfficer},
+		{"メンバー情報なし", "guild", nil, key, ErrNotOfficer},
+		{"別のギルド", "other", member(discordgo.PermissionAdministrator), key, ErrKeyNotFound},
+		{"存在しないキー", "guild", member(discordgo.PermissionAdministrator), "missing", ErrKeyNotFound},
+		{"パスを含むキー", "guild", member(discordgo.PermissionAdministrator), "../rules", ErrKeyNotFound},
+	} {
+		r, err := e.Reveal(tc.guildID, tc.member, tc.key)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if tc.wantErr == nil && (r.Source != "summary" || r.Values["[電話番号#1]"] != "090-1234-5678") {
+			t.Errorf("%s: got %+v", tc.name, r)
+		}
+	}
+}
+
+func TestRemoveRuleKeepsRulesOnError(t *testing.T) {
+	e := testEngine(t)
+	if err := e.AddRule("guild", Rule{Name: "社員番号", Pattern: `EMP-\d{5}`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddRule("guild", Rule{Name: "案件番号", Pattern: `PRJ-\d{3}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.RemoveRule("guild", "なし"); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("RemoveRule(missing) = %v, want ErrRuleNotFound", err)
+	}
+
+	// 保存されたルールが壊れていたら、削除せずにエラーを返す
+	e.settings["guild"].Rules[1].Pattern = "("
+	if err := e.RemoveRule("guild", "社員番号"); err == nil {
+		t.Fatal("RemoveRule with a broken rule succeeded")
+	}
+	if got := len(e.Rules("guild")); got != 2 {
+		t.Errorf("%d rules after the failed removal, want 2", got)
+	}
+	if got := e.NewSession("guild", "test").Redact("EMP-12345"); got != "[社員番号#1]" {
+		t.Errorf("detectors changed after the failed removal: %q", got)
+	}
+
+	e.settings["guild"].Rules[1].Pattern = `PRJ-\d{3}`
+	if err := e.RemoveRule("guild", "社員番号"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.NewSession("guild", "test").Redact("EMP-12345 PRJ-001"); got != "EMP-12345 [案件番号#1]" {
+		t.Errorf("after removal: got %q", got)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
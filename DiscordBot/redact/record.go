@@ -0,0 +1,19 @@
+package redact
+
+import "main/exporter"
+
+// 出力するメッセージの本文と埋め込みを伏せ字にする
+func RecordFilter(ss *Session) exporter.Filter {
+	return func(r *exporter.Record) bool {
+		r.Content = ss.Redact(r.Content)
+		for n := range r.Embeds {
+			r.Embeds[n].Title = ss.Redact(r.Embeds[n].Title)
+			r.Embeds[n].Description = ss.Redact(r.Embeds[n].Description)
+		}
+		return true
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
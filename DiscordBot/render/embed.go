@@ -0,0 +1,83 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 埋め込みに整形し、1メッセージに収まる単位にまとめる
+// 長い本文や項目は続きの埋め込みに分ける
+func (r *Response) Messages() [][]*discordgo.MessageEmbed {
+	color := r.Color
+	if color == 0 {
+		color = DefaultColor
+	}
+
+	var embeds []*discordgo.MessageEmbed
+	add := func(title, text string) {
+		parts := splitText(text, embedDescriptionLimit)
+		if len(parts) == 0 {
+			parts = []string{""}
+		}
+		for n, part := range parts {
+			t := title
+			if n > 0 && title != "" {
+				t = title + "（続き）"
+			}
+			embeds = append(embeds, &discordgo.MessageEmbed{
+				Title:       truncate(t, embedTitleLimit),
+				Description: part,
+				Color:       color,
+			})
+		}
+	}
+
+	if r.Title != "" || r.Description != "" {
+		add(r.Title, r.Description)
+	}
+	for _, sec := range r.sections() {
+		var b strings.Builder
+		if sec.Text != "" {
+			b.WriteString(sec.Text + "\n")
+		}
+		for _, item := range sec.Items {
+			b.WriteString("• " + item + "\n")
+		}
+		add(sec.Title, b.String())
+	}
+	if r.Footer != "" && len(embeds) > 0 {
+		embeds[len(embeds)-1].Footer = &discordgo.MessageEmbedFooter{Text: truncate(r.Footer, embedFooterLimit)}
+	}
+
+	// 1メッセージあたりの埋め込みの数と合計文字数の上限で区切る
+	var messages [][]*discordgo.MessageEmbed
+	var cur []*discordgo.MessageEmbed
+	total := 0
+	for _, e := range embeds {
+		size := embedSize(e)
+		if len(cur) > 0 && (len(cur) >= embedsPerMessage || total+size > embedTotalLimit) {
+			messages = append(messages, cur)
+			cur, total = nil, 0
+		}
+		cur = append(cur, e)
+		total += size
+	}
+	if len(cur) > 0 {
+		messages = append(messages, cur)
+	}
+	return messages
+}
+
+func embedSize(e *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return n
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
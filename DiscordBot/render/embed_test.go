@@ -0,0 +1,77 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 埋め込みの文字数がちょうどsizeになる見出し（タイトル3文字と「• 」を含む）
+func sizedSection(size int) Section {
+	return Section{Title: "見出し", Items: []string{strings.Repeat("あ", size-3-2)}}
+}
+
+func TestMessagesLimits(t *testing.T) {
+	many := func(n int, size int) []Section {
+		sections := make([]Section, n)
+		for i := range sections {
+			sections[i] = sizedSection(size)
+		}
+		return sections
+	}
+	for _, tc := range []struct {
+		name string
+		resp Response
+		want []int // メッセージごとの埋め込みの数
+	}{
+		{"空", Response{}, nil},
+		{"埋め込み10個まで", Response{Sections: many(10, 10)}, []int{10}},
+		{"11個目は次のメッセージ", Response{Sections: many(11, 10)}, []int{10, 1}},
+		{"合計6000文字ちょうど", Response{Sections: many(3, 2000)}, []int{3}},
+		{"合計6000文字を超える", Response{Sections: many(4, 2000)}, []int{3, 1}},
+		{"1文字でも超えれば次のメッセージ", Response{Sections: append(many(2, 2000), sizedSection(2001))}, []int{2, 1}},
+		{"長い本文は続きの埋め込みに分ける", Response{Title: "要約", Description: strings.Repeat("あ", embedDescriptionLimit+1)}, []int{2}},
+		{"空の見出しは出さない", Response{Title: "要約", Sections: []Section{{Title: "空"}}}, []int{1}},
+	} {
+		pages := tc.resp.Messages()
+		got := make([]int, len(pages))
+		for n, embeds := range pages {
+			got[n] = len(embeds)
+			total := 0
+			for _, e := range embeds {
+				total += embedSize(e)
+				if len([]rune(e.Description)) > embedDescriptionLimit {
+					t.Errorf("%s: description of %d characters", tc.name, len([]rune(e.Description)))
+				}
+			}
+			if total > embedTotalLimit {
+				t.Errorf("%s: message %d has %d characters", tc.name, n, total)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: embeds per message = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMessagesContinuationAndFooter(t *testing.T) {
+	r := &Response{Title: "要約", Description: strings.Repeat("あ", embedDescriptionLimit+1), Footer: "フッター"}
+	pages := r.Messages()
+	if len(pages) != 1 || len(pages[0]) != 2 {
+		t.Fatalf("got %v, want one message with two embeds", pages)
+	}
+	first, second := pages[0][0], pages[0][1]
+	if first.Title != "要約" || second.Title != "要約（続き）" {
+		t.Errorf("titles = %q, %q", first.Title, second.Title)
+	}
+	if first.Footer != nil || second.Footer == nil || second.Footer.Text != "フッター" {
+		t.Errorf("footer should be on the last embed only")
+	}
+	if first.Color != DefaultColor {
+		t.Errorf("color = %#x, want the default color", first.Color)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
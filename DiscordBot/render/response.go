@@ -0,0 +1,123 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Discordの上限
+const (
+	messageLimit          = 2000
+	embedTitleLimit       = 256
+	embedDescriptionLimit = 4096
+	embedFooterLimit      = 2048
+	embedsPerMessage      = 10
+	embedTotalLimit       = 6000 // 1メッセージ内の埋め込みの合計文字数
+)
+
+// 既定の色（Discordのブランドカラー）
+const DefaultColor = 0x5865f2
+
+// 見出し付きの項目
+type Section struct {
+	Title string
+	Items []string // 箇条書きの項目
+	Text  string   // 箇条書きでない本文（Itemsより前に表示する）
+}
+
+// コマンドの応答
+// 埋め込みに整形して送り、収まらなければ分割またはMarkdownファイルにする
+type Response struct {
+	Content     string // 埋め込みの上に表示する本文
+	Title       string
+	Description string
+	Sections    []Section
+	Footer      string
+	Color       int
+	FileName    string // Markdownファイルにする場合の名前（拡張子なし）
+	Ephemeral   bool   // フォローアップも本人にだけ見せる（一時応答をephemeralにした場合）
+}
+
+// 空の項目を除いた見出しの一覧
+func (r *Response) sections() []Section {
+	var list []Section
+	for _, sec := range r.Sections {
+		if sec.Text != "" || len(sec.Items) > 0 {
+			list = append(list, sec)
+		}
+	}
+	return list
+}
+
+// Markdownとして書き出す
+func (r *Response) Markdown() string {
+	var b strings.Builder
+	if r.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", r.Title)
+	}
+	if r.Content != "" {
+		b.WriteString(r.Content + "\n\n")
+	}
+	if r.Description != "" {
+		b.WriteString(r.Description + "\n\n")
+	}
+	for _, sec := range r.sections() {
+		fmt.Fprintf(&b, "## %s\n\n", sec.Title)
+		if sec.Text != "" {
+			b.WriteString(sec.Text + "\n\n")
+		}
+		for _, item := range sec.Items {
+			b.WriteString("- " + strings.ReplaceAll(item, "\n", "\n  ") + "\n")
+		}
+		if len(sec.Items) > 0 {
+			b.WriteString("\n")
+		}
+	}
+	if r.Footer != "" {
+		b.WriteString("---\n" + r.Footer + "\n")
+	}
+	return b.String()
+}
+
+// 文字数の上限に収まるよう切り詰める
+func truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
+
+// 上限に収まるよう行単位で分割する（1行が上限を超える場合は行の途中で分ける）
+func splitText(text string, limit int) []string {
+	var parts []string
+	var cur strings.Builder
+	curLen := 0
+	flush := func() {
+		if curLen > 0 {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+		}
+		cur.Reset()
+		curLen = 0
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		runes := []rune(line)
+		for len(runes) > limit {
+			flush()
+			parts = append(parts, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+		if curLen+len(runes) > limit {
+			flush()
+		}
+		cur.WriteString(string(runes))
+		curLen += len(runes)
+	}
+	flush()
+	return parts
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"空", "", 10, nil},
+		{"上限ちょうど", "あいうえお", 5, []string{"あいうえお"}},
+		{"行単位で分ける", "あいう\nえお\nかきくけ\n", 7, []string{"あいう\nえお", "かきくけ"}},
+		{"改行も数える", "あいう\nえお", 5, []string{"あいう", "えお"}},
+		{"長い行は途中で分ける", "あいうえおかきくけこさ", 4, []string{"あいうえ", "おかきく", "けこさ"}},
+		{"長い行の前後の行", "あ\nいうえおかき\nく", 4, []string{"あ", "いうえお", "かき\nく"}},
+	} {
+		got := splitText(tc.text, tc.limit)
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") || len(got) != len(tc.want) {
+			t.Errorf("%s: splitText(%q, %d) = %q, want %q", tc.name, tc.text, tc.limit, got, tc.want)
+		}
+		for _, part := range got {
+			if n := utf8.RuneCountInString(part); n > tc.limit {
+				t.Errorf("%s: part %q has %d characters, over the limit %d", tc.name, part, n, tc.limit)
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"あいうえお", 5, "あいうえお"},
+		{"あいうえおか", 5, "あいうえ…"},
+		{"", 5, ""},
+	} {
+		if got := truncate(tc.text, tc.limit); got != tc.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.text, tc.limit, got, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"bytes"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 分割したメッセージがこの数を超える場合はMarkdownファイルで送る
+const maxMessages = 3
+
+// 1通分の送信内容
+type message struct {
+	content string
+	embeds  []*discordgo.MessageEmbed
+	files   []*discordgo.File
+}
+
+// 送信する単位に分ける
+func (r *Response) plan() []*message {
+	pages := r.Messages()
+	if len(pages) > maxMessages {
+		name := r.FileName
+		if name == "" {
+			name = "response"
+		}
+		return []*message{{
+			content: truncate(r.Content+"\n内容が長いためファイルで送信します。", messageLimit),
+			files: []*discordgo.File{{
+				Name:        name + ".md",
+				ContentType: "text/markdown; charset=utf-8",
+				Reader:      bytes.NewReader([]byte(r.Markdown())),
+			}},
+		}}
+	}
+
+	if len(pages) == 0 {
+		return []*message{{content: truncate(r.Content, messageLimit)}}
+	}
+	messages := make([]*message, len(pages))
+	for n, embeds := range pages {
+		messages[n] = &message{embeds: embeds}
+	}
+	messages[0].content = truncate(r.Content, messageLimit)
+	return messages
+}
+
+// インタラクションの応答として送る
+// 1通目は一時応答を書き換え、残りはフォローアップとして送る
+func Interaction(s *discordgo.Session, i *discordgo.InteractionCreate, r *Response) error {
+	var flags discordgo.MessageFlags
+	if r.Ephemeral {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	for n, m := range r.plan() {
+		if n == 0 {
+			content := m.content
+			embeds := m.embeds
+			if embeds == nil {
+				embeds = []*discordgo.MessageEmbed{}
+			}
+			_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+				Content: &content,
+				Embeds:  &embeds,
+				Files:   m.files,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: m.content,
+			Embeds:  m.embeds,
+			Files:   m.files,
+			Flags:   flags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// チャンネルに送る
+func Channel(s *discordgo.Session, channelID string, r *Response) error {
+	for _, m := range r.plan() {
+		_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: m.content,
+			Embeds:  m.embeds,
+			Files:   m.files,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
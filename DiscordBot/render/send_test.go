@@ -0,0 +1,75 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	sections := func(n int) []Section {
+		list := make([]Section, n)
+		for i := range list {
+			list[i] = sizedSection(2000)
+		}
+		return list
+	}
+	for _, tc := range []struct {
+		name     string
+		resp     Response
+		messages int
+		file     bool
+	}{
+		{"本文だけ", Response{Content: "本文"}, 1, false},
+		{"1通に収まる", Response{Content: "本文", Sections: sections(3)}, 1, false},
+		{"上限の3通まで分割する", Response{Content: "本文", Sections: sections(9)}, 3, false},
+		{"4通以上になるとファイルにする", Response{Content: "本文", Sections: sections(10)}, 1, true},
+	} {
+		plan := tc.resp.plan()
+		if len(plan) != tc.messages {
+			t.Errorf("%s: got %d messages, want %d", tc.name, len(plan), tc.messages)
+			continue
+		}
+		if !strings.HasPrefix(plan[0].content, "本文") {
+			t.Errorf("%s: first content = %q, want the response content", tc.name, plan[0].content)
+		}
+		for n, m := range plan[1:] {
+			if m.content != "" {
+				t.Errorf("%s: message %d repeats the content", tc.name, n+2)
+			}
+		}
+		if got := len(plan[0].files) > 0; got != tc.file {
+			t.Errorf("%s: file = %v, want %v", tc.name, got, tc.file)
+		}
+	}
+}
+
+func TestPlanFile(t *testing.T) {
+	r := &Response{Content: strings.Repeat("あ", messageLimit), Title: "要約", Sections: make([]Section, 0, 40)}
+	for i := 0; i < 40; i++ {
+		r.Sections = append(r.Sections, Section{Title: "見出し", Items: []string{"項目"}})
+	}
+	plan := r.plan()
+	if len(plan) != 1 || len(plan[0].files) != 1 {
+		t.Fatalf("plan = %+v, want one message with a file", plan)
+	}
+	m := plan[0]
+	if len([]rune(m.content)) > messageLimit || !strings.HasSuffix(m.content, "…") {
+		t.Errorf("content of %d characters, want it truncated to %d", len([]rune(m.content)), messageLimit)
+	}
+	if m.embeds != nil {
+		t.Errorf("file message has %d embeds", len(m.embeds))
+	}
+	f := m.files[0]
+	if f.Name != "response.md" {
+		t.Errorf("file name = %q, want the default name", f.Name)
+	}
+	body, _ := io.ReadAll(f.Reader)
+	if string(body) != r.Markdown() {
+		t.Errorf("file does not contain the Markdown")
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -1,13 +1,16 @@
 import sys
+import json
 import whisper
 import os
 
 def main():
-    if len(sys.argv) < 2:
-        print("Usage: python transcribe.py <audio_file_path>")
+    args = [a for a in sys.argv[1:] if a != "--json"]
+    as_json = "--json" in sys.argv[1:]
+    if len(args) < 1:
+        print("Usage: python transcribe.py [--json] <audio_file_path>")
         return
 
-    file_path = sys.argv[1]
+    file_path = args[0]
 
     if not os.path.exists(file_path):
         print(f"File not found: {file_path}")
@@ -17,8 +20,16 @@ def main():
 
     try:
         result = model.transcribe(file_path)
-        print("結果")
-        print(result["text"])
+        if as_json:
+            # 発言ごとの時刻付きで1行のJSONとして出力する
+            segments = [
+                {"start": seg["start"], "end": seg["end"], "text": seg["text"].strip()}
+                for seg in result["segments"]
+            ]
+            print(json.dumps({"text": result["text"], "segments": segments}, ensure_ascii=False))
+        else:
+            print("結果")
+            print(result["text"])
     except Exception as e:
         print(f"Whisper transcription failed: {e}")
         print(f"Error type: {type(e).__name__}")
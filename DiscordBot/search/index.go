@@ -0,0 +1,268 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 検索対象のメッセージ
+type Document struct {
+	ID        string
+	GuildID   string
+	ChannelID string
+	AuthorID  string
+	Author    string
+	Content   string
+	Timestamp time.Time
+}
+
+// メッセージへのリンク
+func (d *Document) URL() string {
+	return "https://discord.com/channels/" + d.GuildID + "/" + d.ChannelID + "/" + d.ID
+}
+
+// 検索条件
+type Query struct {
+	GuildID   string
+	Text      string
+	AuthorID  string // 空なら全員
+	ChannelID string // 空なら全チャンネル
+	After     time.Time
+	Before    time.Time
+
+	// 結果に含めてよいチャンネルか（nilなら全て）
+	CanView func(channelID string) bool
+}
+
+type indexedDoc struct {
+	Document
+	normalized string
+	tokens     []string
+}
+
+// メモリ上の転置インデックス
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*indexedDoc
+	postings map[string]map[string]struct{} // トークン → メッセージID
+}
+
+// 空のIndexを返す
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*indexedDoc),
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// DiscordのメッセージからDocumentを作る
+func DocumentFromMessage(guildID string, m *discordgo.Message) *Document {
+	d := &Document{
+		ID:        m.ID,
+		GuildID:   guildID,
+		ChannelID: m.ChannelID,
+		Content:   m.Content,
+		Timestamp: m.Timestamp,
+	}
+	if m.Author != nil {
+		d.AuthorID = m.Author.ID
+		d.Author = m.Author.Username
+	}
+	if m.Member != nil && m.Member.Nick != "" {
+		d.Author = m.Member.Nick
+	}
+	return d
+}
+
+// メッセージを追加する（同じIDがあれば置き換える）
+func (x *Index) Add(d *Document) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if old, ok := x.docs[d.ID]; ok {
+		if d.Timestamp.IsZero() {
+			d.Timestamp = old.Timestamp
+		}
+		x.remove(old)
+	}
+	doc := &indexedDoc{
+		Document:   *d,
+		normalized: normalize(d.Content),
+		tokens:     uniqueTokens(d.Content),
+	}
+	x.docs[d.ID] = doc
+	for _, t := range doc.tokens {
+		ids, ok := x.postings[t]
+		if !ok {
+			ids = make(map[string]struct{})
+			x.postings[t] = ids
+		}
+		ids[d.ID] = struct{}{}
+	}
+}
+
+// メッセージを取り除く
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if doc, ok := x.docs[id]; ok {
+		x.remove(doc)
+	}
+}
+
+func (x *Index) remove(doc *indexedDoc) {
+	delete(x.docs, doc.ID)
+	for _, t := range doc.tokens {
+		ids := x.postings[t]
+		delete(ids, doc.ID)
+		if len(ids) == 0 {
+			delete(x.postings, t)
+		}
+	}
+}
+
+// 条件に合うメッセージを新しい順に返す
+// 空白で区切った語はすべてを含むもの（AND）だけを返す
+// 閲覧権限（q.CanView）はAPIを呼ぶことがあるため、ロックを外してから確かめる
+func (x *Index) Search(q *Query) []*Document {
+	results := x.lookup(q)
+	if q.CanView != nil {
+		visible := results[:0]
+		for _, d := range results {
+			if q.CanView(d.ChannelID) {
+				visible = append(visible, d)
+			}
+		}
+		results = visible
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	return results
+}
+
+// 閲覧権限以外の条件に合うメッセージを返す
+func (x *Index) lookup(q *Query) []*Document {
+	terms := strings.Fields(normalize(q.Text))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// 最も件数の少ないトークンから候補を絞る
+	// 日本語1文字の語はbigramで索引していないため、本文の照合だけで判定する
+	var tokens []string
+	for _, term := range terms {
+		for _, t := range uniqueTokens(term) {
+			if r := []rune(t); len(r) == 1 && isJapanese(r[0]) {
+				continue
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	var candidates map[string]struct{}
+	for _, t := range tokens {
+		ids := x.postings[t]
+		if len(ids) == 0 {
+			return nil
+		}
+		if candidates == nil || len(ids) < len(candidates) {
+			candidates = ids
+		}
+	}
+
+	var results []*Document
+	collect := func(doc *indexedDoc) {
+		if x.matches(doc, q, tokens, terms) {
+			d := doc.Document
+			results = append(results, &d)
+		}
+	}
+	if candidates == nil {
+		for _, doc := range x.docs {
+			collect(doc)
+		}
+	} else {
+		for id := range candidates {
+			collect(x.docs[id])
+		}
+	}
+	return results
+}
+
+func (x *Index) matches(doc *indexedDoc, q *Query, tokens, terms []string) bool {
+	if doc.GuildID != q.GuildID {
+		return false
+	}
+	if q.AuthorID != "" && doc.AuthorID != q.AuthorID {
+		return false
+	}
+	if q.ChannelID != "" && doc.ChannelID != q.ChannelID {
+		return false
+	}
+	if !q.After.IsZero() && doc.Timestamp.Before(q.After) {
+		return false
+	}
+	if !q.Before.IsZero() && !doc.Timestamp.Before(q.Before) {
+		return false
+	}
+	for _, t := range tokens {
+		if _, ok := x.postings[t][doc.ID]; !ok {
+			return false
+		}
+	}
+	// bigramの組み合わせだけでは語順を区別できないため、本文に語が含まれるか確かめる
+	for _, term := range terms {
+		if !strings.Contains(doc.normalized, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// 検索語の周辺を抜き出す
+func Snippet(content, text string, width int) string {
+	terms := strings.Fields(normalize(text))
+	normalized := []rune(normalize(content))
+	runes := []rune(content)
+	if len(normalized) != len(runes) || len(terms) == 0 {
+		return truncate(runes, 0, width)
+	}
+
+	pos := strings.Index(string(normalized), terms[0])
+	if pos < 0 {
+		return truncate(runes, 0, width)
+	}
+	start := utf8.RuneCountInString(string(normalized)[:pos]) - width/3
+	if start < 0 {
+		start = 0
+	}
+	return truncate(runes, start, width)
+}
+
+func truncate(runes []rune, start, width int) string {
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+	}
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func testDocument(id, channelID, content string, ts time.Time) *Document {
+	return &Document{
+		ID:        id,
+		GuildID:   "guild",
+		ChannelID: channelID,
+		AuthorID:  "user",
+		Content:   content,
+		Timestamp: ts,
+	}
+}
+
+func resultIDs(docs []*Document) []string {
+	var ids []string
+	for _, d := range docs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearchJapaneseAndOrder(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	x := NewIndex()
+	x.Add(testDocument("1", "c", "明日の会議は中止です", base))
+	x.Add(testDocument("2", "c", "会議室を予約しました", base.Add(time.Hour)))
+	x.Add(testDocument("3", "c", "議会の中継", base.Add(2*time.Hour)))
+
+	got := resultIDs(x.Search(&Query{GuildID: "guild", Text: "会議"}))
+	if want := []string{"2", "1"}; !equalIDs(got, want) {
+		t.Errorf("会議: got %v, want %v", got, want)
+	}
+	got = resultIDs(x.Search(&Query{GuildID: "guild", Text: "会議　中止"}))
+	if want := []string{"1"}; !equalIDs(got, want) {
+		t.Errorf("会議 中止: got %v, want %v", got, want)
+	}
+	if got := x.Search(&Query{GuildID: "other", Text: "会議"}); len(got) != 0 {
+		t.Errorf("other guild: got %v, want none", resultIDs(got))
+	}
+}
+
+func TestAddKeepsTimestampOnEdit(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	x := NewIndex()
+	x.Add(testDocument("1", "c", "old text", ts))
+	x.Add(testDocument("1", "c", "new text", time.Time{}))
+
+	if got := x.Search(&Query{GuildID: "guild", Text: "old"}); len(got) != 0 {
+		t.Errorf("old content still indexed: %v", resultIDs(got))
+	}
+	got := x.Search(&Query{GuildID: "guild", Text: "new"})
+	if len(got) != 1 || !got[0].Timestamp.Equal(ts) {
+		t.Fatalf("got %+v, want one result at %v", got, ts)
+	}
+}
+
+func TestSearchChecksViewOutsideLock(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	x := NewIndex()
+	x.Add(testDocument("1", "public", "hello", ts))
+	x.Add(testDocument("2", "secret", "hello", ts.Add(time.Minute)))
+
+	calls := 0
+	q := &Query{GuildID: "guild", Text: "hello", CanView: func(channelID string) bool {
+		calls++
+		// 書き込みロックを取るため、Searchがロックを握ったままだと止まる
+		x.Add(testDocument("3", "public", "other", ts))
+		return channelID == "public"
+	}}
+	got := resultIDs(x.Search(q))
+	if want := []string{"1"}; !equalIDs(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if calls != 2 {
+		t.Errorf("CanView called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,172 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+
+	"main/crawler"
+	"main/messagestore"
+)
+
+// ギルドごとの取り込み状況
+type guildState struct {
+	indexing bool     // 取り込み中か
+	ready    bool     // すべてのチャンネルを一度取り込もうとしたか
+	listed   bool     // 取り込むチャンネルの一覧を取得したか
+	pending  []string // まだ取り込めていないチャンネル
+}
+
+// 保存済みのメッセージから索引を作り、ゲートウェイのイベントで更新し続ける
+// 取り込みには時間がかかるため、起動時やギルドへの参加時に裏で始める
+type Searcher struct {
+	index *Index
+	store *messagestore.Store
+
+	mu     sync.Mutex
+	guilds map[string]*guildState
+}
+
+// Searcherを返す
+func NewSearcher(store *messagestore.Store) *Searcher {
+	return &Searcher{
+		index:  NewIndex(),
+		store:  store,
+		guilds: make(map[string]*guildState),
+	}
+}
+
+// 投稿されたメッセージを索引に追加する
+func (sr *Searcher) OnMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" || m.Author == nil {
+		return
+	}
+	sr.index.Add(DocumentFromMessage(m.GuildID, m.Message))
+}
+
+// 編集されたメッセージの索引を更新する
+func (sr *Searcher) OnMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m.GuildID == "" || m.Author == nil {
+		// 埋め込みの展開など、本文を含まない更新は無視する
+		return
+	}
+	sr.index.Add(DocumentFromMessage(m.GuildID, m.Message))
+}
+
+// 削除されたメッセージを索引から取り除く
+func (sr *Searcher) OnMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	sr.index.Remove(m.ID)
+}
+
+// まとめて削除されたメッセージを索引から取り除く
+func (sr *Searcher) OnMessageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	for _, id := range m.Messages {
+		sr.index.Remove(id)
+	}
+}
+
+// 起動時やギルドへの参加時に、ギルドのメッセージの取り込みを始める
+func (sr *Searcher) OnGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	sr.Prepare(s, g.ID)
+}
+
+// ギルドが検索できる状態かを返す
+// まだ取り込んでいないか、取り込めなかったチャンネルがあれば、裏で取り込みを始める
+func (sr *Searcher) Prepare(s *discordgo.Session, guildID string) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	g, ok := sr.guilds[guildID]
+	if !ok {
+		g = &guildState{}
+		sr.guilds[guildID] = g
+	}
+	if !g.indexing && (!g.listed || len(g.pending) > 0) {
+		g.indexing = true
+		go sr.build(s, guildID, g)
+	}
+	return g.ready
+}
+
+// ギルドのメッセージを索引に取り込む
+// 取り込めなかったチャンネルは次の Prepare でやり直し、取り込めたチャンネルは以降イベントで更新される
+func (sr *Searcher) build(s *discordgo.Session, guildID string, g *guildState) {
+	sr.mu.Lock()
+	listed, channelIDs := g.listed, g.pending
+	sr.mu.Unlock()
+
+	if !listed {
+		var err error
+		if channelIDs, err = readableChannels(s, guildID); err != nil {
+			log.Printf("検索用のチャンネル一覧の取得に失敗しました (%s): %v\n", guildID, err)
+			sr.mu.Lock()
+			g.indexing = false
+			sr.mu.Unlock()
+			return
+		}
+	}
+
+	var count int64
+	errs := crawler.Run(context.Background(), crawler.DefaultWorkers, len(channelIDs), func(ctx context.Context, n int) error {
+		channelID := channelIDs[n]
+		if err := sr.store.Sync(s, channelID); err != nil {
+			log.Printf("検索用の取り込みに失敗しました (%s): %v\n", channelID, err)
+			return err
+		}
+		messages, err := sr.store.Messages(channelID)
+		if err != nil {
+			log.Printf("検索用の取り込みに失敗しました (%s): %v\n", channelID, err)
+			return err
+		}
+		for _, m := range messages {
+			if m.Author == nil {
+				continue
+			}
+			sr.index.Add(DocumentFromMessage(guildID, m))
+			atomic.AddInt64(&count, 1)
+		}
+		return nil
+	})
+	log.Printf("%d 件のメッセージを検索できるようにしました (%s)\n", count, guildID)
+
+	var failed []string
+	for n, err := range errs {
+		if err != nil {
+			failed = append(failed, channelIDs[n])
+		}
+	}
+	if len(failed) > 0 {
+		log.Printf("%d 個のチャンネルは次の検索で取り込み直します (%s)\n", len(failed), guildID)
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	g.listed = true
+	g.pending = failed
+	g.ready = true
+	g.indexing = false
+}
+
+// 条件に合うメッセージを新しい順に返す
+func (sr *Searcher) Search(q *Query) []*Document {
+	return sr.index.Search(q)
+}
+
+// Botが履歴を読めるチャンネル・スレッド・フォーラム投稿のIDを返す
+func readableChannels(s *discordgo.Session, guildID string) ([]string, error) {
+	channels, err := crawler.ListChannels(s, guildID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		ids = append(ids, ch.ID)
+	}
+	return ids, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,91 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 検索用に文字を揃える（全角英数字を半角に、英字を小文字にする）
+func normalize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		if r >= '！' && r <= '～' {
+			r -= 0xFEE0
+		} else if r == '　' {
+			r = ' '
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// 日本語（漢字・ひらがな・カタカナ）の文字か
+func isJapanese(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		r == 'ー' || r == '々'
+}
+
+// 文章をトークンに分割する
+// 日本語は分かち書きしないため、連続する部分を2文字ずつ（bigram）に区切る
+// 英数字は単語ごとに1トークンとする
+func tokenize(text string) []string {
+	var tokens []string
+	var run []rune
+	japanese := false
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if !japanese || len(run) == 1 {
+			tokens = append(tokens, string(run))
+		} else {
+			for n := 0; n+1 < len(run); n++ {
+				tokens = append(tokens, string(run[n:n+2]))
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, r := range normalize(text) {
+		switch {
+		case isJapanese(r):
+			if !japanese {
+				flush()
+				japanese = true
+			}
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if japanese {
+				flush()
+				japanese = false
+			}
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// 重複を除いたトークンを返す
+func uniqueTokens(text string) []string {
+	seen := make(map[string]struct{})
+	var tokens []string
+	for _, t := range tokenize(text) {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,61 @@
+package serverHandler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"main/model"
+	"main/service"
+)
+
+type AnnounceHandler struct {
+	svc *service.AnnounceService
+}
+
+// AnnounceHandlerを返す
+func NewAnnounceHandler(svc *service.AnnounceService) *AnnounceHandler {
+	return &AnnounceHandler{
+		svc: svc,
+	}
+}
+
+// POST /announce    ボイスチャンネルで音声を再生する（API_TOKENによる認証が必要）
+func (h *AnnounceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// POSTリクエストのみ受け付ける
+	if r.Method != http.MethodPost {
+		http.Error(w, "POSTだけが利用できます。", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req model.AnnounceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("JSONデコードエラー: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// 再生キューに追加（再生の完了は待たない）
+	position, err := h.svc.Announce(req.GuildID, req.ChannelID, req.Sound)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		log.Printf("アナウンスエラー: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&model.AnnounceResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&model.AnnounceResponse{
+		Success:  true,
+		Message:  "アナウンスを再生キューに追加しました",
+		Position: position,
+	})
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
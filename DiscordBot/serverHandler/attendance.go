@@ -0,0 +1,107 @@
+package serverHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/attendance"
+	"main/model"
+	"main/service"
+)
+
+type AttendanceHandler struct {
+	svc *service.AttendanceService
+}
+
+// AttendanceHandlerを返す
+func NewAttendanceHandler(svc *service.AttendanceService) *AttendanceHandler {
+	return &AttendanceHandler{
+		svc: svc,
+	}
+}
+
+// GET /attendance?guild_id=...            会議の一覧
+// GET /attendance?guild_id=...&meeting=... 会議の出席記録（format=csvでCSV）
+// どちらもAPI_TOKENによる認証が必要（RequireToken）
+func (h *AttendanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GETだけが利用できます。", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	guildID := q.Get("guild_id")
+
+	if !q.Has("meeting") {
+		meetings, err := h.svc.Meetings(guildID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res := make([]model.MeetingResponse, 0, len(meetings))
+		for _, m := range meetings {
+			res = append(res, toMeetingResponse(m))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	report, err := h.svc.Report(guildID, q.Get("meeting"))
+	if errors.Is(err, attendance.ErrMeetingNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("出席記録の取得エラー: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if q.Get("format") == "csv" {
+		data, err := report.CSV()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="attendance_`+report.Meeting.ID+`.csv"`)
+		w.Write(data)
+		return
+	}
+
+	res := model.AttendanceResponse{
+		Meeting:   toMeetingResponse(report.Meeting),
+		Attendees: make([]model.AttendeeResponse, 0, len(report.Attendees)),
+	}
+	for _, a := range report.Attendees {
+		res.Attendees = append(res.Attendees, model.AttendeeResponse{
+			UserID:          a.UserID,
+			Name:            a.Name,
+			FirstJoin:       a.FirstJoin.UnixMilli(),
+			LastLeave:       a.LastLeave.UnixMilli(),
+			DurationMinutes: a.Duration.Minutes(),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&res)
+}
+
+func toMeetingResponse(m *attendance.Meeting) model.MeetingResponse {
+	res := model.MeetingResponse{
+		ID:        m.ID,
+		Name:      m.Name,
+		ChannelID: m.ChannelID,
+		StartedAt: m.StartedAt.UnixMilli(),
+	}
+	if m.EndedAt != nil {
+		res.EndedAt = m.EndedAt.UnixMilli()
+	}
+	return res
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
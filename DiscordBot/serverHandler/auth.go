@@ -0,0 +1,29 @@
+package serverHandler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Authorization: Bearer <API_TOKEN> を付けた要求だけをnextに渡す
+// API_TOKENが設定されていなければ、どの要求も受け付けない
+func RequireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "API_TOKENが設定されていないため利用できません。", http.StatusForbidden)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "認証に失敗しました。", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
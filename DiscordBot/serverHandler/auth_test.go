@@ -0,0 +1,39 @@
+package serverHandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	for _, tc := range []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"正しいトークン", "secret", "Bearer secret", http.StatusNoContent},
+		{"トークンなし", "secret", "", http.StatusUnauthorized},
+		{"異なるトークン", "secret", "Bearer secre", http.StatusUnauthorized},
+		{"Bearerなし", "secret", "secret", http.StatusUnauthorized},
+		{"API_TOKEN未設定", "", "Bearer ", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/announce", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		RequireToken(tc.token, ok)(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
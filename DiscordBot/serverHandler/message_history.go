@@ -0,0 +1,56 @@
+package serverHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"main/messagestore"
+	"main/service"
+)
+
+type MessageHistoryHandler struct {
+	svc *service.MessageHistoryService
+}
+
+// MessageHistoryHandlerを返す
+func NewMessageHistoryHandler(svc *service.MessageHistoryService) *MessageHistoryHandler {
+	return &MessageHistoryHandler{
+		svc: svc,
+	}
+}
+
+// GET /messages/history?channel_id=...&message_id=...
+// 削除された本文や編集前の本文を返すため、API_TOKENによる認証が必要（RequireToken）
+func (h *MessageHistoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GETだけが利用できます。", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	channelID, messageID := q.Get("channel_id"), q.Get("message_id")
+	if channelID == "" || messageID == "" {
+		http.Error(w, "channel_id と message_id を指定してください", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.Get(channelID, messageID)
+	if errors.Is(err, messagestore.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("メッセージ履歴の取得エラー: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
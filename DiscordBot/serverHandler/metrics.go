@@ -0,0 +1,35 @@
+package serverHandler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"main/service"
+)
+
+type MetricsHandler struct {
+	svc *service.MetricsService
+}
+
+// MetricsHandlerを返す
+func NewMetricsHandler(svc *service.MetricsService) *MetricsHandler {
+	return &MetricsHandler{
+		svc: svc,
+	}
+}
+
+// GET /metrics 外部サービスごとの送信回数・エラー・再試行・遅延・回路の状態
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GETだけが利用できます。", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"http": h.svc.HTTP(),
+	})
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
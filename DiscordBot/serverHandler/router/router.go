@@ -3,21 +3,47 @@ package router
 import (
 	"net/http"
 
+	"main/attendance"
+	"main/messagestore"
+	"main/recording"
 	"main/serverHandler"
 	"main/service"
+	"main/transcript"
+	"main/voice"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-func NewRouter(discordSession *discordgo.Session) *http.ServeMux {
+// ルーティングに必要な依存関係
+type Dependencies struct {
+	Player      *voice.Player
+	Recordings  *recording.Manager
+	SoundsDir   string
+	Transcripts *transcript.Store
+	Attendance  *attendance.Tracker
+	Messages    *messagestore.Store
+	APIToken    string // 管理用のエンドポイントの認証に使うトークン（空なら管理用のエンドポイントを使えない）
+}
+
+func NewRouter(discordSession *discordgo.Session, deps *Dependencies) *http.ServeMux {
 	// *service.IndexService型変数を作成する。
 	var indexService = service.NewIndexService(discordSession)
 	var messageService = service.NewMessageService(discordSession)
+	var announceService = service.NewAnnounceService(discordSession, deps.Player, deps.SoundsDir, deps.Recordings)
+	var transcriptService = service.NewTranscriptService(deps.Transcripts)
+	var attendanceService = service.NewAttendanceService(deps.Attendance)
+	var messageHistoryService = service.NewMessageHistoryService(deps.Messages)
+	var metricsService = service.NewMetricsService()
 
 	// register routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", serverHandler.NewIndexHandler(indexService).ServeHTTP)
 	mux.HandleFunc("/message", serverHandler.NewMessageHandler(messageService).ServeHTTP)
+	mux.HandleFunc("/announce", serverHandler.RequireToken(deps.APIToken, serverHandler.NewAnnounceHandler(announceService).ServeHTTP))
+	mux.HandleFunc("/transcripts/", serverHandler.RequireToken(deps.APIToken, serverHandler.NewTranscriptHandler(transcriptService).ServeHTTP))
+	mux.HandleFunc("/attendance", serverHandler.RequireToken(deps.APIToken, serverHandler.NewAttendanceHandler(attendanceService).ServeHTTP))
+	mux.HandleFunc("/messages/history", serverHandler.RequireToken(deps.APIToken, serverHandler.NewMessageHistoryHandler(messageHistoryService).ServeHTTP))
+	mux.HandleFunc("/metrics", serverHandler.NewMetricsHandler(metricsService).ServeHTTP)
 	return mux
 }
 
@@ -0,0 +1,55 @@
+package serverHandler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"main/service"
+	"main/transcript"
+)
+
+type TranscriptHandler struct {
+	svc *service.TranscriptService
+}
+
+// TranscriptHandlerを返す
+func NewTranscriptHandler(svc *service.TranscriptService) *TranscriptHandler {
+	return &TranscriptHandler{
+		svc: svc,
+	}
+}
+
+// GET /transcripts/<id>.<srt|vtt|json|md>
+// API_TOKENによる認証が必要（RequireToken）。Discordには各形式のファイルを添付して送る
+func (h *TranscriptHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GETだけが利用できます。", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	id := strings.TrimSuffix(name, path.Ext(name))
+
+	data, contentType, err := h.svc.Export(id, ext)
+	if errors.Is(err, transcript.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("書き起こしの出力エラー: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Write(data)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
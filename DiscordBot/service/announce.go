@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"main/recording"
+	"main/voice"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type AnnounceService struct {
+	DiscordSession *discordgo.Session
+	Player         *voice.Player
+	SoundsDir      string
+	Recordings     *recording.Manager
+}
+
+// AnnounceServiceを返す
+func NewAnnounceService(discordSession *discordgo.Session, player *voice.Player, soundsDir string, recordings *recording.Manager) *AnnounceService {
+	return &AnnounceService{
+		DiscordSession: discordSession,
+		Player:         player,
+		SoundsDir:      soundsDir,
+		Recordings:     recordings,
+	}
+}
+
+// 指定されたボイスチャンネルでアナウンス音声を再生する
+// 待ち順を返す（0ならすぐに再生される）
+func (s *AnnounceService) Announce(guildID, channelID, sound string) (int, error) {
+	if guildID == "" {
+		return 0, errors.New("ギルドIDが指定されていません")
+	}
+	if channelID == "" && voice.Connection(s.DiscordSession, guildID) == nil {
+		return 0, errors.New("チャンネルIDが指定されていません")
+	}
+	// 録音中のチャンネルから接続を移さない
+	if s.Recordings != nil {
+		if err := s.Recordings.CheckChannel(guildID, channelID); err != nil {
+			return 0, err
+		}
+	}
+
+	source, err := s.resolveSound(sound)
+	if err != nil {
+		return 0, err
+	}
+
+	position := s.Player.Enqueue(guildID, channelID, &voice.Track{
+		Title:  sound,
+		Source: source,
+	})
+	return position, nil
+}
+
+// 音声の指定をSOUNDS_DIR内のファイルパスに変換する
+// 認証の無いエンドポイントのため、任意のURLやディレクトリ外のファイルは再生しない
+func (s *AnnounceService) resolveSound(sound string) (string, error) {
+	if sound == "" {
+		return "", errors.New("音声が指定されていません")
+	}
+
+	// ディレクトリ外のファイルは参照させない
+	path := filepath.Join(s.SoundsDir, filepath.Base(sound))
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.New("音声ファイルが見つかりません: " + sound)
+	}
+	return path, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
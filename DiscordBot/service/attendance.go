@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+
+	"main/attendance"
+)
+
+type AttendanceService struct {
+	Tracker *attendance.Tracker
+}
+
+// AttendanceServiceを返す
+func NewAttendanceService(tracker *attendance.Tracker) *AttendanceService {
+	return &AttendanceService{
+		Tracker: tracker,
+	}
+}
+
+// ギルドの会議一覧を返す
+func (s *AttendanceService) Meetings(guildID string) ([]*attendance.Meeting, error) {
+	if guildID == "" {
+		return nil, errors.New("ギルドIDが指定されていません")
+	}
+	return s.Tracker.Meetings(guildID), nil
+}
+
+// 会議の出席記録を返す（会議を省略すると最新の会議）
+func (s *AttendanceService) Report(guildID, meeting string) (*attendance.Report, error) {
+	if guildID == "" {
+		return nil, errors.New("ギルドIDが指定されていません")
+	}
+	m, err := s.Tracker.FindMeeting(guildID, meeting)
+	if err != nil {
+		return nil, err
+	}
+	return s.Tracker.Report(m)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"main/messagestore"
+	"main/model"
+)
+
+type MessageHistoryService struct {
+	Store *messagestore.Store
+}
+
+// MessageHistoryServiceを返す
+func NewMessageHistoryService(store *messagestore.Store) *MessageHistoryService {
+	return &MessageHistoryService{
+		Store: store,
+	}
+}
+
+// メッセージの編集・削除の履歴を返す
+func (s *MessageHistoryService) Get(channelID, messageID string) (*model.MessageHistoryResponse, error) {
+	m, err := s.Store.Get(channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.MessageHistoryResponse{
+		ID:        m.ID,
+		ChannelID: m.ChannelID,
+		Content:   m.Content,
+		CreatedAt: m.Timestamp.UnixMilli(),
+		Deleted:   m.DeletedAt != nil,
+		Revisions: []model.MessageRevision{},
+	}
+	if m.Author != nil {
+		res.AuthorID = m.Author.ID
+		res.Author = m.Author.Username
+	}
+	if m.DeletedAt != nil {
+		res.DeletedAt = m.DeletedAt.UnixMilli()
+	}
+	for _, r := range m.Revisions {
+		res.Revisions = append(res.Revisions, model.MessageRevision{Content: r.Content, EditedAt: r.EditedAt.UnixMilli()})
+	}
+	return res, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"main/httpclient"
+)
+
+type MetricsService struct{}
+
+// MetricsServiceを返す
+func NewMetricsService() *MetricsService {
+	return &MetricsService{}
+}
+
+// 外部サービスとの通信の統計を返す
+func (s *MetricsService) HTTP() []httpclient.Stats {
+	return httpclient.Snapshot()
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
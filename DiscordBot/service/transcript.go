@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+
+	"main/transcript"
+)
+
+type TranscriptService struct {
+	Store *transcript.Store
+}
+
+// TranscriptServiceを返す
+func NewTranscriptService(store *transcript.Store) *TranscriptService {
+	return &TranscriptService{
+		Store: store,
+	}
+}
+
+// 書き起こし結果を指定された形式で出力する
+func (s *TranscriptService) Export(id, ext string) ([]byte, string, error) {
+	format, ok := transcript.FormatByExt(ext)
+	if !ok {
+		return nil, "", errors.New("未対応の形式です: " + ext)
+	}
+
+	t, err := s.Store.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := format.Render(t)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, format.ContentType, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,161 @@
+package summary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// 要約を再利用する期間
+	DefaultCacheTTL = 24 * time.Hour
+	// 覚えておく要約の数
+	DefaultCacheEntries = 200
+)
+
+// 要約を再利用できる条件（同じ範囲・スタイル・言語・モデル）
+type CacheKey struct {
+	ChannelID string
+	FirstID   string // 要約した最初のメッセージ
+	LastID    string // 要約した最後のメッセージ
+	Style     string
+	Language  string
+	Model     string
+}
+
+type cacheEntry struct {
+	digest   string          // 依頼の内容のハッシュ（テンプレートや伏せ字の変更を見分ける）
+	messages map[string]bool // 要約に含めたメッセージ
+	result   *Result
+	storedAt time.Time
+}
+
+// 同じ会話を何度も要約しないよう、要約の結果を覚えておく
+// 含めたメッセージが編集・削除されたら、その要約は使わない
+type Cache struct {
+	TTL        time.Duration
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[CacheKey]*cacheEntry
+}
+
+// 既定の設定のCacheを返す
+func NewCache() *Cache {
+	return &Cache{
+		TTL:        DefaultCacheTTL,
+		MaxEntries: DefaultCacheEntries,
+		entries:    make(map[CacheKey]*cacheEntry),
+	}
+}
+
+// 会話の行から要約を探す条件を作る
+func NewCacheKey(channelID string, base Request, model string, lines []Line) CacheKey {
+	key := CacheKey{ChannelID: channelID, Style: base.Style, Language: base.Language, Model: model}
+	for _, l := range lines {
+		if l.ID == "" {
+			continue
+		}
+		if key.FirstID == "" {
+			key.FirstID = l.ID
+		}
+		key.LastID = l.ID
+	}
+	return key
+}
+
+// 依頼の内容（指示と会話）のハッシュ
+func CacheDigest(base Request, lines []Line) string {
+	h := sha256.New()
+	h.Write([]byte(base.Instructions))
+	h.Write([]byte{0})
+	for _, l := range lines {
+		h.Write([]byte(l.Text))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// 覚えている要約を返す（無い・古い・内容が変わっていればfalse）
+func (c *Cache) Get(key CacheKey, digest string) (*Result, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(e.storedAt) > c.TTL || e.digest != digest {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+// 要約の結果を覚える（上限を超えたら古いものから忘れる）
+func (c *Cache) Put(key CacheKey, digest string, lines []Line, result *Result) {
+	if c == nil {
+		return
+	}
+	messages := make(map[string]bool, len(lines))
+	for _, l := range lines {
+		if l.ID != "" {
+			messages[l.ID] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{digest: digest, messages: messages, result: result, storedAt: time.Now()}
+	for len(c.entries) > c.MaxEntries {
+		var oldest CacheKey
+		var oldestAt time.Time
+		for k, e := range c.entries {
+			if oldestAt.IsZero() || e.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, e.storedAt
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// メッセージを含む要約を忘れる
+func (c *Cache) Invalidate(messageIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		for _, id := range messageIDs {
+			if e.messages[id] {
+				delete(c.entries, k)
+				break
+			}
+		}
+	}
+}
+
+// 編集されたメッセージを含む要約を忘れる
+func (c *Cache) OnMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m.Author == nil {
+		// 埋め込みの展開など、本文を含まない更新は無視する
+		return
+	}
+	c.Invalidate(m.ID)
+}
+
+// 削除されたメッセージを含む要約を忘れる
+func (c *Cache) OnMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	c.Invalidate(m.ID)
+}
+
+// まとめて削除されたメッセージを含む要約を忘れる
+func (c *Cache) OnMessageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	c.Invalidate(m.Messages...)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,104 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func cacheLines(ids ...string) []Line {
+	lines := []Line{{Text: "--- スレッド ---"}}
+	for _, id := range ids {
+		lines = append(lines, Line{ID: id, Text: "太郎: メッセージ" + id})
+	}
+	return lines
+}
+
+func TestNewCacheKey(t *testing.T) {
+	base := Request{Style: StyleStandard, Language: "ja"}
+	key := NewCacheKey("c", base, "gpt-4o-mini", cacheLines("1", "2", "3"))
+	want := CacheKey{ChannelID: "c", FirstID: "1", LastID: "3", Style: StyleStandard, Language: "ja", Model: "gpt-4o-mini"}
+	if key != want {
+		t.Errorf("key = %+v, want %+v", key, want)
+	}
+}
+
+func TestCacheGet(t *testing.T) {
+	base := Request{Instructions: "箇条書きで"}
+	lines := cacheLines("1", "2")
+	key := NewCacheKey("c", base, "m", lines)
+	digest := CacheDigest(base, lines)
+
+	for _, tc := range []struct {
+		name   string
+		change func(c *Cache)
+		key    CacheKey
+		digest string
+		want   bool
+	}{
+		{"同じ依頼", nil, key, digest, true},
+		{"別の範囲", nil, NewCacheKey("c", base, "m", cacheLines("1", "3")), digest, false},
+		{"別のモデル", nil, NewCacheKey("c", base, "other", lines), digest, false},
+		{"指示の変更", nil, key, CacheDigest(Request{Instructions: "表で"}, lines), false},
+		{"本文の変更（伏せ字など）", nil, key, CacheDigest(base, []Line{{ID: "1", Text: "太郎: [電話番号#1]"}, {ID: "2", Text: "太郎: メッセージ2"}}), false},
+		{"期限切れ", func(c *Cache) { c.entries[key].storedAt = time.Now().Add(-c.TTL - time.Minute) }, key, digest, false},
+		{"含めたメッセージの編集", func(c *Cache) {
+			c.OnMessageUpdate(nil, &discordgo.MessageUpdate{Message: &discordgo.Message{ID: "2", Author: &discordgo.User{ID: "u"}}})
+		}, key, digest, false},
+		{"本文を含まない更新", func(c *Cache) {
+			c.OnMessageUpdate(nil, &discordgo.MessageUpdate{Message: &discordgo.Message{ID: "2"}})
+		}, key, digest, true},
+		{"含めたメッセージの削除", func(c *Cache) {
+			c.OnMessageDelete(nil, &discordgo.MessageDelete{Message: &discordgo.Message{ID: "1"}})
+		}, key, digest, false},
+		{"含めていないメッセージの削除", func(c *Cache) {
+			c.OnMessageDelete(nil, &discordgo.MessageDelete{Message: &discordgo.Message{ID: "9"}})
+		}, key, digest, true},
+		{"まとめて削除", func(c *Cache) {
+			c.OnMessageDeleteBulk(nil, &discordgo.MessageDeleteBulk{Messages: []string{"8", "2"}})
+		}, key, digest, false},
+	} {
+		c := NewCache()
+		result := &Result{Summary: "要約"}
+		c.Put(key, digest, lines, result)
+		if tc.change != nil {
+			tc.change(c)
+		}
+		got, ok := c.Get(tc.key, tc.digest)
+		if ok != tc.want || (ok && got != result) {
+			t.Errorf("%s: Get = %v, %v, want %v", tc.name, got, ok, tc.want)
+		}
+	}
+}
+
+func TestCacheEvictsOldest(t *testing.T) {
+	c := NewCache()
+	c.MaxEntries = 2
+	keys := []CacheKey{{ChannelID: "a"}, {ChannelID: "b"}, {ChannelID: "c"}}
+	for n, key := range keys {
+		c.Put(key, "d", nil, &Result{})
+		// 同じ時刻にならないよう、保存した時刻をずらす
+		c.entries[key].storedAt = time.Now().Add(time.Duration(n-len(keys)) * time.Minute)
+	}
+	if _, ok := c.Get(keys[0], "d"); ok {
+		t.Error("the oldest entry was not evicted")
+	}
+	for _, key := range keys[1:] {
+		if _, ok := c.Get(key, "d"); !ok {
+			t.Errorf("%s was evicted", key.ChannelID)
+		}
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Put(CacheKey{}, "d", nil, &Result{})
+	if _, ok := c.Get(CacheKey{}, "d"); ok {
+		t.Error("nil cache returned an entry")
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
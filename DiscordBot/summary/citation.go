@@ -0,0 +1,54 @@
+package summary
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 要約の本文に含まれる発言番号の引用（[#12]、[#3, #15]、[#3][#15] など）
+// [2025] のような番号だけの括弧は引用と区別できないため、先頭の番号に # を付けさせる
+var citationPattern = regexp.MustCompile(`\s*[\[［][#＃]\d+(?:\s*[,，、]\s*[#＃]?\d+)*[\]］]`)
+
+var citationSeparator = regexp.MustCompile(`\s*[,，、]\s*`)
+
+// 会話の行の先頭に付ける発言番号
+func CitationMarker(n int) string {
+	return "[#" + strconv.Itoa(n) + "] "
+}
+
+// 本文の引用をそれぞれ replace の結果に置き換える
+// replace には引用に含まれる番号が渡される（[#3, #15] なら 3 と 15）
+func ReplaceCitations(text string, replace func(numbers []int) string) string {
+	return citationPattern.ReplaceAllStringFunc(text, func(m string) string {
+		inner := strings.Trim(strings.TrimSpace(m), "[]［］")
+		var numbers []int
+		for _, part := range citationSeparator.Split(inner, -1) {
+			if n, err := strconv.Atoi(strings.TrimLeft(part, "#＃")); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+		return replace(numbers)
+	})
+}
+
+// 本文から引用を取り出す
+// 引用を取り除いた本文と、引用された番号を出現順に重複なく返す
+func ExtractCitations(text string) (string, []int) {
+	var numbers []int
+	seen := make(map[int]bool)
+	stripped := ReplaceCitations(text, func(found []int) string {
+		for _, n := range found {
+			if !seen[n] {
+				seen[n] = true
+				numbers = append(numbers, n)
+			}
+		}
+		return ""
+	})
+	return strings.TrimSpace(stripped), numbers
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
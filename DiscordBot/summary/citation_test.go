@@ -0,0 +1,61 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReplaceCitations(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want string
+	}{
+		{"1つの番号", "会場を決めた [#3]", "会場を決めた <3>"},
+		{"複数の番号", "会場を決めた [#3, #15]", "会場を決めた <3 15>"},
+		{"2つ目以降の#は省略できる", "会場を決めた [#3, 15]", "会場を決めた <3 15>"},
+		{"全角の括弧と読点", "会場を決めた［＃3、＃15］", "会場を決めた <3 15>"},
+		{"続けて書いた引用", "会場を決めた [#3][#15]", "会場を決めた <3> <15>"},
+		{"番号だけの括弧は引用にしない", "[2025] 年度の予算 [1]", "[2025] 年度の予算 [1]"},
+		{"番号でない括弧", "[#a] と [TODO]", "[#a] と [TODO]"},
+	} {
+		got := ReplaceCitations(tc.text, func(numbers []int) string {
+			parts := make([]string, len(numbers))
+			for n, number := range numbers {
+				parts[n] = fmt.Sprint(number)
+			}
+			return " <" + strings.Join(parts, " ") + ">"
+		})
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractCitations(t *testing.T) {
+	text, numbers := ExtractCitations("会場を決めた [#3, #15]。予算は [2025] 年度から [#15][#1]")
+	if text != "会場を決めた。予算は [2025] 年度から" {
+		t.Errorf("text = %q", text)
+	}
+	if fmt.Sprint(numbers) != "[3 15 1]" {
+		t.Errorf("numbers = %v, want [3 15 1]", numbers)
+	}
+
+	text, numbers = ExtractCitations("  引用の無い文  ")
+	if text != "引用の無い文" || len(numbers) != 0 {
+		t.Errorf("got %q %v, want the trimmed text and no numbers", text, numbers)
+	}
+}
+
+func TestCitationMarkerIsCitation(t *testing.T) {
+	// 会話の行に付けた番号は、そのまま引用として読み取れる
+	_, numbers := ExtractCitations(strings.TrimSpace(CitationMarker(12)))
+	if len(numbers) != 1 || numbers[0] != 12 {
+		t.Errorf("numbers = %v, want [12]", numbers)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
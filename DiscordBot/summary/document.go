@@ -0,0 +1,185 @@
+package summary
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// 要約の構造化された内容
+type Document struct {
+	Overview    string       `json:"overview"`
+	Topics      []string     `json:"topics"`
+	Decisions   []string     `json:"decisions"`
+	ActionItems []ActionItem `json:"action_items"`
+}
+
+// 要約から取り出したやること
+type ActionItem struct {
+	Task     string `json:"task"`
+	Assignee string `json:"assignee,omitempty"` // 会話に出てきた担当者の名前
+	Due      string `json:"due,omitempty"`      // 書かれたままの期限（例: 2025-04-10, 4/10, 金曜）
+}
+
+// 見出しから項目を判定する
+var (
+	topicHeading    = regexp.MustCompile(`(?i)^(話題|トピック|topics?|chủ đề)`)
+	decisionHeading = regexp.MustCompile(`(?i)^(決定事項|決まったこと|結論|decisions?|quyết định)`)
+	actionHeading   = regexp.MustCompile(`(?i)^(アクションアイテム|タスク|todo|課題|残っている課題|action\s*items?|việc cần làm)`)
+	overviewHeading = regexp.MustCompile(`(?i)^(概要|まとめ|要約|overview|summary|tóm tắt)`)
+	bulletPrefix    = regexp.MustCompile(`^\s*(?:[-*・•]|\d+[.)）])\s*`)
+	headingPrefix   = regexp.MustCompile(`^\s*(?:#{1,6}\s*|【)`)
+)
+
+// 文章のアクションアイテムから担当者と期限を読み取る
+var (
+	assigneeLabel   = regexp.MustCompile(`[（(]?担当(?:者)?\s*[:：]\s*([^）)、,／/]+)[）)]?`)
+	assigneePrefix  = regexp.MustCompile(`^([^\s:：、,]{1,20})\s*[:：]\s*`)
+	assigneeSubject = regexp.MustCompile(`^(.{1,20}?)(?:さん|くん|君|先生)(?:が|は)`)
+	duePattern      = `(\d{4}[-/]\d{1,2}[-/]\d{1,2}|\d{1,2}/\d{1,2}|\d{1,2}月\d{1,2}日|明後日|明日|今日|今週中?|来週中?|[月火水木金土日]曜日?)`
+	dueLabel        = regexp.MustCompile(`(?i)[（(]?(?:期限|締切|〆切|締め切り|due|deadline|hạn)\s*[:：]?\s*` + duePattern + `(?:まで)?[）)]?`)
+	dueSuffix       = regexp.MustCompile(duePattern + `(?:まで|中)`)
+)
+
+// バックエンドの出力を読み取る
+//
+// 次のどれにも対応する。
+//   - {"overview": ..., "topics": [...], "decisions": [...], "action_items": [...]} のJSON
+//   - {"summary": "..."} のような文字列を1つ含むJSON、またはJSONの文字列（FastAPIの応答）
+//   - 「## 決定事項」などの見出しと箇条書きを含むMarkdown
+//
+// 見出しの無い文章は概要として扱う。
+func ParseDocument(output string) *Document {
+	text := strings.TrimSpace(output)
+
+	var doc Document
+	if err := json.Unmarshal([]byte(text), &doc); err == nil && !doc.empty() {
+		return &doc
+	}
+	var str string
+	if err := json.Unmarshal([]byte(text), &str); err == nil {
+		return ParseDocument(str)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &obj); err == nil {
+		for _, key := range []string{"summary", "result", "description", "text", "content"} {
+			if s, ok := obj[key].(string); ok {
+				return ParseDocument(s)
+			}
+		}
+	}
+	return parseMarkdown(text)
+}
+
+func parseMarkdown(text string) *Document {
+	doc := &Document{}
+	var overview, actions []string
+	var current *[]string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if headingPrefix.MatchString(trimmed) || strings.HasSuffix(trimmed, ":") || strings.HasSuffix(trimmed, "：") {
+			title := strings.Trim(headingPrefix.ReplaceAllString(trimmed, ""), "】*:： ")
+			switch {
+			case topicHeading.MatchString(title):
+				current = &doc.Topics
+				continue
+			case decisionHeading.MatchString(title):
+				current = &doc.Decisions
+				continue
+			case actionHeading.MatchString(title):
+				current = &actions
+				continue
+			case overviewHeading.MatchString(title):
+				current = nil
+				continue
+			}
+		}
+		if current != nil && bulletPrefix.MatchString(trimmed) {
+			*current = append(*current, bulletPrefix.ReplaceAllString(trimmed, ""))
+			continue
+		}
+		if current != nil && len(*current) > 0 && strings.HasPrefix(line, "  ") {
+			// 字下げされた行は直前の項目の続き
+			(*current)[len(*current)-1] += "\n" + trimmed
+			continue
+		}
+		overview = append(overview, line)
+	}
+	doc.Overview = strings.TrimSpace(strings.Join(overview, "\n"))
+	for _, a := range actions {
+		doc.ActionItems = append(doc.ActionItems, ParseActionItem(a))
+	}
+	return doc
+}
+
+// 「田中: 回覧板を作る（期限: 4/10）」「田中さんが金曜までに回覧板を作る」のような
+// 文章から担当者と期限を読み取る。読み取れなかった項目は空にする
+func ParseActionItem(text string) ActionItem {
+	item := ActionItem{Task: strings.TrimSpace(text)}
+
+	if m := dueLabel.FindStringSubmatch(item.Task); m != nil {
+		item.Due = m[1]
+		item.Task = strings.TrimSpace(strings.Replace(item.Task, m[0], "", 1))
+	} else if m := dueSuffix.FindStringSubmatch(item.Task); m != nil {
+		item.Due = m[1]
+	}
+
+	if m := assigneeLabel.FindStringSubmatch(item.Task); m != nil {
+		item.Assignee = strings.TrimSpace(m[1])
+		item.Task = strings.TrimSpace(strings.Replace(item.Task, m[0], "", 1))
+	} else if m := assigneePrefix.FindStringSubmatch(item.Task); m != nil {
+		item.Assignee = m[1]
+		item.Task = strings.TrimSpace(item.Task[len(m[0]):])
+	} else if m := assigneeSubject.FindStringSubmatch(item.Task); m != nil && !strings.ContainsAny(m[1], "、。 ") {
+		item.Assignee = m[1]
+	}
+	item.Assignee = strings.TrimPrefix(item.Assignee, "@")
+	// 括弧の中身を取り除いた残り（「回覧板を作る（、」など）を整える
+	item.Task = strings.TrimRight(item.Task, "、,（( ")
+	return item
+}
+
+// JSONでは {"task": ..., "assignee": ..., "due": ...} と文章のどちらも受け付ける
+func (a *ActionItem) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*a = ParseActionItem(text)
+		return nil
+	}
+	type plain ActionItem
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*a = ActionItem(p)
+	if a.Task == "" {
+		return nil
+	}
+	if a.Assignee == "" && a.Due == "" {
+		*a = ParseActionItem(a.Task)
+	}
+	return nil
+}
+
+// 担当者と期限を添えた1行の表記
+func (a ActionItem) String() string {
+	text := a.Task
+	if a.Assignee != "" {
+		text = a.Assignee + ": " + text
+	}
+	if a.Due != "" {
+		text += "（期限: " + a.Due + "）"
+	}
+	return text
+}
+
+func (d *Document) empty() bool {
+	return d.Overview == "" && len(d.Topics) == 0 && len(d.Decisions) == 0 && len(d.ActionItems) == 0
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,65 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 外部のサービスを使わずに決まった形の要約を返す（オフラインでの動作確認用）
+// 同じ入力には常に同じ結果を返す
+type Fake struct{}
+
+func (Fake) Name() string {
+	return "fake"
+}
+
+func (Fake) Model() string {
+	return "fake"
+}
+
+func (Fake) Summarize(ctx context.Context, req *Request) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if req.Stage == StageCombine {
+		parts := strings.Count(req.Text, "【部分要約 ")
+		return fmt.Sprintf("【テスト用の要約】%d 個の部分要約をまとめました\n", parts), nil
+	}
+
+	var lines []string
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(req.Text, "\n") {
+		line = strings.TrimSpace(line)
+		// 行頭の発言番号は引用として行末に移す
+		if marker, rest, ok := strings.Cut(line, "] "); ok && strings.HasPrefix(line, "[") {
+			line = rest + " " + marker + "]"
+		}
+		name, _, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		lines = append(lines, line)
+		if !seen[name] {
+			seen[name] = true
+			speakers = append(speakers, name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "【テスト用の要約】%d 件の発言 / 参加者: %s\n", len(lines), strings.Join(speakers, ", "))
+	if req.Style != "" || req.Language != "" {
+		fmt.Fprintf(&b, "- スタイル: %s / 言語: %s\n", req.Style, req.Language)
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(&b, "- 最初の発言: %s\n", lines[0])
+		fmt.Fprintf(&b, "- 最後の発言: %s\n", lines[len(lines)-1])
+	}
+	return b.String(), nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,82 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"main/httpclient"
+)
+
+// 既定のFastAPIサーバー
+const DefaultFastAPIURL = "https://st-kdaz.onrender.com/items/"
+
+// FastAPIの /items/ に会話を送り、返ってきた本文を要約とする
+// 無料枠のサーバーは起動に時間がかかるため、タイムアウトは長めにする
+type FastAPI struct {
+	url    string
+	client *httpclient.Client
+}
+
+// FastAPIを返す（urlが空なら既定のサーバー）
+func NewFastAPI(url string) *FastAPI {
+	if url == "" {
+		url = DefaultFastAPIURL
+	}
+	// 要約は送るたびに結果が変わり、処理の時間もかかるため再試行しない
+	return &FastAPI{
+		url:    url,
+		client: httpclient.New(httpclient.Config{Name: "fastapi", Timeout: 3 * time.Minute, Retries: -1}),
+	}
+}
+
+func (f *FastAPI) Name() string {
+	return "fastapi"
+}
+
+// モデルはサーバー側で決まるため、バックエンドの名前を使う
+func (f *FastAPI) Model() string {
+	return "fastapi"
+}
+
+func (f *FastAPI) Summarize(ctx context.Context, req *Request) (string, error) {
+	// 会話は従来どおり description に入れ、スタイル・言語・指示文を添える
+	jsonData, err := json.Marshal(map[string]string{
+		"description": req.Text,
+		"stage":       req.Stage,
+		"style":       req.Style,
+		"language":    req.Language,
+		"prompt":      Prompt(req),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(jsonData))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &HTTPError{Backend: "FastAPI", StatusCode: resp.StatusCode}
+	}
+	return string(body), nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
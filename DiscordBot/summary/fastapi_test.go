@@ -0,0 +1,111 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFastAPISendsRequestFields(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("メソッド = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("本文を読めません: %v", err)
+		}
+		io.WriteString(w, `{"overview": "要約"}`)
+	}))
+	defer srv.Close()
+
+	req := &Request{
+		Stage:        StageChunk,
+		Text:         "太郎: こんにちは",
+		Style:        StyleDecisions,
+		Language:     "en",
+		Instructions: "決定事項だけを書いてください",
+	}
+	text, err := NewFastAPI(srv.URL).Summarize(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `{"overview": "要約"}` {
+		t.Errorf("要約 = %q", text)
+	}
+
+	want := map[string]string{
+		"description": req.Text,
+		"stage":       StageChunk,
+		"style":       StyleDecisions,
+		"language":    "en",
+		"prompt":      Prompt(req),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestFastAPIErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status    int
+		temporary bool
+		attempts  int32
+	}{
+		{http.StatusBadRequest, false, 1},
+		{http.StatusInternalServerError, true, 1},
+		{http.StatusServiceUnavailable, true, 1}, // 要約は再試行しない
+	} {
+		var attempts int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(tc.status)
+		}))
+
+		_, err := NewFastAPI(srv.URL).Summarize(context.Background(), &Request{Text: "太郎: こんにちは"})
+		srv.Close()
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Errorf("%d: err = %v, want *HTTPError", tc.status, err)
+			continue
+		}
+		if httpErr.StatusCode != tc.status || httpErr.Backend != "FastAPI" {
+			t.Errorf("%d: err = %+v", tc.status, httpErr)
+		}
+		if httpErr.Temporary() != tc.temporary {
+			t.Errorf("%d: Temporary() = %v, want %v", tc.status, httpErr.Temporary(), tc.temporary)
+		}
+		if n := atomic.LoadInt32(&attempts); n != tc.attempts {
+			t.Errorf("%d: 送信回数 = %d, want %d", tc.status, n, tc.attempts)
+		}
+	}
+}
+
+func TestFastAPICanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewFastAPI(srv.URL).Summarize(ctx, &Request{Text: "太郎: こんにちは"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,62 @@
+package summary
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"main/storage"
+)
+
+// 最後に要約した範囲
+type Entry struct {
+	MessageID    string    `json:"message_id"` // 要約に含めた最新のメッセージ
+	UserID       string    `json:"user_id"`    // 要約を実行したユーザー
+	SummarizedAt time.Time `json:"summarized_at"`
+}
+
+// チャンネルごとに最後に要約したメッセージを記録する
+type History struct {
+	file *storage.JSONFile
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// 保存済みの記録を読み込んでHistoryを返す
+func NewHistory(dir string) (*History, error) {
+	h := &History{
+		file:    storage.NewJSONFile(dir, "history.json"),
+		entries: make(map[string]*Entry),
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := h.file.Load(&h.entries); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// チャンネルで最後に要約した範囲を返す（無ければnil）
+func (h *History) Last(channelID string) *Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e, ok := h.entries[channelID]; ok {
+		copied := *e
+		return &copied
+	}
+	return nil
+}
+
+// 要約した最新のメッセージを記録する
+func (h *History) Record(channelID, messageID, userID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries[channelID] = &Entry{MessageID: messageID, UserID: userID, SummarizedAt: time.Now()}
+	return h.file.Save(h.entries)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
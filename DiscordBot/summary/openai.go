@@ -0,0 +1,98 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"main/httpclient"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// 既定のモデル
+const DefaultOpenAIModel = "gpt-4o-mini"
+
+// OpenAI互換のチャットAPIの設定
+type OpenAIConfig struct {
+	BaseURL string // 空ならOpenAIのAPI（ローカルのサーバーも指定できる）
+	APIKey  string
+	Model   string
+}
+
+// OpenAI互換のチャットAPIで要約する
+type OpenAI struct {
+	client *openai.Client
+	model  string
+}
+
+// OpenAIを返す
+func NewOpenAI(cfg OpenAIConfig) *OpenAI {
+	config := openai.DefaultConfig(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		config.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	}
+	// 要約は送るたびに結果が変わり、1回ごとに料金がかかるため再試行しない
+	config.HTTPClient = httpclient.New(httpclient.Config{Name: "openai", Timeout: 3 * time.Minute, Retries: -1})
+	model := cfg.Model
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	return &OpenAI{
+		client: openai.NewClientWithConfig(config),
+		model:  model,
+	}
+}
+
+func (o *OpenAI) Name() string {
+	return "openai"
+}
+
+func (o *OpenAI) Model() string {
+	return o.model
+}
+
+func (o *OpenAI) Summarize(ctx context.Context, req *Request) (string, error) {
+	text, _, err := o.SummarizeWithUsage(ctx, req)
+	return text, err
+}
+
+// 要約と、APIが報告したトークン数を返す
+func (o *OpenAI) SummarizeWithUsage(ctx context.Context, req *Request) (string, *Usage, error) {
+	resp, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: o.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: Prompt(req)},
+			{Role: openai.ChatMessageRoleUser, Content: req.Text},
+		},
+	})
+	if err != nil {
+		return "", nil, openAIError(err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", nil, errors.New("OpenAI互換APIの応答に要約が含まれていません")
+	}
+	var usage *Usage
+	if resp.Usage.TotalTokens > 0 {
+		usage = &Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens}
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), usage, nil
+}
+
+// ステータスコードを含むエラーをHTTPErrorに揃える
+func openAIError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return &HTTPError{Backend: "OpenAI互換API", StatusCode: apiErr.HTTPStatusCode, Message: apiErr.Message}
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return &HTTPError{Backend: "OpenAI互換API", StatusCode: reqErr.HTTPStatusCode}
+	}
+	return err
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,144 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// チャットAPIに送られた本文
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func TestOpenAISendsChatCompletion(t *testing.T) {
+	var got chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("リクエスト = %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("本文を読めません: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"  {\"overview\": \"要約\"}\n"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	o := NewOpenAI(OpenAIConfig{BaseURL: srv.URL + "/v1/", APIKey: "sk-test", Model: "local-model"})
+	if o.Model() != "local-model" {
+		t.Errorf("Model() = %q", o.Model())
+	}
+	req := &Request{Text: "太郎: こんにちは", Style: StyleRecap, Language: "en"}
+	text, err := o.Summarize(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `{"overview": "要約"}` {
+		t.Errorf("要約 = %q", text)
+	}
+
+	if got.Model != "local-model" {
+		t.Errorf("model = %q", got.Model)
+	}
+	if len(got.Messages) != 2 {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	if got.Messages[0].Role != "system" || got.Messages[0].Content != Prompt(req) {
+		t.Errorf("1件目のメッセージ = %+v", got.Messages[0])
+	}
+	if got.Messages[1].Role != "user" || got.Messages[1].Content != req.Text {
+		t.Errorf("2件目のメッセージ = %+v", got.Messages[1])
+	}
+}
+
+func TestOpenAIReportsUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		usage string
+		want  *Usage
+	}{
+		{"トークン数あり", `,"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150}`, &Usage{InputTokens: 120, OutputTokens: 30}},
+		{"トークン数なし", "", nil},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"要約"},"finish_reason":"stop"}]`+tc.usage+`}`)
+		}))
+		_, got, err := NewOpenAI(OpenAIConfig{BaseURL: srv.URL}).SummarizeWithUsage(context.Background(), &Request{Text: "太郎: こんにちは"})
+		srv.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("%s: usage = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOpenAIDefaultModel(t *testing.T) {
+	if m := NewOpenAI(OpenAIConfig{}).Model(); m != DefaultOpenAIModel {
+		t.Errorf("Model() = %q, want %q", m, DefaultOpenAIModel)
+	}
+}
+
+func TestOpenAIErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status    int
+		body      string
+		temporary bool
+		message   string
+	}{
+		{http.StatusUnauthorized, `{"error":{"message":"Incorrect API key","type":"invalid_request_error"}}`, false, "Incorrect API key"},
+		{http.StatusInternalServerError, `{"error":{"message":"server error","type":"server_error"}}`, true, "server error"},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tc.status)
+			io.WriteString(w, tc.body)
+		}))
+
+		_, err := NewOpenAI(OpenAIConfig{BaseURL: srv.URL, APIKey: "sk-test"}).Summarize(context.Background(), &Request{Text: "太郎: こんにちは"})
+		srv.Close()
+
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Errorf("%d: err = %v, want *HTTPError", tc.status, err)
+			continue
+		}
+		if httpErr.StatusCode != tc.status || httpErr.Message != tc.message {
+			t.Errorf("%d: err = %+v", tc.status, httpErr)
+		}
+		if httpErr.Temporary() != tc.temporary {
+			t.Errorf("%d: Temporary() = %v, want %v", tc.status, httpErr.Temporary(), tc.temporary)
+		}
+	}
+}
+
+func TestOpenAINoChoices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"1","object":"chat.completion","choices":[]}`)
+	}))
+	defer srv.Close()
+
+	if _, err := NewOpenAI(OpenAIConfig{BaseURL: srv.URL}).Summarize(context.Background(), &Request{Text: "太郎: こんにちは"}); err == nil {
+		t.Error("要約が無い応答でエラーになりません")
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
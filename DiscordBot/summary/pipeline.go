@@ -0,0 +1,236 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// 1回の要約に渡す会話の上限（トークンの見積もり）
+	DefaultChunkTokens = 6000
+	// 同時に要約する区間の数
+	DefaultWorkers = 3
+)
+
+// 要約の段階
+const (
+	StageWhole   = ""        // 会話全体を一度に要約する
+	StageChunk   = "chunk"   // 会話の一部を要約する
+	StageCombine = "combine" // 部分要約をまとめる
+)
+
+// 会話の1行（スレッドの見出しなどメッセージでない行はIDが空）
+type Line struct {
+	ID   string
+	Text string
+}
+
+// 1回の要約に渡す会話の区間
+type Chunk struct {
+	FirstID string // 区間に含まれる最初のメッセージ
+	LastID  string // 区間に含まれる最後のメッセージ
+	Lines   int
+	Tokens  int
+	Text    string
+}
+
+// 区間ごとの要約
+type Partial struct {
+	FirstID string
+	LastID  string
+	Summary string
+}
+
+// 要約の進み具合
+type Progress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+// 要約の結果
+type Result struct {
+	Summary  string
+	Partials []*Partial // 会話を分割した場合の区間ごとの要約
+}
+
+// 長い会話を区間に分けて並行して要約し、部分要約をさらに要約する（map-reduce）
+type Pipeline struct {
+	ChunkTokens int
+	Workers     int
+}
+
+// 既定の設定のPipelineを返す
+func NewPipeline() *Pipeline {
+	return &Pipeline{ChunkTokens: DefaultChunkTokens, Workers: DefaultWorkers}
+}
+
+// 行を上限のトークン数に収まる区間に分ける（1行が上限を超える場合はその行だけの区間にする）
+func SplitLines(lines []Line, maxTokens int) []*Chunk {
+	var chunks []*Chunk
+	var cur *Chunk
+	var b strings.Builder
+	flush := func() {
+		if cur != nil && cur.Lines > 0 {
+			cur.Text = b.String()
+			chunks = append(chunks, cur)
+		}
+		cur = nil
+		b.Reset()
+	}
+	for _, l := range lines {
+		tokens := EstimateTokens(l.Text) + 1
+		if cur != nil && cur.Tokens+tokens > maxTokens {
+			flush()
+		}
+		if cur == nil {
+			cur = &Chunk{}
+		}
+		b.WriteString(l.Text + "\n")
+		cur.Tokens += tokens
+		cur.Lines++
+		if l.ID != "" {
+			if cur.FirstID == "" {
+				cur.FirstID = l.ID
+			}
+			cur.LastID = l.ID
+		}
+	}
+	flush()
+	return chunks
+}
+
+// 会話を要約する
+// 上限に収まる会話はそのまま要約し、収まらなければ区間ごとの要約をまとめる。
+// 部分要約をまとめた文章も上限を超える場合は、収まるまで同じ手順を繰り返す。
+// baseのギルド・スタイル・言語・指示は、すべての段階の依頼に引き継ぐ
+func (p *Pipeline) Run(ctx context.Context, sz Summarizer, base Request, lines []Line, progress func(Progress)) (*Result, error) {
+	report := func(pr Progress) {
+		if progress != nil {
+			progress(pr)
+		}
+	}
+
+	chunks := SplitLines(lines, p.ChunkTokens)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("要約する会話がありません")
+	}
+	if len(chunks) == 1 {
+		report(Progress{Stage: StageWhole, Total: 1})
+		text, err := sz.Summarize(ctx, base.with(StageWhole, chunks[0].Text))
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Summary: text}, nil
+	}
+
+	partials, err := p.summarizeChunks(ctx, sz, base, StageChunk, chunks, report)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Partials: partials}
+
+	for {
+		combined := make([]Line, 0, len(partials))
+		for n, pt := range partials {
+			combined = append(combined, Line{ID: pt.FirstID, Text: partialText(n, len(partials), pt)})
+		}
+		// 部分要約1つを1行として分けるため、区間の範囲は部分要約の範囲から求める
+		chunks := SplitLines(combined, p.ChunkTokens)
+		for n, c := range chunks {
+			c.FirstID, c.LastID = rangeOf(partials, chunks, n)
+		}
+		if len(chunks) == 1 {
+			report(Progress{Stage: StageCombine, Total: 1})
+			text, err := sz.Summarize(ctx, base.with(StageCombine, chunks[0].Text))
+			if err != nil {
+				return nil, err
+			}
+			result.Summary = text
+			return result, nil
+		}
+		if len(chunks) >= len(partials) {
+			return nil, fmt.Errorf("部分要約が長すぎるため、まとめることができません")
+		}
+		if partials, err = p.summarizeChunks(ctx, sz, base, StageCombine, chunks, report); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// 区間を並行して要約する（同時に実行する数はWorkersまで）
+func (p *Pipeline) summarizeChunks(ctx context.Context, sz Summarizer, base Request, stage string, chunks []*Chunk, report func(Progress)) ([]*Partial, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := p.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	sem := make(chan struct{}, workers)
+	partials := make([]*Partial, len(chunks))
+
+	var (
+		mu       sync.Mutex
+		done     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	report(Progress{Stage: stage, Total: len(chunks)})
+	for n, c := range chunks {
+		wg.Add(1)
+		go func(n int, c *Chunk) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			text, err := sz.Summarize(ctx, base.with(stage, c.Text))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			partials[n] = &Partial{FirstID: c.FirstID, LastID: c.LastID, Summary: text}
+			done++
+			report(Progress{Stage: stage, Done: done, Total: len(chunks)})
+		}(n, c)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return partials, nil
+}
+
+// 部分要約をまとめるときの1区間分の文章
+// 見出しは会話の順番だけを表し、発言番号の引用と紛れないよう【】で囲む
+func partialText(n, total int, pt *Partial) string {
+	return fmt.Sprintf("【部分要約 %d/%d】\n%s", n+1, total, strings.TrimSpace(pt.Summary))
+}
+
+// n番目の区間に含まれる部分要約の範囲（最初の区間の開始から最後の区間の終わりまで）
+func rangeOf(partials []*Partial, chunks []*Chunk, n int) (string, string) {
+	start := 0
+	for _, c := range chunks[:n] {
+		start += c.Lines
+	}
+	end := start + chunks[n].Lines - 1
+	return partials[start].FirstID, partials[end].LastID
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
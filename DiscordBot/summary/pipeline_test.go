@@ -0,0 +1,224 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 段階ごとに決まった要約を返し、受け取った依頼を記録する
+type scriptedSummarizer struct {
+	Fake
+	chunk   string // 会話の一部の要約
+	combine string // 部分要約のまとめ
+	fail    string // この文字列を含む依頼は失敗させる
+
+	mu       sync.Mutex
+	requests []*Request
+}
+
+func (s *scriptedSummarizer) Summarize(ctx context.Context, req *Request) (string, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	if s.fail != "" && strings.Contains(req.Text, s.fail) {
+		return "", errors.New("要約に失敗しました")
+	}
+	switch req.Stage {
+	case StageChunk:
+		return s.chunk, nil
+	case StageCombine:
+		return s.combine, nil
+	}
+	return "全体の要約", nil
+}
+
+func (s *scriptedSummarizer) stages() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range s.requests {
+		counts[r.Stage]++
+	}
+	return counts
+}
+
+// n行の会話（1行は6トークン）
+func testConversation(n int) []Line {
+	lines := make([]Line, n)
+	for i := range lines {
+		lines[i] = Line{ID: fmt.Sprintf("13000000000000%05d", i), Text: "あいうえお"}
+	}
+	return lines
+}
+
+func TestSplitLines(t *testing.T) {
+	lines := []Line{
+		{Text: "--- スレッド ---"},
+		{ID: "1", Text: "あいうえお"},
+		{ID: "2", Text: "かきくけこ"},
+		{ID: "3", Text: "さしすせそ"},
+		{ID: "4", Text: strings.Repeat("長", 30)},
+		{ID: "5", Text: "たちつてと"},
+	}
+	for _, tc := range []struct {
+		name      string
+		maxTokens int
+		want      []Chunk // Text以外を比べる
+	}{
+		{"すべて収まる", 1000, []Chunk{{FirstID: "1", LastID: "5", Lines: 6}}},
+		{
+			"上限で分ける",
+			15,
+			[]Chunk{
+				{FirstID: "1", LastID: "1", Lines: 2},
+				{FirstID: "2", LastID: "3", Lines: 2},
+				{FirstID: "4", LastID: "4", Lines: 1}, // 上限を超える1行はその行だけにする
+				{FirstID: "5", LastID: "5", Lines: 1},
+			},
+		},
+	} {
+		chunks := SplitLines(lines, tc.maxTokens)
+		if len(chunks) != len(tc.want) {
+			t.Errorf("%s: got %d chunks, want %d", tc.name, len(chunks), len(tc.want))
+			continue
+		}
+		total := 0
+		for n, c := range chunks {
+			w := tc.want[n]
+			if c.FirstID != w.FirstID || c.LastID != w.LastID || c.Lines != w.Lines {
+				t.Errorf("%s: chunk %d = %+v, want %+v", tc.name, n, c, w)
+			}
+			if c.Lines > 1 && c.Tokens > tc.maxTokens {
+				t.Errorf("%s: chunk %d has %d tokens, over %d", tc.name, n, c.Tokens, tc.maxTokens)
+			}
+			if got := strings.Count(c.Text, "\n"); got != c.Lines {
+				t.Errorf("%s: chunk %d text has %d lines, want %d", tc.name, n, got, c.Lines)
+			}
+			total += c.Lines
+		}
+		if total != len(lines) {
+			t.Errorf("%s: %d lines in chunks, want %d", tc.name, total, len(lines))
+		}
+	}
+	if got := SplitLines(nil, 10); len(got) != 0 {
+		t.Errorf("no lines: got %d chunks", len(got))
+	}
+}
+
+func TestRunWhole(t *testing.T) {
+	sz := &scriptedSummarizer{}
+	base := Request{GuildID: "g", Style: StyleRecap, Language: "en", Instructions: "指示"}
+	res, err := (&Pipeline{ChunkTokens: 1000, Workers: 2}).Run(context.Background(), sz, base, testConversation(10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary != "全体の要約" || res.Partials != nil {
+		t.Errorf("result = %+v", res)
+	}
+	if len(sz.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(sz.requests))
+	}
+	r := sz.requests[0]
+	if r.Stage != StageWhole || r.GuildID != "g" || r.Style != StyleRecap || r.Language != "en" || r.Instructions != "指示" {
+		t.Errorf("request = %+v, want the base carried over", r)
+	}
+}
+
+func TestRunCombinesInLevels(t *testing.T) {
+	sz := &scriptedSummarizer{chunk: "要約", combine: "まとめ"}
+	base := Request{GuildID: "g", Style: StyleStandard}
+	var stages []string
+	progress := func(p Progress) {
+		if p.Done == 0 {
+			stages = append(stages, fmt.Sprintf("%s/%d", p.Stage, p.Total))
+		}
+	}
+	// 1行6トークンなので、1区間に4行ずつ5区間に分かれる
+	res, err := (&Pipeline{ChunkTokens: 24, Workers: 3}).Run(context.Background(), sz, base, testConversation(20), progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Summary != "まとめ" {
+		t.Errorf("summary = %q", res.Summary)
+	}
+	if len(res.Partials) != 5 {
+		t.Fatalf("got %d partials, want 5", len(res.Partials))
+	}
+	if res.Partials[0].FirstID != "1300000000000000000" || res.Partials[4].LastID != "1300000000000000019" {
+		t.Errorf("partials cover %s to %s", res.Partials[0].FirstID, res.Partials[4].LastID)
+	}
+
+	counts := sz.stages()
+	if counts[StageChunk] != 5 {
+		t.Errorf("%d chunk requests, want 5", counts[StageChunk])
+	}
+	// まとめた文章も上限を超えるため、まとめを複数回に分ける
+	if counts[StageCombine] < 3 {
+		t.Errorf("%d combine requests, want several levels (progress %v)", counts[StageCombine], stages)
+	}
+	if last := stages[len(stages)-1]; last != StageCombine+"/1" {
+		t.Errorf("last stage = %s, want a single combine", last)
+	}
+	for _, r := range sz.requests {
+		if r.Stage != StageCombine {
+			continue
+		}
+		if !strings.Contains(r.Text, "【部分要約 ") {
+			t.Errorf("combine request without partial labels: %q", r.Text)
+		}
+		// 部分要約の見出しにメッセージのIDを出さない
+		if strings.Contains(r.Text, "13000000000000") {
+			t.Errorf("combine request contains a message ID: %q", r.Text)
+		}
+		if r.GuildID != "g" || r.Style != StyleStandard {
+			t.Errorf("combine request = %+v, want the base carried over", r)
+		}
+	}
+}
+
+func TestRunPartialsTooLong(t *testing.T) {
+	// 部分要約が1つで上限を超えると、まとめても区間が減らない
+	sz := &scriptedSummarizer{chunk: strings.Repeat("長い要約", 10)}
+	_, err := (&Pipeline{ChunkTokens: 20, Workers: 1}).Run(context.Background(), sz, Request{}, testConversation(10), nil)
+	if err == nil || !strings.Contains(err.Error(), "部分要約が長すぎる") {
+		t.Errorf("err = %v, want the partials-too-long error", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if _, err := NewPipeline().Run(context.Background(), &scriptedSummarizer{}, Request{}, nil, nil); err == nil {
+		t.Error("empty conversation: no error")
+	}
+
+	lines := testConversation(20)
+	lines[10].Text = "失敗する行"
+	sz := &scriptedSummarizer{chunk: "要約", combine: "まとめ", fail: "失敗する行"}
+	if _, err := (&Pipeline{ChunkTokens: 20, Workers: 2}).Run(context.Background(), sz, Request{}, lines, nil); err == nil {
+		t.Error("failed chunk: no error")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want int
+	}{
+		{"空", "", 0},
+		{"英数字4文字で1トークン", "abcd", 1},
+		{"英数字は切り上げる", "abcde", 2},
+		{"漢字・ひらがな・カタカナは1文字1トークン", "会議はカメラ", 6},
+		{"記号は2文字で1トークン", "、。「", 2},
+		{"混在", "明日 10:00 から", 4 + 2},
+	} {
+		if got := EstimateTokens(tc.text); got != tc.want {
+			t.Errorf("%s: EstimateTokens(%q) = %d, want %d", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
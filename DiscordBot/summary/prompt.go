@@ -0,0 +1,58 @@
+package summary
+
+import "strings"
+
+// 出力の形式（ParseDocumentで読み取る）
+const outputFormat = `
+次の形式のJSONだけを出力してください。
+{"overview": "全体の概要", "topics": ["話題"], "decisions": ["決定事項"], "action_items": [{"task": "やること", "assignee": "担当者の名前（不明なら空）", "due": "期限（YYYY-MM-DD、M/D、曜日など会話の表記のまま。無ければ空）"}]}`
+
+// 会話を要約するとき
+const conversationIntro = `あなたはDiscordの会話を要約するアシスタントです。
+与えられた会話は「名前: 本文」の行で、スレッドは [スレッド「名前」] で区切られています。`
+
+// 部分要約をまとめるとき
+const partialsIntro = `あなたはDiscordの会話を要約するアシスタントです。
+与えられた文章は、長い会話を区間ごとに要約したものを古い順に並べたものです。
+重複をまとめ、会話全体として次の指示どおりの要約にしてください。`
+
+// 会話の一部を要約するとき
+const chunkNote = `これは長い会話の一部です。後でほかの部分の要約とまとめるため、省略しすぎずに要約してください。`
+
+// 発言番号を引用させる
+const citationNote = `会話の各行の先頭の [#番号] は発言の番号です。概要の各文と各項目の末尾に、根拠になった発言の番号を [#12] や [#3, #15] の形で付けてください。会話に無い番号は使わないでください。`
+
+// 部分要約の引用を残させる
+const combineCitationNote = `部分要約に付いている [#番号] は元の発言の番号です。まとめた文や項目にも、根拠になった番号をそのまま [#12] や [#3, #15] の形で付けてください。`
+
+// テンプレートの指示が無いとき
+const defaultInstructions = `話題ごとに重要な内容・決定事項・残っている課題を日本語でまとめてください。`
+
+// 依頼の段階・指示・言語からバックエンドに渡す指示文を作る
+func Prompt(req *Request) string {
+	intro := conversationIntro
+	if req.Stage == StageCombine {
+		intro = partialsIntro
+	}
+	instructions := strings.TrimSpace(req.Instructions)
+	if instructions == "" {
+		instructions = defaultInstructions
+	}
+	parts := []string{intro, instructions}
+	switch req.Stage {
+	case StageChunk:
+		parts = append(parts, chunkNote, citationNote)
+	case StageCombine:
+		parts = append(parts, combineCitationNote)
+	default:
+		parts = append(parts, citationNote)
+	}
+	if l := req.LanguageInstruction(); l != "" {
+		parts = append(parts, l)
+	}
+	return strings.Join(parts, "\n") + outputFormat
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,92 @@
+package summary
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+
+	"main/storage"
+)
+
+var ErrUnknownBackend = errors.New("登録されていない要約バックエンドです")
+
+// ギルドごとに使う要約バックエンドを選ぶ
+type Selector struct {
+	file     *storage.JSONFile
+	fallback string
+
+	mu       sync.Mutex
+	backends map[string]Summarizer
+	guilds   map[string]string // ギルドID -> バックエンド名
+}
+
+// ギルドごとの設定を読み込んでSelectorを返す
+// fallbackは設定していないギルドで使うバックエンドの名前
+func NewSelector(dir, fallback string, backends ...Summarizer) (*Selector, error) {
+	sel := &Selector{
+		file:     storage.NewJSONFile(dir, "backends.json"),
+		fallback: fallback,
+		backends: make(map[string]Summarizer),
+		guilds:   make(map[string]string),
+	}
+	for _, b := range backends {
+		sel.backends[b.Name()] = b
+	}
+	if _, ok := sel.backends[fallback]; !ok {
+		return nil, ErrUnknownBackend
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := sel.file.Load(&sel.guilds); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// ギルドで使うバックエンドを返す
+func (sel *Selector) For(guildID string) Summarizer {
+	sel.mu.Lock()
+	defer sel.mu.Unlock()
+	if b, ok := sel.backends[sel.guilds[guildID]]; ok {
+		return b
+	}
+	return sel.backends[sel.fallback]
+}
+
+// ギルドで使うバックエンドを設定する（空なら既定に戻す）
+func (sel *Selector) Set(guildID, name string) error {
+	sel.mu.Lock()
+	defer sel.mu.Unlock()
+	if name == "" {
+		delete(sel.guilds, guildID)
+	} else {
+		if _, ok := sel.backends[name]; !ok {
+			return ErrUnknownBackend
+		}
+		sel.guilds[guildID] = name
+	}
+	return sel.file.Save(sel.guilds)
+}
+
+// 登録されているバックエンドの名前
+func (sel *Selector) Names() []string {
+	sel.mu.Lock()
+	defer sel.mu.Unlock()
+	names := make([]string, 0, len(sel.backends))
+	for name := range sel.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 設定していないギルドで使うバックエンドの名前
+func (sel *Selector) Fallback() string {
+	return sel.fallback
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"errors"
+	"testing"
+)
+
+// 名前だけを持つバックエンド
+type namedSummarizer struct {
+	Fake
+	name string
+}
+
+func (n namedSummarizer) Name() string {
+	return n.name
+}
+
+func TestSelectorFallback(t *testing.T) {
+	dir := t.TempDir()
+	fastapi := namedSummarizer{name: "fastapi"}
+	openai := namedSummarizer{name: "openai"}
+
+	if _, err := NewSelector(dir, "missing", fastapi, openai); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("登録されていない既定: err = %v, want ErrUnknownBackend", err)
+	}
+
+	sel, err := NewSelector(dir, "fastapi", fastapi, openai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sel.For("g1"); b.Name() != "fastapi" {
+		t.Errorf("設定していないギルド: %s, want fastapi", b.Name())
+	}
+	if err := sel.Set("g1", "unknown"); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("登録されていないバックエンドの設定: err = %v", err)
+	}
+	if err := sel.Set("g1", "openai"); err != nil {
+		t.Fatal(err)
+	}
+	if b := sel.For("g1"); b.Name() != "openai" {
+		t.Errorf("設定したギルド: %s, want openai", b.Name())
+	}
+	if b := sel.For("g2"); b.Name() != "fastapi" {
+		t.Errorf("別のギルド: %s, want fastapi", b.Name())
+	}
+
+	// 設定は保存され、読み込み直しても残る
+	sel, err = NewSelector(dir, "fastapi", fastapi, openai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sel.For("g1"); b.Name() != "openai" {
+		t.Errorf("読み込み直した設定: %s, want openai", b.Name())
+	}
+
+	// 設定したバックエンドが登録されなくなったら既定を使う
+	sel, err = NewSelector(dir, "fastapi", fastapi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sel.For("g1"); b.Name() != "fastapi" {
+		t.Errorf("登録されなくなったバックエンド: %s, want fastapi", b.Name())
+	}
+
+	// 空の設定で既定に戻す
+	if err := sel.Set("g1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if b := sel.For("g1"); b.Name() != "fastapi" {
+		t.Errorf("既定に戻したギルド: %s, want fastapi", b.Name())
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,137 @@
+package summary
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 要約のスタイル
+const (
+	StyleStandard  = "standard"  // 話題・決定事項・課題をまとめる
+	StyleDecisions = "decisions" // 決定事項とアクションアイテムを中心にする（役員向け）
+	StyleRecap     = "recap"     // 参加できなかった人向けの親しみやすいふりかえり
+)
+
+// 要約のスタイルと既定のテンプレート
+type Style struct {
+	Name     string
+	Label    string
+	Template string
+}
+
+// 組み込みのスタイル（ギルドごとにテンプレートを上書きできる）
+var Styles = []Style{
+	{
+		Name:  StyleStandard,
+		Label: "標準",
+		Template: "{guild} の {channel} で {from}〜{to} に交わされた会話（{messages} 件）を要約してください。\n" +
+			"話題ごとに重要な内容・決定事項・残っている課題をまとめてください。",
+	},
+	{
+		Name:  StyleDecisions,
+		Label: "決定事項とアクションアイテム",
+		Template: "{guild} の {channel} で {from}〜{to} に交わされた会話（{messages} 件）から、決まったことと、誰が何をいつまでにやるかを漏れなく抜き出してください。\n" +
+			"経緯や雑談は概要で1〜2文触れる程度にし、決定事項とアクションアイテムを中心にしてください。",
+	},
+	{
+		Name:  StyleRecap,
+		Label: "ふりかえり",
+		Template: "{guild} の {channel} で {from}〜{to} に交わされた会話（{messages} 件）を、参加できなかった人向けに親しみやすい口調でふりかえってください。\n" +
+			"盛り上がった話題やお知らせを中心に、堅苦しくない文章でまとめてください。",
+	},
+}
+
+// 要約を出力する言語
+type Language struct {
+	Code  string
+	Label string // 選択肢に表示する名前
+	Name  string // 指示に使う言語の名前
+}
+
+// 既定の言語
+const DefaultLanguage = "ja"
+
+var Languages = []Language{
+	{Code: "ja", Label: "日本語", Name: "日本語"},
+	{Code: "en", Label: "English", Name: "英語（English）"},
+	{Code: "vi", Label: "Tiếng Việt", Name: "ベトナム語（Tiếng Việt）"},
+}
+
+// テンプレートで使える変数
+var TemplateVariables = []string{"channel", "guild", "from", "to", "messages", "language"}
+
+// テンプレートの長さの上限（Discordのオプションに収まる長さ）
+const maxTemplateLength = 1500
+
+var templateVariable = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// 名前からスタイルを探す
+func StyleByName(name string) (Style, bool) {
+	for _, s := range Styles {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Style{}, false
+}
+
+// コードから言語を探す
+func LanguageByCode(code string) (Language, bool) {
+	for _, l := range Languages {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
+
+// テンプレートに知らない変数が無いか確かめる
+func ValidateTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("テンプレートが空です")
+	}
+	if utf8.RuneCountInString(tmpl) > maxTemplateLength {
+		return fmt.Errorf("テンプレートは %d 文字以内にしてください", maxTemplateLength)
+	}
+	known := make(map[string]bool)
+	for _, v := range TemplateVariables {
+		known[v] = true
+	}
+	var unknown []string
+	for _, m := range templateVariable.FindAllStringSubmatch(tmpl, -1) {
+		if !known[m[1]] {
+			unknown = append(unknown, "{"+m[1]+"}")
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("使えない変数があります: %s（使える変数: %s）", strings.Join(unknown, ", "), VariablesHelp())
+	}
+	return nil
+}
+
+// 使える変数の一覧（案内用）
+func VariablesHelp() string {
+	names := make([]string, len(TemplateVariables))
+	for n, v := range TemplateVariables {
+		names[n] = "{" + v + "}"
+	}
+	return strings.Join(names, " ")
+}
+
+// テンプレートの変数を値に置き換える（値の無い変数はそのまま残す）
+func RenderTemplate(tmpl string, vars map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(tmpl, func(v string) string {
+		if value, ok := vars[strings.Trim(v, "{}")]; ok {
+			return value
+		}
+		return v
+	})
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
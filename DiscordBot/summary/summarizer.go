@@ -0,0 +1,81 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+)
+
+// 要約の依頼
+type Request struct {
+	GuildID string
+	Stage   string // 会話全体・会話の一部・部分要約のまとめのどれか（StageWhole など）
+	Text    string // 「名前: 本文」の行を並べた会話、または部分要約
+
+	Style        string // 要約のスタイル（StyleStandard など）
+	Language     string // 出力する言語のコード（Languages のどれか）
+	Instructions string // ギルドのテンプレートの変数を展開した指示
+}
+
+// 段階と本文を差し替えた依頼を返す
+func (r Request) with(stage, text string) *Request {
+	r.Stage = stage
+	r.Text = text
+	return &r
+}
+
+// 出力する言語の指示（既定の日本語なら空）
+func (r *Request) LanguageInstruction() string {
+	if r.Language == "" || r.Language == DefaultLanguage {
+		return ""
+	}
+	lang, ok := LanguageByCode(r.Language)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("要約の本文はすべて%sで書いてください。JSONのキーは変えないでください。", lang.Name)
+}
+
+// 会話を要約するバックエンド
+type Summarizer interface {
+	// 設定やコマンドで指定する名前
+	Name() string
+	// 要約に使うモデルの名前（要約の再利用と料金の見積もりに使う）
+	Model() string
+	Summarize(ctx context.Context, req *Request) (string, error)
+}
+
+// APIが報告したトークン数
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// 要約に使ったトークン数も返せるバックエンド
+// 報告が無ければ（ローカルのサーバーなど）Usageはnilになる
+type UsageSummarizer interface {
+	Summarizer
+	SummarizeWithUsage(ctx context.Context, req *Request) (string, *Usage, error)
+}
+
+// バックエンドが返したエラー応答
+type HTTPError struct {
+	Backend    string
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s から異常なステータスコード: %d (%s)", e.Backend, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s から異常なステータスコード: %d", e.Backend, e.StatusCode)
+}
+
+// 時間をおけば成功する可能性があるか（混雑やサーバー側の障害）
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == 429 || e.StatusCode >= 500
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
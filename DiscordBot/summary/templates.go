@@ -0,0 +1,83 @@
+package summary
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"main/storage"
+)
+
+var ErrUnknownStyle = errors.New("登録されていないスタイルです")
+
+// ギルドごとに上書きした要約のテンプレート
+type Templates struct {
+	file *storage.JSONFile
+
+	mu     sync.Mutex
+	guilds map[string]map[string]string // ギルドID -> スタイル名 -> テンプレート
+}
+
+// 保存済みのテンプレートを読み込んでTemplatesを返す
+func NewTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		file:   storage.NewJSONFile(dir, "templates.json"),
+		guilds: make(map[string]map[string]string),
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := t.file.Load(&t.guilds); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ギルドで使うテンプレートを返す（上書きしていなければスタイルの既定のテンプレート）
+func (t *Templates) Get(guildID, style string) (tmpl string, custom bool, err error) {
+	s, ok := StyleByName(style)
+	if !ok {
+		return "", false, ErrUnknownStyle
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tmpl, ok := t.guilds[guildID][style]; ok {
+		return tmpl, true, nil
+	}
+	return s.Template, false, nil
+}
+
+// ギルドのテンプレートを上書きする
+func (t *Templates) Set(guildID, style, tmpl string) error {
+	if _, ok := StyleByName(style); !ok {
+		return ErrUnknownStyle
+	}
+	if err := ValidateTemplate(tmpl); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.guilds[guildID] == nil {
+		t.guilds[guildID] = make(map[string]string)
+	}
+	t.guilds[guildID][style] = tmpl
+	return t.file.Save(t.guilds)
+}
+
+// ギルドのテンプレートを既定に戻す
+func (t *Templates) Reset(guildID, style string) error {
+	if _, ok := StyleByName(style); !ok {
+		return ErrUnknownStyle
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.guilds[guildID], style)
+	if len(t.guilds[guildID]) == 0 {
+		delete(t.guilds, guildID)
+	}
+	return t.file.Save(t.guilds)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
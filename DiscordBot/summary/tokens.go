@@ -0,0 +1,27 @@
+package summary
+
+import "unicode"
+
+// 文章のトークン数を大まかに見積もる
+//
+// 日本語（漢字・ひらがな・カタカナ）は1文字あたり約1トークン、
+// 英数字は4文字あたり約1トークン、それ以外の記号などは2文字あたり約1トークンとして数える。
+// モデルごとの正確な値ではないため、上限には余裕を持たせて使う。
+func EstimateTokens(text string) int {
+	japanese, ascii, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			japanese++
+		case r < 0x80:
+			ascii++
+		default:
+			other++
+		}
+	}
+	return japanese + (ascii+3)/4 + (other+1)/2
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,81 @@
+package task
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	isoDate   = regexp.MustCompile(`^(\d{4})[-/](\d{1,2})[-/](\d{1,2})$`)
+	slashDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+	kanjiDate = regexp.MustCompile(`^(\d{1,2})月(\d{1,2})日$`)
+	weekdays  = []string{"日", "月", "火", "水", "木", "金", "土"}
+)
+
+// 会話に書かれた期限を日時にする
+// 「4/10」「金曜」のように年や日付が無い場合は、baseより後で最も近い日にする。
+// 期限はその日の終わり（23:59）とする
+func ParseDue(text string, base time.Time) (time.Time, bool) {
+	text = strings.TrimSuffix(strings.TrimSpace(text), "まで")
+	text = strings.TrimSuffix(text, "中")
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 23, 59, 0, 0, base.Location())
+	}
+	today := day(base.Year(), base.Month(), base.Day())
+
+	switch text {
+	case "今日":
+		return today, true
+	case "明日":
+		return today.AddDate(0, 0, 1), true
+	case "明後日":
+		return today.AddDate(0, 0, 2), true
+	case "今週":
+		// 週の終わりは土曜日とする
+		return today.AddDate(0, 0, int(time.Saturday-base.Weekday())), true
+	case "来週":
+		return today.AddDate(0, 0, int(time.Saturday-base.Weekday())+7), true
+	}
+
+	if m := isoDate.FindStringSubmatch(text); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		return validDate(y, m[2], m[3], day)
+	}
+	m := slashDate.FindStringSubmatch(text)
+	if m == nil {
+		m = kanjiDate.FindStringSubmatch(text)
+	}
+	if m != nil {
+		due, ok := validDate(base.Year(), m[1], m[2], day)
+		if !ok || due.Before(today) {
+			// 過ぎた日付や今年に無い日付（2/29）は来年のこと
+			due, ok = validDate(base.Year()+1, m[1], m[2], day)
+		}
+		return due, ok
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(text, "日"), "曜")
+	for n, w := range weekdays {
+		if name == w {
+			days := (n - int(base.Weekday()) + 7) % 7
+			return today.AddDate(0, 0, days), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func validDate(y int, month, date string, day func(int, time.Month, int) time.Time) (time.Time, bool) {
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(date)
+	t := day(y, time.Month(m), d)
+	if t.Month() != time.Month(m) || t.Day() != d {
+		return time.Time{}, false // 4/31 のような存在しない日付
+	}
+	return t, true
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,60 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	// 2025-04-02 は水曜日
+	wed := time.Date(2025, 4, 2, 15, 0, 0, 0, time.Local)
+	sun := time.Date(2025, 4, 6, 10, 0, 0, 0, time.Local)
+	dec := time.Date(2025, 12, 30, 10, 0, 0, 0, time.Local)
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 23, 59, 0, 0, time.Local)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		text   string
+		base   time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{"今日", "今日", wed, date(2025, 4, 2), true},
+		{"明日まで", "明日まで", wed, date(2025, 4, 3), true},
+		{"明後日", "明後日", wed, date(2025, 4, 4), true},
+		{"今週は土曜日まで", "今週", wed, date(2025, 4, 5), true},
+		{"今週中", "今週中", wed, date(2025, 4, 5), true},
+		{"日曜日の今週", "今週", sun, date(2025, 4, 12), true},
+		{"来週", "来週", wed, date(2025, 4, 12), true},
+		{"日曜日の来週", "来週", sun, date(2025, 4, 19), true},
+		{"年月日", "2025-04-10", wed, date(2025, 4, 10), true},
+		{"年月日（スラッシュ）", "2025/5/1", wed, date(2025, 5, 1), true},
+		{"存在しない年月日", "2025-04-31", wed, time.Time{}, false},
+		{"月/日", "4/10", wed, date(2025, 4, 10), true},
+		{"月日", "4月10日まで", wed, date(2025, 4, 10), true},
+		{"当日の月/日", "4/2", wed, date(2025, 4, 2), true},
+		{"過ぎた月/日は来年", "4/1", wed, date(2026, 4, 1), true},
+		{"年末から見た年明け", "1/5", dec, date(2026, 1, 5), true},
+		{"年末から見た年明け（漢字）", "1月5日", dec, date(2026, 1, 5), true},
+		{"存在しない月/日", "4/31", wed, time.Time{}, false},
+		{"今年に無い2/29", "2/29", time.Date(2027, 3, 1, 0, 0, 0, 0, time.Local), date(2028, 2, 29), true},
+		{"曜日", "金曜", wed, date(2025, 4, 4), true},
+		{"曜日（日付き）", "月曜日", wed, date(2025, 4, 7), true},
+		{"曜日（1文字）", "金", wed, date(2025, 4, 4), true},
+		{"当日の曜日", "水曜", wed, date(2025, 4, 2), true},
+		{"日曜", "日曜", wed, date(2025, 4, 6), true},
+		{"読めない期限", "そのうち", wed, time.Time{}, false},
+		{"空", "", wed, time.Time{}, false},
+	} {
+		got, ok := ParseDue(tc.text, tc.base)
+		if ok != tc.wantOK || !got.Equal(tc.want) {
+			t.Errorf("%s: ParseDue(%q) = %v, %v, want %v, %v", tc.name, tc.text, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,120 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"main/digest"
+)
+
+const (
+	// 予定を確認する間隔
+	checkInterval = time.Minute
+	// 期限のどれだけ前に担当者へ通知するか
+	RemindBefore = 24 * time.Hour
+	// 未完了タスクを報告する予定（毎週月曜日の朝）
+	ReportSchedule = "0 9 * * 1"
+)
+
+// 期限が近いタスクを担当者に通知する
+type RemindFunc func(t *Task) error
+
+// ギルドの未完了のタスクを報告先のチャンネルに送る
+type ReportFunc func(guildID, channelID string, open []*Task) error
+
+// ctxが終わるまで予定を確認し、期限前の通知と週ごとの報告を行う
+func (st *Store) Start(ctx context.Context, remind RemindFunc, report ReportFunc) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		st.remindDue(remind)
+		st.reportDue(report)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 期限まで RemindBefore を切ったタスクを1回だけ通知する
+// 期限を過ぎてから作られたタスク（古い会話の要約など）は通知しない
+func (st *Store) remindDue(remind RemindFunc) {
+	now := time.Now()
+	st.mu.Lock()
+	var due []*Task
+	for _, t := range st.state.Tasks {
+		if !t.Open() || t.Due.IsZero() || !t.RemindedAt.IsZero() {
+			continue
+		}
+		if now.Before(t.Due.Add(-RemindBefore)) || !now.Before(t.Due) {
+			continue
+		}
+		t.RemindedAt = now
+		copied := *t
+		due = append(due, &copied)
+	}
+	if len(due) > 0 {
+		if err := st.file.Save(st.state); err != nil {
+			log.Printf("タスクの保存に失敗しました: %v\n", err)
+		}
+	}
+	st.mu.Unlock()
+
+	for _, t := range due {
+		if err := remind(t); err != nil {
+			log.Printf("タスクの通知に失敗しました (%s #%d): %v\n", t.GuildID, t.ID, err)
+		}
+	}
+}
+
+// 報告の時刻を過ぎたギルドに未完了のタスクを送る
+func (st *Store) reportDue(report ReportFunc) {
+	now := time.Now()
+	type job struct {
+		guildID, channelID string
+	}
+	st.mu.Lock()
+	var jobs []job
+	for guildID, r := range st.state.Reports {
+		if now.Before(r.NextRunAt) {
+			continue
+		}
+		// 停止中に過ぎた報告は、起動後に1回だけ行う
+		next, err := nextReport(now)
+		if err != nil {
+			log.Printf("未完了タスクの報告の予定を読み込めません: %v\n", err)
+			break
+		}
+		r.NextRunAt = next
+		jobs = append(jobs, job{guildID, r.ChannelID})
+	}
+	if len(jobs) > 0 {
+		if err := st.file.Save(st.state); err != nil {
+			log.Printf("タスクの保存に失敗しました: %v\n", err)
+		}
+	}
+	st.mu.Unlock()
+
+	for _, j := range jobs {
+		if err := report(j.guildID, j.channelID, st.List(j.guildID, "", false)); err != nil {
+			log.Printf("未完了タスクの報告に失敗しました (%s): %v\n", j.guildID, err)
+		}
+	}
+}
+
+// ReportScheduleは一度だけ読み込んでおく
+var reportSchedule, reportScheduleErr = digest.ParseSchedule(ReportSchedule)
+
+// tより後の報告の時刻
+func nextReport(t time.Time) (time.Time, error) {
+	if reportScheduleErr != nil {
+		return time.Time{}, reportScheduleErr
+	}
+	return reportSchedule.Next(t), nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
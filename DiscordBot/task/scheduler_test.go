@@ -0,0 +1,22 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextReport(t *testing.T) {
+	// 2025-04-02 は水曜日
+	now := time.Date(2025, 4, 2, 12, 0, 0, 0, time.Local)
+	next, err := nextReport(now)
+	if err != nil {
+		t.Fatalf("ReportSchedule を読み込めません: %v", err)
+	}
+	if want := time.Date(2025, 4, 7, 9, 0, 0, 0, time.Local); !next.Equal(want) {
+		t.Errorf("nextReport = %v, want %v", next, want)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
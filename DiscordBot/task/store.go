@@ -0,0 +1,234 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"main/storage"
+)
+
+var (
+	ErrNotFound    = errors.New("タスクが見つかりません")
+	ErrAlreadyDone = errors.New("このタスクはすでに完了しています")
+)
+
+// タスクを作った元の会話
+type Source struct {
+	Command   string `json:"command"` // タスクを作ったコマンド（summary, digest）
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id,omitempty"`
+	URL       string `json:"url,omitempty"` // 元のメッセージへのリンク
+}
+
+// 要約のアクションアイテムから作ったタスク
+type Task struct {
+	ID           int       `json:"id"` // ギルドごとの通し番号
+	GuildID      string    `json:"guild_id"`
+	ChannelID    string    `json:"channel_id"` // 担当者に届かないときの通知先
+	Text         string    `json:"text"`
+	AssigneeID   string    `json:"assignee_id,omitempty"`
+	AssigneeName string    `json:"assignee_name,omitempty"` // 要約に書かれていた担当者の名前
+	DueText      string    `json:"due_text,omitempty"`      // 要約に書かれていた期限
+	Due          time.Time `json:"due,omitempty"`
+	Source       Source    `json:"source"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	DoneBy     string    `json:"done_by,omitempty"`
+	DoneAt     time.Time `json:"done_at,omitempty"`
+	RemindedAt time.Time `json:"reminded_at,omitempty"`
+}
+
+// 未完了か
+func (t *Task) Open() bool {
+	return t.DoneAt.IsZero()
+}
+
+// 担当者の表記（メンション、名前、未定の順）
+func (t *Task) Assignee() string {
+	switch {
+	case t.AssigneeID != "":
+		return "<@" + t.AssigneeID + ">"
+	case t.AssigneeName != "":
+		return t.AssigneeName + "（ユーザー未設定）"
+	}
+	return "担当者未定"
+}
+
+// 週ごとの未完了タスクの報告先
+type Report struct {
+	ChannelID string    `json:"channel_id"`
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+type state struct {
+	Tasks   []*Task            `json:"tasks"`
+	NextID  map[string]int     `json:"next_id"`
+	Reports map[string]*Report `json:"reports"`
+}
+
+// タスクを保存し、期限前の通知と週ごとの報告を行う
+type Store struct {
+	file *storage.JSONFile
+
+	mu    sync.Mutex
+	state state
+}
+
+// 保存済みのタスクを読み込んでStoreを返す
+func NewStore(dir string) (*Store, error) {
+	st := &Store{
+		file: storage.NewJSONFile(dir, "tasks.json"),
+		state: state{
+			NextID:  make(map[string]int),
+			Reports: make(map[string]*Report),
+		},
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := st.file.Load(&st.state); err != nil {
+		return nil, err
+	}
+	if st.state.NextID == nil {
+		st.state.NextID = make(map[string]int)
+	}
+	if st.state.Reports == nil {
+		st.state.Reports = make(map[string]*Report)
+	}
+	return st, nil
+}
+
+// タスクを追加する
+// 同じ担当者の同じ内容の未完了タスクがあれば、追加せずにそれを返す（createdはfalse）
+func (st *Store) Create(t *Task) (added *Task, created bool, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, existing := range st.state.Tasks {
+		if existing.GuildID == t.GuildID && existing.Open() && sameText(existing.Text, t.Text) &&
+			existing.AssigneeID == t.AssigneeID && existing.AssigneeName == t.AssigneeName {
+			copied := *existing
+			return &copied, false, nil
+		}
+	}
+
+	st.state.NextID[t.GuildID]++
+	t.ID = st.state.NextID[t.GuildID]
+	t.CreatedAt = time.Now()
+	st.state.Tasks = append(st.state.Tasks, t)
+	copied := *t
+	return &copied, true, st.file.Save(st.state)
+}
+
+// ギルドのタスクを期限の近い順に返す（期限の無いタスクは最後）
+// assigneeIDを指定するとその担当者のタスクだけを返す
+func (st *Store) List(guildID, assigneeID string, includeDone bool) []*Task {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var list []*Task
+	for _, t := range st.state.Tasks {
+		if t.GuildID != guildID || (assigneeID != "" && t.AssigneeID != assigneeID) || (!includeDone && !t.Open()) {
+			continue
+		}
+		copied := *t
+		list = append(list, &copied)
+	}
+	sortTasks(list)
+	return list
+}
+
+// タスクを完了にする
+func (st *Store) Done(guildID string, id int, userID string) (*Task, error) {
+	return st.update(guildID, id, func(t *Task) error {
+		if !t.Open() {
+			return ErrAlreadyDone
+		}
+		t.DoneBy = userID
+		t.DoneAt = time.Now()
+		return nil
+	})
+}
+
+// タスクの担当者を変える（新しい担当者にも期限前に通知する）
+func (st *Store) Assign(guildID string, id int, userID string) (*Task, error) {
+	return st.update(guildID, id, func(t *Task) error {
+		t.AssigneeID = userID
+		t.RemindedAt = time.Time{}
+		return nil
+	})
+}
+
+func (st *Store) update(guildID string, id int, fn func(t *Task) error) (*Task, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, t := range st.state.Tasks {
+		if t.GuildID != guildID || t.ID != id {
+			continue
+		}
+		if err := fn(t); err != nil {
+			return nil, err
+		}
+		copied := *t
+		return &copied, st.file.Save(st.state)
+	}
+	return nil, ErrNotFound
+}
+
+// 週ごとの報告先を設定する（channelIDが空なら報告をやめる）
+func (st *Store) SetReportChannel(guildID, channelID string) (*Report, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if channelID == "" {
+		delete(st.state.Reports, guildID)
+		return nil, st.file.Save(st.state)
+	}
+	next, err := nextReport(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{ChannelID: channelID, NextRunAt: next}
+	st.state.Reports[guildID] = r
+	copied := *r
+	return &copied, st.file.Save(st.state)
+}
+
+// 週ごとの報告先（設定されていなければnil）
+func (st *Store) ReportChannel(guildID string) *Report {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	r, ok := st.state.Reports[guildID]
+	if !ok {
+		return nil
+	}
+	copied := *r
+	return &copied
+}
+
+func sortTasks(list []*Task) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Due.IsZero() != b.Due.IsZero() {
+			return !a.Due.IsZero()
+		}
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// 表記の揺れ（空白・句点）を無視して比べる
+func sameText(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.TrimRight(strings.Join(strings.Fields(s), ""), "。.")
+	}
+	return normalize(a) == normalize(b)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,122 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 出力形式
+type Format struct {
+	Ext         string
+	ContentType string
+	Render      func(t *Transcript) ([]byte, error)
+}
+
+// 対応している出力形式
+var Formats = []Format{
+	{Ext: "srt", ContentType: "application/x-subrip; charset=utf-8", Render: SRT},
+	{Ext: "vtt", ContentType: "text/vtt; charset=utf-8", Render: WebVTT},
+	{Ext: "json", ContentType: "application/json; charset=utf-8", Render: JSON},
+	{Ext: "md", ContentType: "text/markdown; charset=utf-8", Render: Markdown},
+}
+
+// 拡張子から出力形式を探す
+func FormatByExt(ext string) (Format, bool) {
+	for _, f := range Formats {
+		if f.Ext == ext {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// SubRip形式で出力する
+func SRT(t *Transcript) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, seg := range t.Segments {
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s: %s\n\n",
+			i+1, timestamp(seg.Start, ","), timestamp(seg.End, ","), seg.Speaker, seg.Text)
+	}
+	return buf.Bytes(), nil
+}
+
+// WebVTT形式で出力する（話者はvoiceタグで表す）
+func WebVTT(t *Transcript) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n\n")
+	for _, seg := range t.Segments {
+		fmt.Fprintf(&buf, "%s --> %s\n<v %s>%s\n\n",
+			timestamp(seg.Start, "."), timestamp(seg.End, "."), seg.Speaker, seg.Text)
+	}
+	return buf.Bytes(), nil
+}
+
+// JSON形式で出力する（時刻は秒単位）
+func JSON(t *Transcript) ([]byte, error) {
+	type jsonSegment struct {
+		Start   float64 `json:"start"`
+		End     float64 `json:"end"`
+		Speaker string  `json:"speaker"`
+		UserID  string  `json:"user_id,omitempty"`
+		Text    string  `json:"text"`
+	}
+	segments := make([]jsonSegment, len(t.Segments))
+	for i, seg := range t.Segments {
+		segments[i] = jsonSegment{
+			Start:   seg.Start.Seconds(),
+			End:     seg.End.Seconds(),
+			Speaker: seg.Speaker,
+			UserID:  seg.UserID,
+			Text:    seg.Text,
+		}
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"id":         t.ID,
+		"guild_id":   t.GuildID,
+		"channel_id": t.ChannelID,
+		"started_at": t.StartedAt,
+		"segments":   segments,
+	}, "", "  ")
+}
+
+// 議事録のテンプレートに沿ったMarkdownで出力する
+func Markdown(t *Transcript) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# 議事録 %s\n\n", t.StartedAt.Format("2006年01月02日"))
+	fmt.Fprintf(&buf, "- 日時: %s 〜 %s\n",
+		t.StartedAt.Format("2006/01/02 15:04"), t.StartedAt.Add(t.Duration()).Format("15:04"))
+	if t.Channel != "" {
+		fmt.Fprintf(&buf, "- 場所: %s\n", t.Channel)
+	}
+	fmt.Fprintf(&buf, "- 参加者: %s\n\n", strings.Join(t.Speakers(), "、"))
+
+	buf.WriteString("## 議題\n\n- \n\n")
+	buf.WriteString("## 決定事項\n\n- \n\n")
+	buf.WriteString("## 発言記録\n\n")
+	for _, seg := range t.Segments {
+		fmt.Fprintf(&buf, "- **%s** (%s): %s\n", seg.Speaker, clock(seg.Start), seg.Text)
+	}
+	return buf.Bytes(), nil
+}
+
+// 00:00:00,000 形式の時刻を返す
+func timestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// 00:00 形式の経過時間を返す
+func clock(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
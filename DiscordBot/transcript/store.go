@@ -0,0 +1,46 @@
+package transcript
+
+import (
+	"errors"
+	"regexp"
+
+	"main/storage"
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f-]+$`)
+
+var ErrNotFound = errors.New("書き起こしが見つかりません")
+
+// 書き起こし結果をデータディレクトリに保存する
+type Store struct {
+	dir string
+}
+
+// Storeを返す
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// 書き起こし結果を保存する
+func (s *Store) Save(t *Transcript) error {
+	return storage.NewJSONFile(s.dir, t.ID+".json").Save(t)
+}
+
+// 保存済みの書き起こし結果を読み込む
+func (s *Store) Get(id string) (*Transcript, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	var t Transcript
+	if err := storage.NewJSONFile(s.dir, id+".json").Load(&t); err != nil {
+		return nil, err
+	}
+	if t.ID == "" {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
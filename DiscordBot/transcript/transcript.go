@@ -0,0 +1,62 @@
+package transcript
+
+import (
+	"sort"
+	"time"
+)
+
+// 話者ごとに区切られた発言
+type Segment struct {
+	Start   time.Duration `json:"start"`
+	End     time.Duration `json:"end"`
+	Speaker string        `json:"speaker"`
+	UserID  string        `json:"user_id,omitempty"`
+	Text    string        `json:"text"`
+}
+
+// 1回の録音の書き起こし結果
+type Transcript struct {
+	ID        string    `json:"id"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	Channel   string    `json:"channel"`
+	StartedAt time.Time `json:"started_at"`
+	Segments  []Segment `json:"segments"`
+
+	RedactionKey string `json:"redaction_key,omitempty"` // 個人情報を伏せ字にしたときの復元キー
+}
+
+// 発言を開始時刻順に並べる
+func (t *Transcript) Sort() {
+	sort.SliceStable(t.Segments, func(i, j int) bool {
+		return t.Segments[i].Start < t.Segments[j].Start
+	})
+}
+
+// 発言した話者を登場順に返す
+func (t *Transcript) Speakers() []string {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, seg := range t.Segments {
+		if !seen[seg.Speaker] {
+			seen[seg.Speaker] = true
+			speakers = append(speakers, seg.Speaker)
+		}
+	}
+	return speakers
+}
+
+// 録音全体の長さを返す
+func (t *Transcript) Duration() time.Duration {
+	var d time.Duration
+	for _, seg := range t.Segments {
+		if seg.End > d {
+			d = seg.End
+		}
+	}
+	return d
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,70 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ローカルのコマンド（Open JTalk, espeakなど）で音声を生成する
+//
+// コマンド中の {output} は出力するWAVファイルのパス、{voice} は話者、
+// {speed} は話速に置き換えられる。テキストは標準入力から渡す。
+//
+//	例: open_jtalk -x /var/lib/mecab/dic/open-jtalk/naist-jdic -m {voice} -r {speed} -ow {output}
+//	例: espeak -v ja -w {output} --stdin
+type CommandSynthesizer struct {
+	args []string
+}
+
+// CommandSynthesizerを返す
+func NewCommandSynthesizer(command string) *CommandSynthesizer {
+	return &CommandSynthesizer{
+		args: strings.Fields(command),
+	}
+}
+
+func (c *CommandSynthesizer) Synthesize(ctx context.Context, text string, voice Voice) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "tts-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "out.wav")
+
+	speed := voice.Speed
+	if speed <= 0 {
+		speed = 1.0
+	}
+	replacer := strings.NewReplacer(
+		"{output}", output,
+		"{voice}", voice.Speaker,
+		"{speed}", strconv.FormatFloat(speed, 'f', 2, 64),
+	)
+	if len(c.args) == 0 {
+		return nil, fmt.Errorf("読み上げコマンドが設定されていません")
+	}
+	args := make([]string, len(c.args))
+	for i, a := range c.args {
+		args[i] = replacer.Replace(a)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("読み上げコマンドの実行に失敗しました: %v: %s", err, stderr.String())
+	}
+
+	return os.ReadFile(output)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
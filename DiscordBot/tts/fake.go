@@ -0,0 +1,54 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync"
+)
+
+// 読み上げを要求されたテキストを記録し、無音のWAVを返すテスト用エンジン
+type FakeSynthesizer struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (f *FakeSynthesizer) Synthesize(ctx context.Context, text string, voice Voice) ([]byte, error) {
+	f.mu.Lock()
+	f.texts = append(f.texts, text)
+	f.mu.Unlock()
+	return silentWAV(48000 / 10), nil
+}
+
+// これまでに読み上げたテキストを返す
+func (f *FakeSynthesizer) Texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.texts...)
+}
+
+// 48kHz/16bit/モノラルの無音WAVを作る
+func silentWAV(samples int) []byte {
+	const sampleRate = 48000
+	dataSize := uint32(samples * 2)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // モノラル
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
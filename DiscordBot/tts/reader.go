@@ -0,0 +1,214 @@
+package tts
+
+import (
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"main/voice"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 1メッセージで読み上げる最大文字数
+const maxReadLength = 100
+
+var (
+	urlPattern         = regexp.MustCompile(`https?://[^\s]+`)
+	customEmojiPattern = regexp.MustCompile(`<a?:(\w+):\d+>`)
+	codeBlockPattern   = regexp.MustCompile("(?s)```.*?```")
+)
+
+// 読み上げ中のギルドの状態
+type readingChannel struct {
+	textChannelID string
+	lastAuthorID  string
+}
+
+// 連携したテキストチャンネルの新しいメッセージをボイスチャンネルで読み上げる
+type Reader struct {
+	synth    Synthesizer
+	settings *Settings
+	enqueue  func(guildID string, t *voice.Track) int // 再生キューへの追加
+
+	mu       sync.Mutex
+	channels map[string]*readingChannel // ギルドID → 読み上げ対象
+}
+
+// Readerを返す
+func NewReader(synth Synthesizer, player *voice.Player, settings *Settings) *Reader {
+	return &Reader{
+		synth:    synth,
+		settings: settings,
+		enqueue: func(guildID string, t *voice.Track) int {
+			return player.Enqueue(guildID, "", t)
+		},
+		channels: make(map[string]*readingChannel),
+	}
+}
+
+// 設定を返す
+func (r *Reader) Settings() *Settings {
+	return r.settings
+}
+
+// テキストチャンネルの読み上げを開始する
+func (r *Reader) Enable(guildID, textChannelID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[guildID] = &readingChannel{textChannelID: textChannelID}
+}
+
+// 読み上げを終了する（読み上げ中でなければfalse）
+func (r *Reader) Disable(guildID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.channels[guildID]
+	delete(r.channels, guildID)
+	return ok
+}
+
+// メッセージが作成されたときに読み上げる
+// 読み上げの順番が入れ替わらないよう、合成を待たずに到着順で再生キューに入れる
+func (r *Reader) OnMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || m.GuildID == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch, ok := r.channels[m.GuildID]
+	if !ok || ch.textChannelID != m.ChannelID {
+		return
+	}
+	// 話し手が変わったときだけ名前を読む
+	withName := ch.lastAuthorID != m.Author.ID
+	ch.lastAuthorID = m.Author.ID
+
+	// ボイスチャンネルから切断されていたら読み上げない
+	if voice.Connection(s, m.GuildID) == nil {
+		return
+	}
+
+	text := r.buildText(s, m, withName)
+	if text == "" {
+		return
+	}
+
+	r.speak(m.GuildID, m.Author.ID, text)
+}
+
+// 読み上げるテキストを組み立てる
+func (r *Reader) buildText(s *discordgo.Session, m *discordgo.MessageCreate, withName bool) string {
+	content, err := m.ContentWithMoreMentionsReplaced(s)
+	if err != nil {
+		content = m.ContentWithMentionsReplaced()
+	}
+	content = codeBlockPattern.ReplaceAllString(content, "コード省略")
+	content = urlPattern.ReplaceAllString(content, "URL省略")
+	content = customEmojiPattern.ReplaceAllString(content, "$1")
+	if len(m.Attachments) > 0 {
+		content += " 添付ファイル"
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+
+	if runes := []rune(content); len(runes) > maxReadLength {
+		content = string(runes[:maxReadLength]) + " 以下略"
+	}
+
+	if withName {
+		content = displayName(m) + "、" + content
+	}
+	return r.settings.ApplyDictionary(m.GuildID, content)
+}
+
+// 再生キューに追加し、音声の合成を始める（再生の直前に合成の完了を待つ）
+func (r *Reader) speak(guildID, userID, text string) {
+	audio := r.synthesize(text, r.settings.Voice(userID))
+	r.enqueue(guildID, &voice.Track{
+		Title:       "読み上げ",
+		RequestedBy: userID,
+		Prepare:     audio.wait,
+		OnFinish:    audio.remove,
+	})
+}
+
+// 合成中の音声
+type pendingAudio struct {
+	done chan struct{}
+	path string // 合成した音声の一時ファイル
+	err  error
+}
+
+// 音声を一時ファイルに合成する（完了を待たずに戻る）
+func (r *Reader) synthesize(text string, v Voice) *pendingAudio {
+	audio := &pendingAudio{done: make(chan struct{})}
+	go func() {
+		defer close(audio.done)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		data, err := r.synth.Synthesize(ctx, text, v)
+		if err != nil {
+			log.Printf("音声合成に失敗しました: %v\n", err)
+			audio.err = err
+			return
+		}
+
+		file, err := os.CreateTemp("", "tts-*.wav")
+		if err != nil {
+			log.Printf("一時ファイルの作成に失敗しました: %v\n", err)
+			audio.err = err
+			return
+		}
+		_, err = file.Write(data)
+		file.Close()
+		if err != nil {
+			os.Remove(file.Name())
+			log.Printf("一時ファイルの書き込みに失敗しました: %v\n", err)
+			audio.err = err
+			return
+		}
+		audio.path = file.Name()
+	}()
+	return audio
+}
+
+// 合成が終わるのを待ってファイルパスを返す
+func (a *pendingAudio) wait(ctx context.Context) (string, error) {
+	select {
+	case <-a.done:
+		return a.path, a.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// 一時ファイルを削除する（合成中なら終わってから削除する）
+func (a *pendingAudio) remove() {
+	go func() {
+		<-a.done
+		if a.path != "" {
+			os.Remove(a.path)
+		}
+	}()
+}
+
+// サーバーでの表示名を返す
+func displayName(m *discordgo.MessageCreate) string {
+	if m.Member != nil && m.Member.Nick != "" {
+		return m.Member.Nick
+	}
+	return m.Author.Username
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,177 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"main/storage"
+	"main/voice"
+)
+
+// 再生キューに入ったトラックを記録するReaderを返す
+func newTestReader(t *testing.T, synth Synthesizer) (*Reader, func() []*voice.Track) {
+	t.Helper()
+	settings, err := LoadSettings(storage.NewJSONFile(t.TempDir(), "tts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(synth, nil, settings)
+	var mu sync.Mutex
+	var tracks []*voice.Track
+	r.enqueue = func(guildID string, tr *voice.Track) int {
+		mu.Lock()
+		defer mu.Unlock()
+		tracks = append(tracks, tr)
+		return len(tracks) - 1
+	}
+	return r, func() []*voice.Track {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*voice.Track(nil), tracks...)
+	}
+}
+
+func TestReaderSpeakWithFakeSynthesizer(t *testing.T) {
+	fake := &FakeSynthesizer{}
+	r, queued := newTestReader(t, fake)
+	for _, text := range []string{"こんにちは", "よろしく", "おつかれさま"} {
+		r.speak("guild", "user", text)
+	}
+
+	tracks := queued()
+	if len(tracks) != 3 {
+		t.Fatalf("キューのトラック数 = %d, want 3", len(tracks))
+	}
+	var paths []string
+	for n, tr := range tracks {
+		path, err := tr.Prepare(context.Background())
+		if err != nil {
+			t.Fatalf("トラック %d の合成に失敗しました: %v", n, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("RIFF")) {
+			t.Errorf("トラック %d がWAVではありません", n)
+		}
+		paths = append(paths, path)
+	}
+	if got := fake.Texts(); len(got) != 3 {
+		t.Errorf("合成したテキスト = %v, want 3件", got)
+	}
+
+	// 再生後は一時ファイルを削除する
+	for _, tr := range tracks {
+		tr.OnFinish()
+	}
+	for _, path := range paths {
+		waitRemoved(t, path)
+	}
+}
+
+// 一時ファイルが削除されるまで待つ
+func waitRemoved(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("一時ファイルが削除されていません: %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 先に届いたメッセージほど合成に時間がかかる合成エンジン
+type slowFirstSynthesizer struct {
+	FakeSynthesizer
+	delays map[string]time.Duration
+}
+
+func (s *slowFirstSynthesizer) Synthesize(ctx context.Context, text string, v Voice) ([]byte, error) {
+	s.FakeSynthesizer.Synthesize(ctx, text, v)
+	time.Sleep(s.delays[text])
+	return []byte(text), nil
+}
+
+func TestReaderKeepsArrivalOrder(t *testing.T) {
+	synth := &slowFirstSynthesizer{delays: map[string]time.Duration{
+		"1": 60 * time.Millisecond,
+		"2": 30 * time.Millisecond,
+		"3": 0,
+	}}
+	r, queued := newTestReader(t, synth)
+	for _, text := range []string{"1", "2", "3"} {
+		r.speak("guild", "user", text)
+	}
+
+	// 合成の速さに関わらず、到着順にキューへ入っている
+	for n, tr := range queued() {
+		path, err := tr.Prepare(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := string(rune('1' + n)); string(data) != want {
+			t.Errorf("%d 番目のトラック = %q, want %q", n, data, want)
+		}
+		tr.OnFinish()
+	}
+}
+
+// releaseが閉じられるまで合成を終えない合成エンジン
+type gatedSynthesizer struct {
+	FakeSynthesizer
+	release chan struct{}
+}
+
+func (s *gatedSynthesizer) Synthesize(ctx context.Context, text string, v Voice) ([]byte, error) {
+	<-s.release
+	return s.FakeSynthesizer.Synthesize(ctx, text, v)
+}
+
+func TestReaderPrepareCanceled(t *testing.T) {
+	synth := &gatedSynthesizer{release: make(chan struct{})}
+	r, queued := newTestReader(t, synth)
+	r.speak("guild", "user", "遅い")
+
+	// 合成が終わる前にキャンセルされたら、待たずにエラーを返す
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tr := queued()[0]
+	if _, err := tr.Prepare(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	tr.OnFinish()
+
+	// 合成が終わってから一時ファイルを削除する
+	close(synth.release)
+	path, err := tr.Prepare(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitRemoved(t, path)
+}
+
+func TestNewSynthesizerRejectsEmptyCommand(t *testing.T) {
+	for _, command := range []string{"", "   ", "\t\n"} {
+		if _, err := NewSynthesizer("command", "", command); err == nil {
+			t.Errorf("NewSynthesizer(command, %q) でエラーになりません", command)
+		}
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,117 @@
+package tts
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"main/storage"
+)
+
+// 保存する設定の内容
+type settingsData struct {
+	Voices       map[string]Voice             `json:"voices"`       // ユーザーID → 声の設定
+	Dictionaries map[string]map[string]string `json:"dictionaries"` // ギルドID → 表記 → 読み
+}
+
+// ユーザーごとの声の設定とギルドごとの読み方辞書
+type Settings struct {
+	file *storage.JSONFile
+
+	mu   sync.RWMutex
+	data settingsData
+}
+
+// 保存済みの設定を読み込んでSettingsを返す
+func LoadSettings(file *storage.JSONFile) (*Settings, error) {
+	st := &Settings{
+		file: file,
+		data: settingsData{
+			Voices:       make(map[string]Voice),
+			Dictionaries: make(map[string]map[string]string),
+		},
+	}
+	if err := file.Load(&st.data); err != nil {
+		return nil, err
+	}
+	if st.data.Voices == nil {
+		st.data.Voices = make(map[string]Voice)
+	}
+	if st.data.Dictionaries == nil {
+		st.data.Dictionaries = make(map[string]map[string]string)
+	}
+	return st, nil
+}
+
+// ユーザーの声の設定を返す
+func (st *Settings) Voice(userID string) Voice {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.data.Voices[userID]
+}
+
+// ユーザーの声の設定を保存する
+func (st *Settings) SetVoice(userID string, v Voice) error {
+	st.mu.Lock()
+	st.data.Voices[userID] = v
+	st.mu.Unlock()
+	return st.save()
+}
+
+// 辞書に読み方を登録する
+func (st *Settings) AddWord(guildID, word, reading string) error {
+	st.mu.Lock()
+	dict, ok := st.data.Dictionaries[guildID]
+	if !ok {
+		dict = make(map[string]string)
+		st.data.Dictionaries[guildID] = dict
+	}
+	dict[word] = reading
+	st.mu.Unlock()
+	return st.save()
+}
+
+// 辞書から読み方を削除する（登録されていなければfalse）
+func (st *Settings) RemoveWord(guildID, word string) (bool, error) {
+	st.mu.Lock()
+	dict := st.data.Dictionaries[guildID]
+	_, ok := dict[word]
+	delete(dict, word)
+	st.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, st.save()
+}
+
+// 辞書の読み方でテキストを置き換える
+// 長い表記から順に置き換えて、短い表記が部分一致で先に置き換わるのを防ぐ
+func (st *Settings) ApplyDictionary(guildID, text string) string {
+	st.mu.RLock()
+	dict := st.data.Dictionaries[guildID]
+	pairs := make([]string, 0, len(dict)*2)
+	words := make([]string, 0, len(dict))
+	for w := range dict {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	for _, w := range words {
+		pairs = append(pairs, w, dict[w])
+	}
+	st.mu.RUnlock()
+
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func (st *Settings) save() error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.file.Save(&st.data)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,45 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 読み上げに使う声の設定
+type Voice struct {
+	Speaker string  `json:"speaker"` // エンジンごとの話者（VOICEVOXなら話者ID）
+	Speed   float64 `json:"speed"`   // 話速（1.0が標準）
+}
+
+// テキストから音声(WAV)を生成するエンジン
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string, voice Voice) ([]byte, error)
+}
+
+// 設定名からSynthesizerを作成する
+//
+//	voicevox: VOICEVOX互換のHTTPサーバー (url)
+//	command:  Open JTalkやespeakなどのローカルコマンド (command)
+//	fake:     テスト用
+func NewSynthesizer(engine, url, command string) (Synthesizer, error) {
+	switch engine {
+	case "voicevox":
+		if url == "" {
+			return nil, fmt.Errorf("VOICEVOXのURLが設定されていません")
+		}
+		return NewVoicevoxSynthesizer(url), nil
+	case "command":
+		if len(strings.Fields(command)) == 0 {
+			return nil, fmt.Errorf("読み上げコマンドが設定されていません")
+		}
+		return NewCommandSynthesizer(command), nil
+	case "fake":
+		return &FakeSynthesizer{}, nil
+	}
+	return nil, fmt.Errorf("未対応の読み上げエンジンです: %s", engine)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,90 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"main/httpclient"
+)
+
+// VOICEVOX互換のHTTPエンジンで音声を生成する
+type VoicevoxSynthesizer struct {
+	baseURL string
+	client  *httpclient.Client
+}
+
+// VoicevoxSynthesizerを返す
+func NewVoicevoxSynthesizer(baseURL string) *VoicevoxSynthesizer {
+	// 音声の合成は何度送っても同じなので、POSTでも再試行する
+	return &VoicevoxSynthesizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  httpclient.New(httpclient.Config{Name: "voicevox", Timeout: 30 * time.Second, Idempotent: true}),
+	}
+}
+
+func (v *VoicevoxSynthesizer) Synthesize(ctx context.Context, text string, voice Voice) ([]byte, error) {
+	speaker := voice.Speaker
+	if speaker == "" {
+		speaker = "1"
+	}
+
+	// 1. 音声合成用のクエリを作成
+	q := url.Values{}
+	q.Set("text", text)
+	q.Set("speaker", speaker)
+	query, err := v.post(ctx, "/audio_query?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 話速を反映
+	if voice.Speed > 0 {
+		var params map[string]interface{}
+		if err := json.Unmarshal(query, &params); err != nil {
+			return nil, fmt.Errorf("audio_queryの応答を解析できませんでした: %v", err)
+		}
+		params["speedScale"] = voice.Speed
+		if query, err = json.Marshal(params); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. クエリから音声を合成
+	q = url.Values{}
+	q.Set("speaker", speaker)
+	return v.post(ctx, "/synthesis?"+q.Encode(), query)
+}
+
+func (v *VoicevoxSynthesizer) post(ctx context.Context, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("VOICEVOXとの通信に失敗しました: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("VOICEVOXから異常なステータスコード: %d", resp.StatusCode)
+	}
+	return data, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,250 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"main/storage"
+)
+
+// 利用量を数える処理の種類
+const (
+	Summary       = "summary"       // 要約（トークン数で数える）
+	Transcription = "transcription" // 書き起こし（音声の長さで数える）
+)
+
+// モデルごとの利用量
+type Amount struct {
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	AudioSeconds float64 `json:"audio_seconds"`
+	Cost         float64 `json:"cost"` // 見積もった料金（米ドル）
+}
+
+// トークン数の合計
+func (a Amount) Tokens() int {
+	return a.InputTokens + a.OutputTokens
+}
+
+// 音声の長さ（分）
+func (a Amount) AudioMinutes() float64 {
+	return a.AudioSeconds / 60
+}
+
+func (a *Amount) add(b Amount) {
+	a.Calls += b.Calls
+	a.InputTokens += b.InputTokens
+	a.OutputTokens += b.OutputTokens
+	a.AudioSeconds += b.AudioSeconds
+	a.Cost += b.Cost
+}
+
+// 1か月の利用量
+type Month struct {
+	Models    map[string]*Amount `json:"models"`     // モデル名 -> 利用量
+	CacheHits int                `json:"cache_hits"` // 保存済みの要約を使って呼び出しを省いた回数
+}
+
+// すべてのモデルの合計
+func (m *Month) Total() Amount {
+	var total Amount
+	for _, a := range m.Models {
+		total.add(*a)
+	}
+	return total
+}
+
+// 利用したモデルの名前（名前順）
+func (m *Month) ModelNames() []string {
+	names := make([]string, 0, len(m.Models))
+	for name := range m.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ギルドごとの月間の上限（0は無制限）
+type Quota struct {
+	Tokens       int     `json:"tokens,omitempty"`        // 要約のトークン数
+	AudioMinutes float64 `json:"audio_minutes,omitempty"` // 書き起こす音声の長さ（分）
+	Cost         float64 `json:"cost,omitempty"`          // 見積もった料金（米ドル、要約と書き起こしの合計）
+}
+
+// 上限が設定されているか
+func (q Quota) Limited() bool {
+	return q.Tokens > 0 || q.AudioMinutes > 0 || q.Cost > 0
+}
+
+// 月間の上限に達したことを表すエラー
+type QuotaError struct {
+	Limit   string    // 達した上限の説明
+	ResetAt time.Time // 上限が戻る日時（翌月の初め）
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("今月の%sの上限に達しました", e.Limit)
+}
+
+// 保存する内容
+type ledgerState struct {
+	Usage  map[string]map[string]*Month `json:"usage"`  // ギルドID -> 月（2006-01） -> 利用量
+	Quotas map[string]Quota             `json:"quotas"` // ギルドID -> 上限
+}
+
+// ギルドごとに要約と書き起こしの利用量を記録し、月間の上限を確かめる
+type Ledger struct {
+	file   *storage.JSONFile
+	prices map[string]Price
+
+	mu    sync.Mutex
+	state ledgerState
+}
+
+// 保存済みの利用量を読み込んでLedgerを返す
+// pricesはモデルごとの料金（nilなら既定の料金）
+func NewLedger(dir string, prices map[string]Price) (*Ledger, error) {
+	if prices == nil {
+		prices = DefaultPrices
+	}
+	l := &Ledger{
+		file:   storage.NewJSONFile(dir, "usage.json"),
+		prices: prices,
+		state: ledgerState{
+			Usage:  make(map[string]map[string]*Month),
+			Quotas: make(map[string]Quota),
+		},
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := l.file.Load(&l.state); err != nil {
+		return nil, err
+	}
+	if l.state.Usage == nil {
+		l.state.Usage = make(map[string]map[string]*Month)
+	}
+	if l.state.Quotas == nil {
+		l.state.Quotas = make(map[string]Quota)
+	}
+	return l, nil
+}
+
+// 月の記録に使うキー
+func MonthKey(t time.Time) string {
+	return t.Local().Format("2006-01")
+}
+
+// 要約の呼び出しを1回記録する
+func (l *Ledger) AddTokens(guildID, model string, input, output int) error {
+	cost := l.prices[model].Cost(input, output, 0)
+	return l.add(guildID, model, Amount{Calls: 1, InputTokens: input, OutputTokens: output, Cost: cost})
+}
+
+// 書き起こした音声の長さを記録する
+func (l *Ledger) AddAudio(guildID, model string, audio time.Duration) error {
+	cost := l.prices[model].Cost(0, 0, audio)
+	return l.add(guildID, model, Amount{Calls: 1, AudioSeconds: audio.Seconds(), Cost: cost})
+}
+
+// 保存済みの要約を使ったことを記録する
+func (l *Ledger) AddCacheHit(guildID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.month(guildID, MonthKey(time.Now())).CacheHits++
+	return l.file.Save(l.state)
+}
+
+func (l *Ledger) add(guildID, model string, a Amount) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.month(guildID, MonthKey(time.Now()))
+	if m.Models[model] == nil {
+		m.Models[model] = &Amount{}
+	}
+	m.Models[model].add(a)
+	return l.file.Save(l.state)
+}
+
+// ギルドの月の記録（無ければ作る）
+func (l *Ledger) month(guildID, key string) *Month {
+	months := l.state.Usage[guildID]
+	if months == nil {
+		months = make(map[string]*Month)
+		l.state.Usage[guildID] = months
+	}
+	m := months[key]
+	if m == nil {
+		m = &Month{Models: make(map[string]*Amount)}
+		months[key] = m
+	}
+	if m.Models == nil {
+		m.Models = make(map[string]*Amount)
+	}
+	return m
+}
+
+// ギルドの月の利用量を返す（記録が無ければ空）
+func (l *Ledger) Month(guildID, key string) *Month {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	copied := &Month{Models: make(map[string]*Amount)}
+	if m, ok := l.state.Usage[guildID][key]; ok {
+		copied.CacheHits = m.CacheHits
+		for name, a := range m.Models {
+			amount := *a
+			copied.Models[name] = &amount
+		}
+	}
+	return copied
+}
+
+// ギルドの月間の上限
+func (l *Ledger) Quota(guildID string) Quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state.Quotas[guildID]
+}
+
+// ギルドの月間の上限を設定する（すべて0なら上限を無くす）
+func (l *Ledger) SetQuota(guildID string, q Quota) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if q.Limited() {
+		l.state.Quotas[guildID] = q
+	} else {
+		delete(l.state.Quotas, guildID)
+	}
+	return l.file.Save(l.state)
+}
+
+// 今月の利用量が上限に達していないか確かめる
+// kindは Summary か Transcription で、料金の上限はどちらにも当てはめる
+func (l *Ledger) Check(guildID, kind string) error {
+	now := time.Now()
+	q := l.Quota(guildID)
+	if !q.Limited() {
+		return nil
+	}
+	total := l.Month(guildID, MonthKey(now)).Total()
+	local := now.Local()
+	resetAt := time.Date(local.Year(), local.Month()+1, 1, 0, 0, 0, 0, local.Location())
+
+	switch {
+	case kind == Summary && q.Tokens > 0 && total.Tokens() >= q.Tokens:
+		return &QuotaError{Limit: fmt.Sprintf("要約のトークン数（%d / %d）", total.Tokens(), q.Tokens), ResetAt: resetAt}
+	case kind == Transcription && q.AudioMinutes > 0 && total.AudioMinutes() >= q.AudioMinutes:
+		return &QuotaError{Limit: fmt.Sprintf("書き起こしの音声の長さ（%.1f / %.1f 分）", total.AudioMinutes(), q.AudioMinutes), ResetAt: resetAt}
+	case q.Cost > 0 && total.Cost >= q.Cost:
+		return &QuotaError{Limit: fmt.Sprintf("推定料金（$%.2f / $%.2f）", total.Cost, q.Cost), ResetAt: resetAt}
+	}
+	return nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
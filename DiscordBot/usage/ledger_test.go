@@ -0,0 +1,91 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLedgerCheck(t *testing.T) {
+	now := time.Now().Local()
+	resetAt := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+	prices := map[string]Price{"model": {Input: 1, Output: 2}, "whisper": {Minute: 0.5}}
+
+	for _, tc := range []struct {
+		name    string
+		quota   Quota
+		tokens  [2]int        // 記録する入力・出力のトークン数
+		audio   time.Duration // 記録する音声の長さ
+		kind    string
+		wantErr bool
+	}{
+		{"上限なし", Quota{}, [2]int{1e6, 1e6}, time.Hour, Summary, false},
+		{"トークン数が上限未満", Quota{Tokens: 1000}, [2]int{600, 399}, 0, Summary, false},
+		{"トークン数が上限に到達", Quota{Tokens: 1000}, [2]int{600, 400}, 0, Summary, true},
+		{"トークン数の上限は書き起こしに当てはめない", Quota{Tokens: 1000}, [2]int{600, 400}, 0, Transcription, false},
+		{"音声が上限に到達", Quota{AudioMinutes: 30}, [2]int{}, 30 * time.Minute, Transcription, true},
+		{"音声の上限は要約に当てはめない", Quota{AudioMinutes: 30}, [2]int{}, 30 * time.Minute, Summary, false},
+		// 入力 $1/100万、出力 $2/100万、音声 $0.5/分
+		{"料金の上限は要約にも当てはめる", Quota{Cost: 1}, [2]int{0, 0}, 2 * time.Minute, Summary, true},
+		{"料金の上限は書き起こしにも当てはめる", Quota{Cost: 1}, [2]int{500000, 250000}, 0, Transcription, true},
+		{"料金が上限未満", Quota{Cost: 1}, [2]int{100000, 100000}, time.Minute, Summary, false},
+	} {
+		l, err := NewLedger(t.TempDir(), prices)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetQuota("guild", tc.quota); err != nil {
+			t.Fatal(err)
+		}
+		if tc.tokens != [2]int{} {
+			l.AddTokens("guild", "model", tc.tokens[0], tc.tokens[1])
+		}
+		if tc.audio > 0 {
+			l.AddAudio("guild", "whisper", tc.audio)
+		}
+
+		err = l.Check("guild", tc.kind)
+		var quotaErr *QuotaError
+		if got := errors.As(err, &quotaErr); got != tc.wantErr {
+			t.Errorf("%s: Check = %v, want error %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if quotaErr != nil && !quotaErr.ResetAt.Equal(resetAt) {
+			t.Errorf("%s: ResetAt = %v, want %v", tc.name, quotaErr.ResetAt, resetAt)
+		}
+		// 別のギルドには当てはめない
+		if err := l.Check("other", tc.kind); err != nil {
+			t.Errorf("%s: other guild: %v", tc.name, err)
+		}
+	}
+}
+
+func TestLedgerPersists(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLedger(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddTokens("guild", "gpt-4o-mini", 1000, 500)
+	l.AddCacheHit("guild")
+	l.SetQuota("guild", Quota{Tokens: 10})
+
+	reloaded, err := NewLedger(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := reloaded.Month("guild", MonthKey(time.Now()))
+	if a := m.Models["gpt-4o-mini"]; a == nil || a.Calls != 1 || a.Tokens() != 1500 {
+		t.Errorf("reloaded usage = %+v", a)
+	}
+	if m.CacheHits != 1 {
+		t.Errorf("CacheHits = %d, want 1", m.CacheHits)
+	}
+	if q := reloaded.Quota("guild"); q.Tokens != 10 {
+		t.Errorf("Quota = %+v", q)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,67 @@
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// モデルごとの料金（米ドル）
+type Price struct {
+	Input  float64 // 入力100万トークンあたり
+	Output float64 // 出力100万トークンあたり
+	Minute float64 // 音声1分あたり
+}
+
+// 既定の料金（一覧に無いモデルやローカルで動かすものは無料として数える）
+var DefaultPrices = map[string]Price{
+	"gpt-4o-mini": {Input: 0.15, Output: 0.60},
+	"gpt-4o":      {Input: 2.50, Output: 10.00},
+	"whisper-1":   {Minute: 0.006},
+}
+
+// 利用量から料金を見積もる
+func (p Price) Cost(input, output int, audio time.Duration) float64 {
+	return p.Input*float64(input)/1e6 + p.Output*float64(output)/1e6 + p.Minute*audio.Minutes()
+}
+
+// 料金の設定を読み込む（既定の料金に上書きする）
+// 書式は「モデル=入力/出力」をカンマで区切ったもの。音声のモデルは「モデル=1分あたり/min」と書く
+// 例: gpt-4o-mini=0.15/0.60,whisper-1=0.006/min
+func ParsePrices(text string) (map[string]Price, error) {
+	prices := make(map[string]Price, len(DefaultPrices))
+	for model, p := range DefaultPrices {
+		prices[model] = p
+	}
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, value, ok := strings.Cut(entry, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("料金の設定 %q は「モデル=入力/出力」の形で書いてください", entry)
+		}
+		a, b, _ := strings.Cut(strings.TrimSpace(value), "/")
+		first, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("料金の設定 %q の金額が正しくありません", entry)
+		}
+		if strings.TrimSpace(b) == "min" {
+			prices[model] = Price{Minute: first}
+			continue
+		}
+		second, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if err != nil || second < 0 {
+			return nil, fmt.Errorf("料金の設定 %q の金額が正しくありません", entry)
+		}
+		prices[model] = Price{Input: first, Output: second}
+	}
+	return prices, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,52 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePrices(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		text    string
+		want    map[string]Price // 確かめるモデルの料金
+		wantErr bool
+	}{
+		{"空なら既定の料金", "", map[string]Price{"gpt-4o-mini": DefaultPrices["gpt-4o-mini"], "whisper-1": DefaultPrices["whisper-1"]}, false},
+		{"既定の料金を上書きする", "gpt-4o-mini=0.2/0.8", map[string]Price{"gpt-4o-mini": {Input: 0.2, Output: 0.8}, "gpt-4o": DefaultPrices["gpt-4o"]}, false},
+		{"音声のモデル", "whisper-1=0.01/min", map[string]Price{"whisper-1": {Minute: 0.01}}, false},
+		{"複数と空白", " local = 0/0 , gpt-4o=3/12 ,", map[string]Price{"local": {}, "gpt-4o": {Input: 3, Output: 12}}, false},
+		{"=が無い", "gpt-4o-mini", nil, true},
+		{"モデル名が無い", "=0.1/0.2", nil, true},
+		{"数値でない", "gpt-4o=abc/1", nil, true},
+		{"出力の料金が無い", "gpt-4o=1", nil, true},
+		{"負の料金", "gpt-4o=-1/1", nil, true},
+	} {
+		got, err := ParsePrices(tc.text)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		for model, want := range tc.want {
+			if got[model] != want {
+				t.Errorf("%s: %s = %+v, want %+v", tc.name, model, got[model], want)
+			}
+		}
+	}
+	// 既定の料金は書き換えない
+	ParsePrices("gpt-4o-mini=9/9")
+	if DefaultPrices["gpt-4o-mini"].Input == 9 {
+		t.Error("ParsePrices modified DefaultPrices")
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	p := Price{Input: 0.15, Output: 0.60, Minute: 0.006}
+	if got := p.Cost(1e6, 5e5, 10*time.Minute); got < 0.509999 || got > 0.510001 {
+		t.Errorf("Cost = %v, want 0.51", got)
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
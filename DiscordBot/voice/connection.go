@@ -0,0 +1,30 @@
+package voice
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// 指定したギルドの接続中のボイスコネクションを返す（未接続ならnil）
+func Connection(s *discordgo.Session, guildID string) *discordgo.VoiceConnection {
+	s.RLock()
+	defer s.RUnlock()
+	return s.VoiceConnections[guildID]
+}
+
+// ボイスチャンネルに参加する
+// 録音と再生を同じ接続で行えるように、ミュートせずに参加する
+func Join(s *discordgo.Session, guildID, channelID string) (*discordgo.VoiceConnection, error) {
+	if v := Connection(s, guildID); v != nil {
+		v.RLock()
+		same := v.ChannelID == channelID && v.Ready
+		v.RUnlock()
+		if same {
+			return v, nil
+		}
+	}
+	return s.ChannelVoiceJoin(guildID, channelID, false, false)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
@@ -0,0 +1,141 @@
+package voice
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	errNotOgg  = errors.New("Oggファイルではありません")
+	errNotOpus = errors.New("Opus形式の音声ではありません")
+)
+
+// Oggコンテナからパケット単位でデータを取り出すリーダー
+type oggReader struct {
+	r       *bufio.Reader
+	lacing  []byte // 現在のページで未処理のセグメント長
+	pending []byte // ページをまたぐパケットの途中データ
+}
+
+func newOggReader(r io.Reader) *oggReader {
+	return &oggReader{r: bufio.NewReader(r)}
+}
+
+// 次のページヘッダーを読み込み、セグメントテーブルを取得する
+func (o *oggReader) readPage() error {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(o.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return io.EOF
+		}
+		return err
+	}
+	if !bytes.Equal(header[:4], []byte("OggS")) {
+		return errNotOgg
+	}
+
+	lacing := make([]byte, int(header[26]))
+	if _, err := io.ReadFull(o.r, lacing); err != nil {
+		return err
+	}
+	o.lacing = lacing
+	return nil
+}
+
+// 次のパケットを返す
+func (o *oggReader) Next() ([]byte, error) {
+	for {
+		if len(o.lacing) == 0 {
+			if err := o.readPage(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		n := int(o.lacing[0])
+		o.lacing = o.lacing[1:]
+
+		segment := make([]byte, n)
+		if _, err := io.ReadFull(o.r, segment); err != nil {
+			return nil, err
+		}
+		o.pending = append(o.pending, segment...)
+
+		// 255未満のセグメントでパケットが終わる
+		if n < 255 {
+			packet := o.pending
+			o.pending = nil
+			return packet, nil
+		}
+	}
+}
+
+// Ogg/Opusストリームから音声フレームだけを取り出すリーダー
+type opusFrameReader struct {
+	ogg *oggReader
+}
+
+// ヘッダー(OpusHead)を確認し、フレームリーダーを返す
+func newOpusFrameReader(r io.Reader) (*opusFrameReader, error) {
+	ogg := newOggReader(r)
+	head, err := ogg.Next()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(head, []byte("OpusHead")) {
+		return nil, errNotOpus
+	}
+	return &opusFrameReader{ogg: ogg}, nil
+}
+
+// 次の音声フレームを返す（コメントヘッダーは読み飛ばす）
+func (f *opusFrameReader) Next() ([]byte, error) {
+	for {
+		packet, err := f.ogg.Next()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(packet, []byte("OpusTags")) || len(packet) == 0 {
+			continue
+		}
+		return packet, nil
+	}
+}
+
+// OpusパケットのTOCバイトから再生時間を求める
+func opusPacketDuration(packet []byte) time.Duration {
+	if len(packet) == 0 {
+		return 0
+	}
+	toc := packet[0]
+	config := int(toc >> 3)
+
+	var frame time.Duration
+	switch {
+	case config < 12: // SILK
+		frame = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16: // Hybrid
+		frame = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default: // CELT
+		frame = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+
+	frames := 1
+	switch toc & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+	return frame * time.Duration(frames)
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
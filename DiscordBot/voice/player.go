@@ -0,0 +1,193 @@
+package voice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// フレーム送信がこの時間詰まったら接続が切れたとみなす
+const sendTimeout = 5 * time.Second
+
+var (
+	ErrNotConnected   = errors.New("ボイスチャンネルに接続していません")
+	ErrConnectionLost = errors.New("ボイスチャンネルとの接続が切れました")
+)
+
+// 再生する音声
+type Track struct {
+	Title       string
+	Source      string // ファイルパスまたはURL
+	RequestedBy string
+	// 再生の直前に呼ばれ、再生する音声のファイルパスまたはURLを返す（nilならSourceを使う）
+	// 合成に時間のかかる音声を、到着順を崩さずにキューへ入れるために使う
+	Prepare  func(ctx context.Context) (string, error)
+	OnFinish func() // 再生後（失敗時を含む）に呼ばれる後処理
+}
+
+// ギルドごとの再生キュー
+type guildQueue struct {
+	channelID string
+	tracks    []*Track
+	running   bool
+	cancel    context.CancelFunc
+}
+
+// ギルドごとのキューを管理し、ボイスチャンネルに音声を送信する
+type Player struct {
+	session *discordgo.Session
+
+	mu     sync.Mutex
+	queues map[string]*guildQueue
+}
+
+// Playerを返す
+func NewPlayer(s *discordgo.Session) *Player {
+	return &Player{
+		session: s,
+		queues:  make(map[string]*guildQueue),
+	}
+}
+
+// トラックをキューに追加し、待ち順を返す（0ならすぐに再生される）
+// すでにギルド内で接続中の場合はそのチャンネルで再生する
+// 再生中のキューのチャンネルは変えない（接続先が決まっていない場合だけ設定する）
+func (p *Player) Enqueue(guildID, channelID string, t *Track) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q, ok := p.queues[guildID]
+	if !ok {
+		q = &guildQueue{}
+		p.queues[guildID] = q
+	}
+	if !q.running || q.channelID == "" {
+		q.channelID = channelID
+	}
+	q.tracks = append(q.tracks, t)
+	if q.running {
+		return len(q.tracks)
+	}
+	q.running = true
+	go p.run(guildID, q)
+	return 0
+}
+
+// 再生を止めてキューを空にする
+func (p *Player) Stop(guildID string) {
+	p.mu.Lock()
+	q, ok := p.queues[guildID]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	rest := q.tracks
+	q.tracks = nil
+	if q.cancel != nil {
+		q.cancel()
+	}
+	p.mu.Unlock()
+
+	for _, t := range rest {
+		finish(t)
+	}
+}
+
+// キューが空になるまで順番に再生する
+func (p *Player) run(guildID string, q *guildQueue) {
+	for {
+		p.mu.Lock()
+		if len(q.tracks) == 0 {
+			q.running = false
+			q.cancel = nil
+			p.mu.Unlock()
+			return
+		}
+		t := q.tracks[0]
+		q.tracks = q.tracks[1:]
+		channelID := q.channelID
+		ctx, cancel := context.WithCancel(context.Background())
+		q.cancel = cancel
+		p.mu.Unlock()
+
+		if err := p.play(ctx, guildID, channelID, t); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("再生に失敗しました (%s): %v\n", t.Title, err)
+		}
+		cancel()
+		finish(t)
+	}
+}
+
+// 1トラックを再生する
+func (p *Player) play(ctx context.Context, guildID, channelID string, t *Track) error {
+	v := Connection(p.session, guildID)
+	if v == nil {
+		if channelID == "" {
+			return ErrNotConnected
+		}
+		var err error
+		v, err = Join(p.session, guildID, channelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.Prepare != nil {
+		source, err := t.Prepare(ctx)
+		if err != nil {
+			return err
+		}
+		t.Source = source
+	}
+
+	st, err := openTrack(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	v.RLock()
+	ready, send := v.Ready, v.OpusSend
+	v.RUnlock()
+	if !ready || send == nil {
+		return ErrNotConnected
+	}
+
+	if err := v.Speaking(true); err != nil {
+		return err
+	}
+	defer v.Speaking(false)
+
+	for {
+		frame, err := st.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case send <- frame:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sendTimeout):
+			return ErrConnectionLost
+		}
+	}
+}
+
+func finish(t *Track) {
+	if t.OnFinish != nil {
+		t.OnFinish()
+	}
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
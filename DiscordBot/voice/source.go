@@ -0,0 +1,146 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"main/httpclient"
+)
+
+// Discordへ送るフレームの長さ（48kHz/960サンプル）
+const frameDuration = 20 * time.Millisecond
+
+// 音声ファイルの取得（再生中は本文を読み続けるため全体のタイムアウトは設けず、contextで止める）
+var audioClient = httpclient.New(httpclient.Config{Name: "audio", Timeout: -1})
+
+// 再生中の音声ストリーム
+type stream struct {
+	frames *opusFrameReader
+	first  []byte
+	close  func() error
+}
+
+// 次のフレームを返す
+func (s *stream) Next() ([]byte, error) {
+	if s.first != nil {
+		frame := s.first
+		s.first = nil
+		return frame, nil
+	}
+	return s.frames.Next()
+}
+
+func (s *stream) Close() error {
+	return s.close()
+}
+
+// トラックを開く
+// 20msフレームのOgg/Opusはそのまま送信し、それ以外はffmpegでOpusに変換する
+func openTrack(ctx context.Context, t *Track) (*stream, error) {
+	ext := strings.ToLower(filepath.Ext(strings.SplitN(t.Source, "?", 2)[0]))
+	if ext == ".ogg" || ext == ".opus" {
+		st, err := openOggOpus(ctx, t.Source)
+		if err == nil {
+			return st, nil
+		}
+	}
+	return openWithFFmpeg(ctx, t.Source)
+}
+
+// URLまたはファイルパスから読み込み用のストリームを開く
+func openSource(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := audioClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("音声ファイルの取得に失敗しました: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Ogg/Opusファイルを変換せずに開く
+func openOggOpus(ctx context.Context, source string) (*stream, error) {
+	body, err := openSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err := newOpusFrameReader(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	first, err := frames.Next()
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	// Discordは20msフレームしか扱えないため、それ以外は変換に回す
+	if opusPacketDuration(first) != frameDuration {
+		body.Close()
+		return nil, fmt.Errorf("未対応のフレーム長です: %v", opusPacketDuration(first))
+	}
+
+	return &stream{frames: frames, first: first, close: body.Close}, nil
+}
+
+// ffmpegで任意の音声を20msフレームのOgg/Opusに変換して開く
+func openWithFFmpeg(ctx context.Context, source string) (*stream, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-loglevel", "error",
+		"-i", source,
+		"-vn",
+		"-c:a", "libopus",
+		"-b:a", "96k",
+		"-ar", "48000",
+		"-ac", "2",
+		"-frame_duration", "20",
+		"-f", "ogg",
+		"pipe:1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ffmpegの起動に失敗しました: %v", err)
+	}
+
+	closeFn := func() error {
+		stdout.Close()
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		cmd.Wait()
+		return nil
+	}
+
+	frames, err := newOpusFrameReader(stdout)
+	if err != nil {
+		closeFn()
+		return nil, fmt.Errorf("ffmpegの出力を読み込めませんでした: %v", err)
+	}
+	return &stream{frames: frames, close: closeFn}, nil
+}
+
+/* Copyright (c) 2025 古川幸樹, 宮浦悠月士 */
+/* このソースコードは自由に使用、複製、改変、再配布することができます。 */
+/* ただし、著作権表示は削除しないでください。  */
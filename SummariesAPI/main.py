@@ -9,6 +9,11 @@ from pydantic import BaseModel
 
 class Item(BaseModel):
     description: str | None = None
+    # Botから送られる要約の指定（古いBotは送らないので省略できる）
+    stage: str | None = None     # 会話全体・会話の一部・部分要約のまとめ
+    style: str | None = None     # 要約のスタイル
+    language: str | None = None  # 出力する言語のコード
+    prompt: str | None = None    # スタイル・言語・引用・出力形式を含む指示文
 
 class Data(BaseModel):
     id : str 
@@ -44,8 +49,8 @@ async def create_item(item: Item):
     # text = "アメリカ航空宇宙局あるいは国家航空宇宙局は、アメリカ合衆国政府内における宇宙開発の計画を担当する連邦機関。1958年7月29日、国家航空宇宙法に基づき、先行の国家航空宇宙諮問委員会を発展的に解消する形で設立された。正式に活動を始めたのは1958年10月1日のことであった。"
     text = item.description
 
-    # チャットプロンプトの作成
-    chat_prompt = create_chat_prompt(text)
+    # チャットプロンプトの作成（指示文があればそれに従って要約する）
+    chat_prompt = create_chat_prompt(text, prompt=item.prompt)
 
     # チャットのレスポンスを取得
     result = get_chat_response(client, deployment, chat_prompt)
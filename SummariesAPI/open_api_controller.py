@@ -17,7 +17,30 @@ def initialize_client():
     return client, deployment
 
 # チャットプロンプトをを準備する 
-def create_chat_prompt(text, length =200):
+# promptが指定されていれば、それをシステムの指示として会話をそのまま渡す
+def create_chat_prompt(text, length =200, prompt=None):
+    if prompt:
+        return [
+            {
+                "role": "system",
+                "content": [
+                    {
+                        "type": "text",
+                        "text": prompt
+                    }
+                ]
+            },
+            {
+                "role": "user",
+                "content": [
+                    {
+                        "type": "text",
+                        "text": text
+                    }
+                ]
+            },
+        ]
+
     chat_prompt = [
         {
             "role": "system",